@@ -0,0 +1,69 @@
+// blog-export renders the published posts to a static site, so the blog is published to a CDN without the API
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/migration"
+	"github.com/voltento/go-blog-project/internal/storage/backend"
+	"github.com/voltento/go-blog-project/internal/web"
+	"golang.org/x/exp/slog"
+)
+
+func run() error {
+	var storageCfg backend.Config
+	storageCfg.BindFlags(flag.CommandLine)
+	migrationFile := flag.String("migration", "", "Migration file the posts are loaded from before the export")
+	outDir := flag.String("out", "./site", "Directory the site is written to")
+	baseURL := flag.String("base-url", "", "Public URL the site is served at, e.g. https://blog.example.com")
+	templatesDir := flag.String("templates-dir", "", "Directory with page templates overriding the embedded ones")
+	flag.Parse()
+
+	if *baseURL == "" {
+		return errors.New("-base-url is required, the feeds and the sitemap have absolute links")
+	}
+
+	templates, err := web.LoadTemplates(*templatesDir)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s, closeStorage, err := backend.Open(ctx, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	if *migrationFile != "" {
+		m := migration.Migration{}
+		if err := m.Apply(ctx, *migrationFile, s); err != nil {
+			return fmt.Errorf("can not apply migration. error: %w", err)
+		}
+	}
+
+	slog.Info("export started", "dir", *outDir)
+	if err := web.Export(ctx, blog.NewBlog(s), templates, *baseURL, *outDir); err != nil {
+		return err
+	}
+
+	slog.Info("export finished", "dir", *outDir)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("Failed to export", "error", err)
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -2,45 +2,181 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/voltento/go-blog-project/internal/blog"
 	"github.com/voltento/go-blog-project/internal/handlers"
+	"github.com/voltento/go-blog-project/internal/metrics"
 	"github.com/voltento/go-blog-project/internal/middlewares"
 	"github.com/voltento/go-blog-project/internal/migration"
-	"github.com/voltento/go-blog-project/internal/storage"
+	"github.com/voltento/go-blog-project/internal/storage/backend"
+	"github.com/voltento/go-blog-project/internal/tracing"
+	"github.com/voltento/go-blog-project/internal/web"
 	"golang.org/x/exp/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 10 * time.Second
+
+// jwtSecretEnv is the environment variable keeping the HS256 secret, so the secret is not seen in the process list
+const jwtSecretEnv = "BLOG_JWT_SECRET"
+
+// newJWTConfig reads the keys bearer tokens are verified with
+func newJWTConfig(publicKeyFile string) (middlewares.JWTConfig, error) {
+	cfg := middlewares.JWTConfig{HMACSecret: []byte(os.Getenv(jwtSecretEnv))}
+
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return cfg, fmt.Errorf("can not read JWT public key. error: %w", err)
+		}
+
+		cfg.RSAPublicKey, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return cfg, fmt.Errorf("can not parse JWT public key '%s'. error: %w", publicKeyFile, err)
+		}
+	}
+
+	if len(cfg.HMACSecret) == 0 && cfg.RSAPublicKey == nil {
+		slog.Warn("no JWT key is configured, all the changes will be rejected", "secret env", jwtSecretEnv)
+	}
+
+	return cfg, nil
+}
+
 func run() error {
 	port := flag.String("port", "8080", "Port for the API handlers")
 	migrationFile := flag.String("migration", "./resourses/blog_data.json", "Migration file")
+	dryRun := flag.Bool("dry-run", false, "Check the migration and print the changes it would make without making them or starting the server")
+	var storageCfg backend.Config
+	storageCfg.BindFlags(flag.CommandLine)
+	var tracingCfg tracing.Config
+	tracingCfg.BindFlags(flag.CommandLine)
+	jwtPublicKey := flag.String("jwt-public-key", "", "PEM file with the RSA public key verifying RS256 tokens")
+	tokenTTL := flag.Duration("token-ttl", 24*time.Hour, "Time the tokens issued on login are valid for")
+	scheduleInterval := flag.Duration("schedule-interval", time.Minute, "Interval the scheduled posts are checked at")
+	templatesDir := flag.String("templates-dir", "", "Directory with page templates overriding the embedded ones")
+	baseURL := flag.String("base-url", "", "Public URL of the site the feed links are built with, http://localhost:<port> by default")
+	validateResponses := flag.Bool("validate-responses", false, "Log the responses not matching the OpenAPI document, they are kept in memory to be checked")
 	flag.Parse()
 
-	s := storage.NewStorage()
+	jwtCfg, err := newJWTConfig(*jwtPublicKey)
+	if err != nil {
+		return err
+	}
+
+	templates, err := web.LoadTemplates(*templatesDir)
+	if err != nil {
+		return err
+	}
+
+	s, closeStorage, err := backend.Open(context.Background(), storageCfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
 
 	if len(*migrationFile) > 1 {
 		slog.Info("migration started", "migration file", *migrationFile)
-		m := migration.Migration{}
+		m := migration.Migration{DryRun: *dryRun}
+		if *dryRun {
+			m.Report = func(change string) { fmt.Println(change) }
+		}
+
 		err := m.Apply(context.Background(), *migrationFile, s)
 		if err != nil {
 			slog.Error("can not apply migration.", "error", err)
 			return err
 		}
 
+		if *dryRun {
+			return nil
+		}
 		slog.Info("migration applied")
 	}
 
+	if *dryRun {
+		return errors.New("-dry-run requires -migration")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingCfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("can not flush traces", "error", err)
+		}
+	}()
+
+	// The metrics middleware goes first to see the status the error middleware renders,
+	// the tracing one goes before the logger to put the trace id to the log lines.
+	// The responses are checked against the API document if it's enabled, the requests are always checked
+	// after the token, so a request with an invalid token is rejected with 401 first.
+	m := metrics.New()
+	spec := handlers.OpenAPI()
 	r := gin.New()
+	r.Use(m.Middleware())
+	r.Use(middlewares.TracingMiddleware())
+	if *validateResponses {
+		r.Use(middlewares.ResponseValidationMiddleware(spec))
+	}
 	middlewares.Setup(r)
+	r.Use(middlewares.AuthMiddleware(jwtCfg))
+	r.Use(middlewares.RequestValidationMiddleware(spec))
 
-	b := blog.NewBlog(s)
-	handlers.RegisterHandlers(r, b)
+	b := blog.NewBlog(tracing.InstrumentStorage(m.InstrumentStorage(s)))
+	if err := b.IndexPosts(context.Background()); err != nil {
+		return err
+	}
 
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go b.RunScheduler(schedulerCtx, *scheduleInterval)
+
+	// Login tokens are signed with the HS256 secret, so they are verified by the auth middleware
+	tokens := &middlewares.TokenIssuer{Secret: jwtCfg.HMACSecret, TTL: *tokenTTL}
+	handlers.RegisterHandlers(r, b, tokens)
+	handlers.RegisterDocs(r)
+	if *baseURL == "" {
+		*baseURL = "http://localhost:" + *port
+	}
+	web.RegisterPages(r, b, templates, *baseURL)
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+
+	return serve(r, ":"+*port)
+}
+
+// serve runs the http server till SIGINT or SIGTERM and waits for the handled requests to finish,
+// so the storage is closed after the last change is made
+func serve(h http.Handler, addr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: h}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
 	slog.Info("service started")
-	return r.Run(":" + *port)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("service stopping")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
 }
 
 //	@Title			Blog API
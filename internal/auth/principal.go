@@ -0,0 +1,34 @@
+// Package auth keeps the principal a request is made on behalf of
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// RoleAdmin allows changing posts and comments of any author
+const RoleAdmin = "admin"
+
+// Principal is an authenticated user
+type Principal struct {
+	// Subject is the id of the user
+	Subject string
+	Roles   []string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal carried by ctx, if any
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
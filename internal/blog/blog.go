@@ -2,43 +2,571 @@ package blog
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/diff"
 	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/markdown"
+	"github.com/voltento/go-blog-project/internal/search"
+	"net/http"
+	"strconv"
+	"time"
 )
 
 // Blog intended to keep business logic and interact with storage
 // Any new business logic should be added here rather than in Storage entity.
 type Blog struct {
-	storage Storage
+	storage      Storage
+	index        *search.Index
+	now          func() time.Time
+	passwordCost int
+	renderer     *markdown.Renderer
+	rendered     *renderCache
 }
 
 func NewBlog(s Storage) *Blog {
-	return &Blog{storage: s}
+	return &Blog{
+		storage:      s,
+		index:        search.NewIndex(),
+		now:          time.Now,
+		passwordCost: defaultPasswordCost,
+		renderer:     markdown.NewRenderer(),
+		rendered:     newRenderCache(renderCacheSize),
+	}
 }
 
 type Storage interface {
 	Post(ctx context.Context, id domain.PostId) (*domain.Post, error)
 	CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error)
-	DeletePost(ctx context.Context, id domain.PostId) error
+	CreatePostWithID(ctx context.Context, post *domain.Post) error
+	DeletePost(ctx context.Context, id domain.PostId, version int) error
 	UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error
-	Posts(ctx context.Context) []*domain.Post
+	Posts(ctx context.Context) ([]*domain.Post, error)
+	PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error)
+	ScheduledPosts(ctx context.Context, until time.Time) ([]*domain.Post, error)
+	Tags(ctx context.Context) ([]*domain.TagCount, error)
+	StatusCounts(ctx context.Context) (map[domain.PostStatus]int, error)
+
+	AddRevision(ctx context.Context, rev *domain.Revision) error
+	Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error)
+	Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error)
+
+	CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error)
+	Comment(ctx context.Context, postId domain.PostId, id domain.CommentId) (*domain.Comment, error)
+	Comments(ctx context.Context, postId domain.PostId) ([]*domain.Comment, error)
+	UpdateComment(ctx context.Context, comment *domain.Comment) error
+	DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error
+
+	CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error)
+	User(ctx context.Context, id domain.UserId) (*domain.User, error)
+	UserByUsername(ctx context.Context, username string) (*domain.User, error)
+	UpdateUser(ctx context.Context, user *domain.User) error
+
+	MigrationRecord(ctx context.Context, id string) (*domain.MigrationRecord, error)
+	AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error
+	DeleteMigrationRecord(ctx context.Context, id string) error
 }
 
-func (b *Blog) CreatePost(ctx context.Context, p *domain.Post) (domain.PostId, error) {
-	return b.storage.CreatePost(ctx, p)
+// CreatePost keeps the post written by the authenticated user. The post is published unless another status is set.
+func (b *Blog) CreatePost(ctx context.Context, p *domain.Post) (_ domain.PostId, err error) {
+	ctx, end := startSpan(ctx, "CreatePost")
+	defer func() { end(err) }()
+
+	editor, err := b.currentEditor(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := b.setLifecycle(p, nil); err != nil {
+		return 0, err
+	}
+
+	p.AuthorID = editor.user.ID
+	p.Author = editor.user.DisplayName
+	p.Tags = domain.NormalizeTags(p.Tags)
+	id, err := b.storage.CreatePost(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+
+	b.indexPost(p)
+	if err := b.addRevision(ctx, p, "", editor.user.DisplayName); err != nil {
+		return 0, err
+	}
+
+	return id, nil
 }
 
-func (b *Blog) Post(ctx context.Context, id domain.PostId) (*domain.Post, error) {
-	return b.storage.Post(ctx, id)
+// Post returns the post with the current display name of its author.
+// Posts which are not published are seen only by their authors and admins.
+func (b *Blog) Post(ctx context.Context, id domain.PostId) (_ *domain.Post, err error) {
+	ctx, end := startSpan(ctx, "Post")
+	defer func() { end(err) }()
+
+	post, err := b.visiblePost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := b.withAuthorNames(ctx, []*domain.Post{post})
+	if err != nil {
+		return nil, err
+	}
+
+	return posts[0], nil
+}
+
+// DeletePost deletes the post if the authenticated user is its author or an admin
+func (b *Blog) DeletePost(ctx context.Context, id domain.PostId, version int) (err error) {
+	ctx, end := startSpan(ctx, "DeletePost")
+	defer func() { end(err) }()
+
+	editor, err := b.currentEditor(ctx)
+	if err != nil {
+		return err
+	}
+
+	stored, err := b.storage.Post(ctx, id)
+	if err != nil && httperr.HTTPStatusCode(err, 0) != http.StatusNotFound {
+		return err
+	}
+
+	// A missing post is left to the storage, it knows whether it's an error for the version
+	if stored != nil && !editor.canChange(stored.AuthorID) {
+		return forbiddenError(editor)
+	}
+
+	if err := b.storage.DeletePost(ctx, id, version); err != nil {
+		return err
+	}
+
+	b.index.Remove(id)
+	return nil
 }
 
-func (b *Blog) DeletePost(ctx context.Context, id domain.PostId) error {
-	return b.storage.DeletePost(ctx, id)
+// UpdatePost changes the post if the authenticated user is its author or an admin. The post keeps its author.
+func (b *Blog) UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) (err error) {
+	ctx, end := startSpan(ctx, "UpdatePost")
+	defer func() { end(err) }()
+
+	editor, err := b.currentEditor(ctx)
+	if err != nil {
+		return err
+	}
+
+	stored, err := b.storage.Post(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !editor.canChange(stored.AuthorID) {
+		return forbiddenError(editor)
+	}
+
+	if err := b.setLifecycle(post, stored); err != nil {
+		return err
+	}
+
+	post.ID = id
+	post.AuthorID = stored.AuthorID
+	post.Author = stored.Author
+	post.Tags = domain.NormalizeTags(post.Tags)
+	if err := b.storage.UpdatePost(ctx, post, id); err != nil {
+		return err
+	}
+
+	b.indexPost(post)
+
+	// The posts stored before revisions were introduced have no previous revision
+	prevContent := ""
+	prev, err := b.storage.Revision(ctx, id, post.Version-1)
+	if err == nil {
+		prevContent = prev.Content
+	} else if httperr.HTTPStatusCode(err, 0) != http.StatusNotFound {
+		return err
+	}
+
+	return b.addRevision(ctx, post, prevContent, editor.user.DisplayName)
 }
 
-func (b *Blog) UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error {
-	return b.storage.UpdatePost(ctx, post, id)
+// addRevision keeps the revision produced by the change of the post made by the editor
+func (b *Blog) addRevision(ctx context.Context, post *domain.Post, prevContent, editor string) error {
+	err := b.storage.AddRevision(ctx, &domain.Revision{
+		PostID:    post.ID,
+		Number:    post.Version,
+		Title:     post.Title,
+		Content:   post.Content,
+		Author:    editor,
+		CreatedAt: b.now().UTC(),
+		Diff:      diff.Lines(prevContent, post.Content),
+	})
+	if err != nil {
+		return fmt.Errorf("post is changed, but its revision is not saved. id: %v. error: %w", post.ID, err)
+	}
+
+	return nil
 }
 
-func (b *Blog) Posts(ctx context.Context) []*domain.Post {
-	return b.storage.Posts(ctx)
+// Revisions returns revisions of the post ordered by number
+func (b *Blog) Revisions(ctx context.Context, id domain.PostId) (_ []*domain.Revision, err error) {
+	ctx, end := startSpan(ctx, "Revisions")
+	defer func() { end(err) }()
+
+	if _, err := b.visiblePost(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return b.storage.Revisions(ctx, id)
+}
+
+func (b *Blog) Revision(ctx context.Context, id domain.PostId, number int) (_ *domain.Revision, err error) {
+	ctx, end := startSpan(ctx, "Revision")
+	defer func() { end(err) }()
+
+	if _, err := b.visiblePost(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return b.storage.Revision(ctx, id, number)
+}
+
+// RestoreRevision updates the post with the content of the revision. The restore is kept as a new revision.
+// Tags are not a part of the content, so the post keeps its current tags.
+func (b *Blog) RestoreRevision(ctx context.Context, id domain.PostId, number int) (_ *domain.Post, err error) {
+	ctx, end := startSpan(ctx, "RestoreRevision")
+	defer func() { end(err) }()
+
+	editor, err := b.currentEditor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := b.storage.Post(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The editor is checked first, so whether a revision exists is not revealed to the others
+	if !editor.canChange(current.AuthorID) {
+		return nil, forbiddenError(editor)
+	}
+
+	rev, err := b.storage.Revision(ctx, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	post := &domain.Post{
+		Title:     rev.Title,
+		Content:   rev.Content,
+		Tags:      current.Tags,
+		Status:    current.Status,
+		PublishAt: current.PublishAt,
+	}
+	if err := b.UpdatePost(ctx, post, id); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// CreateComment keeps the comment written by the authenticated user
+func (b *Blog) CreateComment(ctx context.Context, comment *domain.Comment) (_ domain.CommentId, err error) {
+	ctx, end := startSpan(ctx, "CreateComment")
+	defer func() { end(err) }()
+
+	editor, err := b.currentEditor(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := b.visiblePost(ctx, comment.PostID); err != nil {
+		return 0, err
+	}
+
+	comment.AuthorID = editor.user.ID
+	comment.Author = editor.user.DisplayName
+	comment.CreatedAt = b.now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+	return b.storage.CreateComment(ctx, comment)
+}
+
+// CommentThreads returns top-level comments of the post with the replies nested into them.
+// Comments and replies are ordered by creation, they have the current display names of their authors.
+func (b *Blog) CommentThreads(ctx context.Context, postId domain.PostId) (_ []*domain.CommentThread, err error) {
+	ctx, end := startSpan(ctx, "CommentThreads")
+	defer func() { end(err) }()
+
+	if _, err := b.visiblePost(ctx, postId); err != nil {
+		return nil, err
+	}
+
+	comments, err := b.storage.Comments(ctx, postId)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[domain.UserId]string{}
+	threads := make([]*domain.CommentThread, 0)
+	byId := make(map[domain.CommentId]*domain.CommentThread, len(comments))
+	for _, c := range comments {
+		named := *c
+		if named.Author, err = b.authorName(ctx, names, c.AuthorID, c.Author); err != nil {
+			return nil, err
+		}
+
+		thread := &domain.CommentThread{Comment: &named, Replies: make([]*domain.CommentThread, 0)}
+		byId[c.ID] = thread
+
+		// Comments are ordered by id, so the parent is met before the replies to it
+		if parent, ok := byId[c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, thread)
+		} else {
+			threads = append(threads, thread)
+		}
+	}
+
+	return threads, nil
+}
+
+// UpdateComment changes the content of the comment if the authenticated user is its author or an admin
+func (b *Blog) UpdateComment(ctx context.Context, comment *domain.Comment) (err error) {
+	ctx, end := startSpan(ctx, "UpdateComment")
+	defer func() { end(err) }()
+
+	if err := b.authorizeComment(ctx, comment.PostID, comment.ID); err != nil {
+		return err
+	}
+
+	comment.UpdatedAt = b.now().UTC()
+	return b.storage.UpdateComment(ctx, comment)
+}
+
+// DeleteComment deletes the comment with all the replies to it
+// if the authenticated user is the comment author or an admin
+func (b *Blog) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) (err error) {
+	ctx, end := startSpan(ctx, "DeleteComment")
+	defer func() { end(err) }()
+
+	err = b.authorizeComment(ctx, postId, id)
+	if httperr.HTTPStatusCode(err, 0) == http.StatusNotFound {
+		// Deletion of a not existing comment is not an error
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return b.storage.DeleteComment(ctx, postId, id)
+}
+
+// authorizeComment checks that the authenticated user is allowed to change the comment
+func (b *Blog) authorizeComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	editor, err := b.currentEditor(ctx)
+	if err != nil {
+		return err
+	}
+
+	stored, err := b.storage.Comment(ctx, postId, id)
+	if err != nil {
+		return err
+	}
+
+	if !editor.canChange(stored.AuthorID) {
+		return forbiddenError(editor)
+	}
+
+	return nil
+}
+
+// editor is the authenticated user a change is made by
+type editor struct {
+	principal *auth.Principal
+	user      *domain.User
+}
+
+// canChange reports whether the editor is allowed to change a post or a comment of the author
+func (e *editor) canChange(authorId domain.UserId) bool {
+	return e.user.ID == authorId || e.principal.HasRole(auth.RoleAdmin)
+}
+
+// currentEditor returns the authenticated user the request is made by.
+// The subject of the principal is the user id as it's issued on login.
+func (b *Blog) currentEditor(ctx context.Context) (*editor, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		err := errors.New("authentication is required")
+		return nil, httperr.WrapWithHttpCode(err, http.StatusUnauthorized)
+	}
+
+	id, err := strconv.Atoi(p.Subject)
+	if err != nil {
+		err := fmt.Errorf("token subject should be a user id. subject: %v", p.Subject)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusUnauthorized)
+	}
+
+	user, err := b.storage.User(ctx, domain.UserId(id))
+	if httperr.HTTPStatusCode(err, 0) == http.StatusNotFound {
+		err := fmt.Errorf("token subject is not a known user. subject: %v", p.Subject)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusUnauthorized)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &editor{principal: p, user: user}, nil
+}
+
+func forbiddenError(e *editor) error {
+	err := fmt.Errorf("only the author or an admin can change it. user id: %v", e.user.ID)
+	return httperr.WrapWithHttpCode(err, http.StatusForbidden)
+}
+
+// withAuthorNames returns copies of the posts with the current display names of their authors
+func (b *Blog) withAuthorNames(ctx context.Context, posts []*domain.Post) ([]*domain.Post, error) {
+	names := map[domain.UserId]string{}
+	named := make([]*domain.Post, 0, len(posts))
+	for _, p := range posts {
+		name, err := b.authorName(ctx, names, p.AuthorID, p.Author)
+		if err != nil {
+			return nil, err
+		}
+
+		post := *p
+		post.Author = name
+		named = append(named, &post)
+	}
+
+	return named, nil
+}
+
+// authorName returns the current display name of the user caching it in names.
+// The stored name is returned for posts and comments made before users were introduced.
+func (b *Blog) authorName(ctx context.Context, names map[domain.UserId]string, id domain.UserId, stored string) (string, error) {
+	if id == 0 {
+		return stored, nil
+	}
+
+	if name, ok := names[id]; ok {
+		return name, nil
+	}
+
+	user, err := b.storage.User(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("can not get author. id: %v. error: %w", id, err)
+	}
+
+	names[id] = user.DisplayName
+	return user.DisplayName, nil
+}
+
+func (b *Blog) Posts(ctx context.Context) (_ []*domain.Post, err error) {
+	ctx, end := startSpan(ctx, "Posts")
+	defer func() { end(err) }()
+
+	posts, err := b.storage.Posts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.withAuthorNames(ctx, posts)
+}
+
+// PostsPage returns a page of published posts. The authors see their own posts in any status, the admins see all the posts.
+func (b *Blog) PostsPage(ctx context.Context, q domain.PostsQuery) (_ *domain.Page, err error) {
+	ctx, end := startSpan(ctx, "PostsPage")
+	defer func() { end(err) }()
+
+	viewer, err := b.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q.Tags = domain.NormalizeTags(q.Tags)
+	q.PublishedOnly, q.Viewer = true, 0
+	if viewer != nil {
+		q.PublishedOnly = !viewer.principal.HasRole(auth.RoleAdmin)
+		q.Viewer = viewer.user.ID
+	}
+
+	page, err := b.storage.PostsPage(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := b.withAuthorNames(ctx, page.Posts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Page{Posts: posts, HasMore: page.HasMore}, nil
+}
+
+// Tags returns all the tags with the number of posts having them, the most used tags go first
+func (b *Blog) Tags(ctx context.Context) (_ []*domain.TagCount, err error) {
+	ctx, end := startSpan(ctx, "Tags")
+	defer func() { end(err) }()
+
+	return b.storage.Tags(ctx)
+}
+
+// IndexPosts adds all the stored posts to the search index.
+// The posts created through Blog are indexed right away, it's required for the posts stored before Blog is created.
+func (b *Blog) IndexPosts(ctx context.Context) (err error) {
+	ctx, end := startSpan(ctx, "IndexPosts")
+	defer func() { end(err) }()
+
+	posts, err := b.storage.Posts(ctx)
+	if err != nil {
+		return fmt.Errorf("can not index posts. error: %w", err)
+	}
+
+	for _, p := range posts {
+		b.indexPost(p)
+	}
+	return nil
+}
+
+// Search returns up to limit published posts matching the query ordered by relevance
+func (b *Blog) Search(ctx context.Context, query string, limit int) (_ []*domain.SearchResult, err error) {
+	ctx, end := startSpan(ctx, "Search")
+	defer func() { end(err) }()
+
+	hits := b.index.Search(query, limit)
+
+	names := map[domain.UserId]string{}
+	results := make([]*domain.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		post, err := b.storage.Post(ctx, hit.ID)
+		if httperr.HTTPStatusCode(err, 0) == http.StatusNotFound {
+			// The post is deleted concurrently
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if post.Status != domain.StatusPublished {
+			// The post is unpublished concurrently
+			continue
+		}
+
+		named := *post
+		if named.Author, err = b.authorName(ctx, names, post.AuthorID, post.Author); err != nil {
+			return nil, err
+		}
+
+		results = append(results, &domain.SearchResult{
+			Post:    &named,
+			Score:   hit.Score,
+			Snippet: search.Snippet(post.Content, query),
+		})
+	}
+
+	return results, nil
 }
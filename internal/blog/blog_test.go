@@ -3,12 +3,22 @@ package blog
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/auth"
 	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/tracing/tracingtest"
 	"github.com/voltento/go-blog-project/mocks"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type BlogTestSuite struct {
@@ -22,19 +32,47 @@ type BlogTestSuite struct {
 func (s *BlogTestSuite) SetupTest() {
 	s.mockStorage = new(mocks.Storage)
 	s.blog = NewBlog(s.mockStorage)
-	s.ctx = context.Background()
+	s.blog.now = func() time.Time { return testNow }
+	s.blog.passwordCost = bcrypt.MinCost
+	s.ctx = auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "1"})
 	s.postId = domain.PostId(1)
+	s.mockStorage.On("User", mock.Anything, testUserId).Return(testUser(), nil).Maybe()
+}
+
+var testNow = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// testUserId is the user the test changes are made by, testAuthor is the display name of the user
+const (
+	testUserId domain.UserId = 1
+	testAuthor               = "Author"
+)
+
+func testUser() *domain.User {
+	return &domain.User{ID: testUserId, Username: "author", DisplayName: testAuthor}
+}
+
+func (s *BlogTestSuite) storedPost() *domain.Post {
+	return &domain.Post{ID: s.postId, Title: "Title", Content: "Content", AuthorID: testUserId, Author: testAuthor, Status: domain.StatusPublished, Version: 1}
+}
+
+// withUser returns a context of the principal authenticated as the user
+func (s *BlogTestSuite) withUser(user *domain.User, roles ...string) context.Context {
+	s.mockStorage.On("User", mock.Anything, user.ID).Return(user, nil).Maybe()
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: strconv.Itoa(int(user.ID)), Roles: roles})
 }
 
 func (s *BlogTestSuite) TestCreatePost() {
 	newPost := &domain.Post{Title: "New Post", Content: "New Content", Author: "New Author"}
 
 	s.mockStorage.On("CreatePost", s.ctx, newPost).Return(s.postId, nil)
+	s.mockStorage.On("AddRevision", s.ctx, mock.Anything).Return(nil)
 
 	id, err := s.blog.CreatePost(s.ctx, newPost)
 
 	assert.NoError(s.T(), err)
 	assert.Equal(s.T(), s.postId, id)
+	assert.Equal(s.T(), testUserId, newPost.AuthorID, "the author should be the authenticated user")
+	assert.Equal(s.T(), testAuthor, newPost.Author)
 	s.mockStorage.AssertExpectations(s.T())
 }
 
@@ -50,7 +88,7 @@ func (s *BlogTestSuite) TestCreatePost_Error() {
 }
 
 func (s *BlogTestSuite) TestPost() {
-	post := &domain.Post{ID: 1, Title: "Test Post", Content: "Test Content", Author: "Test Author"}
+	post := &domain.Post{ID: 1, Title: "Test Post", Content: "Test Content", Author: "Test Author", Status: domain.StatusPublished}
 	postId := domain.PostId(1)
 
 	s.mockStorage.On("Post", s.ctx, postId).Return(post, nil)
@@ -75,10 +113,11 @@ func (s *BlogTestSuite) TestPost_Error() {
 
 func (s *BlogTestSuite) TestDeletePost() {
 	postId := domain.PostId(1)
+	s.mockStorage.On("Post", s.ctx, postId).Return(s.storedPost(), nil)
 
-	s.mockStorage.On("DeletePost", s.ctx, postId).Return(nil)
+	s.mockStorage.On("DeletePost", s.ctx, postId, domain.AnyVersion).Return(nil)
 
-	err := s.blog.DeletePost(s.ctx, postId)
+	err := s.blog.DeletePost(s.ctx, postId, domain.AnyVersion)
 
 	assert.NoError(s.T(), err)
 	s.mockStorage.AssertExpectations(s.T())
@@ -86,19 +125,44 @@ func (s *BlogTestSuite) TestDeletePost() {
 
 func (s *BlogTestSuite) TestDeletePost_Error() {
 	postId := domain.PostId(1)
-	s.mockStorage.On("DeletePost", s.ctx, postId).Return(errors.New("error"))
+	s.mockStorage.On("Post", s.ctx, postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("DeletePost", s.ctx, postId, domain.AnyVersion).Return(errors.New("error"))
 
-	err := s.blog.DeletePost(s.ctx, postId)
+	err := s.blog.DeletePost(s.ctx, postId, domain.AnyVersion)
 
 	assert.Error(s.T(), err)
 	s.mockStorage.AssertExpectations(s.T())
 }
 
+func (s *BlogTestSuite) TestDeletePost_Traced() {
+	exporter := tracingtest.Record(s.T())
+	var storageSpan trace.SpanContext
+	s.mockStorage.On("Post", mock.Anything, s.postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("DeletePost", mock.Anything, s.postId, 2).
+		Run(func(args mock.Arguments) {
+			storageSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+		}).
+		Return(httperr.WrapWithHttpCode(errors.New("version mismatch"), http.StatusPreconditionFailed))
+
+	err := s.blog.DeletePost(s.ctx, s.postId, 2)
+
+	s.Require().Error(err)
+	span := tracingtest.Span(s.T(), exporter, "Blog.DeletePost")
+	// The storage is called in the span of the method
+	assert.Equal(s.T(), span.SpanContext, storageSpan)
+	assert.Equal(s.T(), codes.Error, span.Status.Code)
+	assert.Equal(s.T(), "version mismatch", span.Status.Description)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
 func (s *BlogTestSuite) TestUpdatePost() {
 	updatedPost := &domain.Post{ID: 1, Title: "Updated Post", Content: "Updated Content", Author: "Updated Author"}
 	postId := domain.PostId(1)
 
+	s.mockStorage.On("Post", s.ctx, postId).Return(s.storedPost(), nil)
 	s.mockStorage.On("UpdatePost", s.ctx, updatedPost, postId).Return(nil)
+	s.mockStorage.On("Revision", s.ctx, postId, mock.Anything).Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+	s.mockStorage.On("AddRevision", s.ctx, mock.Anything).Return(nil)
 
 	err := s.blog.UpdatePost(s.ctx, updatedPost, postId)
 
@@ -109,6 +173,7 @@ func (s *BlogTestSuite) TestUpdatePost() {
 func (s *BlogTestSuite) TestUpdatePost_Error() {
 	updatedPost := &domain.Post{ID: 1, Title: "Updated Post", Content: "Updated Content", Author: "Updated Author"}
 
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(s.storedPost(), nil)
 	s.mockStorage.On("UpdatePost", s.ctx, updatedPost, s.postId).Return(errors.New("error"))
 
 	err := s.blog.UpdatePost(s.ctx, updatedPost, s.postId)
@@ -123,14 +188,384 @@ func (s *BlogTestSuite) TestPosts() {
 		{ID: 2, Title: "Post 2", Content: "Content 2", Author: "Author 2"},
 	}
 
-	s.mockStorage.On("Posts", s.ctx).Return(posts)
+	s.mockStorage.On("Posts", s.ctx).Return(posts, nil)
 
-	result := s.blog.Posts(s.ctx)
+	result, err := s.blog.Posts(s.ctx)
 
+	assert.NoError(s.T(), err)
 	assert.Equal(s.T(), posts, result)
 	s.mockStorage.AssertExpectations(s.T())
 }
 
+func (s *BlogTestSuite) TestPostsPage() {
+	q := domain.PostsQuery{After: 1, Limit: 10}
+	page := &domain.Page{Posts: []*domain.Post{{ID: 2, Title: "Post 2", Content: "Content 2", Author: "Author 2"}}}
+
+	s.mockStorage.On("PostsPage", s.ctx, domain.PostsQuery{After: 1, Limit: 10, PublishedOnly: true, Viewer: testUserId}).Return(page, nil)
+
+	result, err := s.blog.PostsPage(s.ctx, q)
+
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), page, result)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestIndexPosts() {
+	posts := []*domain.Post{
+		{ID: 1, Title: "Cooking", Content: "Boil pasta", Author: "Author 1", Status: domain.StatusPublished},
+		{ID: 2, Title: "Go", Content: "Channels and goroutines", Author: "Author 2", Status: domain.StatusPublished},
+	}
+	s.mockStorage.On("Posts", s.ctx).Return(posts, nil)
+	s.mockStorage.On("Post", s.ctx, domain.PostId(2)).Return(posts[1], nil)
+
+	s.Require().NoError(s.blog.IndexPosts(s.ctx))
+
+	results, err := s.blog.Search(s.ctx, "channel", 10)
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal(posts[1], results[0].Post)
+	s.Equal("<mark>Channels</mark> and goroutines", results[0].Snippet)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestIndexPosts_Error() {
+	s.mockStorage.On("Posts", s.ctx).Return(nil, errors.New("error"))
+
+	s.Error(s.blog.IndexPosts(s.ctx))
+}
+
+func (s *BlogTestSuite) TestSearch_IndexFollowsChanges() {
+	post := &domain.Post{Title: "Go", Content: "Channels", Author: "Author"}
+	s.mockStorage.On("CreatePost", s.ctx, post).Return(s.postId, nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Post).ID = s.postId
+	})
+	s.mockStorage.On("UpdatePost", s.ctx, mock.Anything, s.postId).Return(nil)
+	s.mockStorage.On("Revision", s.ctx, s.postId, mock.Anything).Return(&domain.Revision{}, nil)
+	s.mockStorage.On("AddRevision", s.ctx, mock.Anything).Return(nil)
+	s.mockStorage.On("DeletePost", s.ctx, s.postId, domain.AnyVersion).Return(nil)
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(post, nil)
+
+	_, err := s.blog.CreatePost(s.ctx, post)
+	s.Require().NoError(err)
+	results, err := s.blog.Search(s.ctx, "channels", 10)
+	s.Require().NoError(err)
+	s.Len(results, 1)
+
+	s.Require().NoError(s.blog.UpdatePost(s.ctx, &domain.Post{Title: "Rust", Content: "Ownership"}, s.postId))
+	results, err = s.blog.Search(s.ctx, "channels", 10)
+	s.Require().NoError(err)
+	s.Empty(results)
+
+	s.Require().NoError(s.blog.DeletePost(s.ctx, s.postId, domain.AnyVersion))
+	results, err = s.blog.Search(s.ctx, "ownership", 10)
+	s.Require().NoError(err)
+	s.Empty(results)
+}
+
+func (s *BlogTestSuite) TestSearch_SkipsDeletedPosts() {
+	s.mockStorage.On("Posts", s.ctx).Return([]*domain.Post{{ID: 1, Title: "Go"}}, nil)
+	s.mockStorage.On("Post", s.ctx, domain.PostId(1)).Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+	s.Require().NoError(s.blog.IndexPosts(s.ctx))
+
+	results, err := s.blog.Search(s.ctx, "go", 10)
+	s.NoError(err)
+	s.Empty(results)
+}
+
+func (s *BlogTestSuite) TestCreatePost_AddsRevision() {
+	post := &domain.Post{Title: "Title", Content: "line 1\nline 2", Author: "Author"}
+	s.mockStorage.On("CreatePost", s.ctx, post).Return(s.postId, nil).Run(func(args mock.Arguments) {
+		p := args.Get(1).(*domain.Post)
+		p.ID, p.Version = s.postId, 1
+	})
+	s.mockStorage.On("AddRevision", s.ctx, &domain.Revision{
+		PostID:    s.postId,
+		Number:    1,
+		Title:     "Title",
+		Content:   "line 1\nline 2",
+		Author:    "Author",
+		CreatedAt: testNow,
+		Diff:      "+line 1\n+line 2\n",
+	}).Return(nil)
+
+	_, err := s.blog.CreatePost(s.ctx, post)
+
+	s.NoError(err)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestUpdatePost_AddsRevisionWithDiff() {
+	post := &domain.Post{Title: "Title", Content: "line 1\nline 3"}
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("UpdatePost", s.ctx, post, s.postId).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Post).Version = 3
+	})
+	s.mockStorage.On("Revision", s.ctx, s.postId, 2).Return(&domain.Revision{Content: "line 1\nline 2"}, nil)
+	s.mockStorage.On("AddRevision", s.ctx, &domain.Revision{
+		PostID:    s.postId,
+		Number:    3,
+		Title:     "Title",
+		Content:   "line 1\nline 3",
+		Author:    testAuthor,
+		CreatedAt: testNow,
+		Diff:      " line 1\n-line 2\n+line 3\n",
+	}).Return(nil)
+
+	s.NoError(s.blog.UpdatePost(s.ctx, post, s.postId))
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestUpdatePost_RevisionError() {
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("UpdatePost", s.ctx, mock.Anything, s.postId).Return(nil)
+	s.mockStorage.On("Revision", s.ctx, s.postId, mock.Anything).Return(nil, errors.New("error"))
+
+	s.Error(s.blog.UpdatePost(s.ctx, &domain.Post{}, s.postId))
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestRevisions() {
+	revisions := []*domain.Revision{{PostID: s.postId, Number: 1}, {PostID: s.postId, Number: 2}}
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(&domain.Post{ID: s.postId, Status: domain.StatusPublished}, nil)
+	s.mockStorage.On("Revisions", s.ctx, s.postId).Return(revisions, nil)
+
+	result, err := s.blog.Revisions(s.ctx, s.postId)
+
+	s.NoError(err)
+	s.Equal(revisions, result)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestRevisions_PostNotFound() {
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+
+	_, err := s.blog.Revisions(s.ctx, s.postId)
+
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestRestoreRevision() {
+	s.mockStorage.On("Revision", s.ctx, s.postId, 1).Return(&domain.Revision{PostID: s.postId, Number: 1, Title: "Old", Content: "old", Author: "Author"}, nil)
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(&domain.Post{ID: s.postId, Title: "New", Content: "new", AuthorID: testUserId, Author: testAuthor, Tags: []string{"go"}, Status: domain.StatusPublished, Version: 2}, nil)
+	s.mockStorage.On("UpdatePost", s.ctx, mock.Anything, s.postId).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Post).Version = 3
+	})
+	s.mockStorage.On("Revision", s.ctx, s.postId, 2).Return(&domain.Revision{Content: "new"}, nil)
+	s.mockStorage.On("AddRevision", s.ctx, mock.MatchedBy(func(rev *domain.Revision) bool {
+		return rev.Number == 3 && rev.Content == "old" && rev.Diff == "-new\n+old\n"
+	})).Return(nil)
+
+	post, err := s.blog.RestoreRevision(s.ctx, s.postId, 1)
+
+	s.NoError(err)
+	s.Equal(&domain.Post{ID: s.postId, Title: "Old", Content: "old", AuthorID: testUserId, Author: testAuthor, Tags: []string{"go"}, Status: domain.StatusPublished, Version: 3}, post)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestRestoreRevision_NotFound() {
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("Revision", s.ctx, s.postId, 5).Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+
+	_, err := s.blog.RestoreRevision(s.ctx, s.postId, 5)
+
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestRestoreRevision_NotOwner() {
+	ctx := s.withUser(&domain.User{ID: 2, DisplayName: "Stranger"})
+	s.mockStorage.On("Post", ctx, s.postId).Return(s.storedPost(), nil)
+
+	for _, number := range []int{1, 5} {
+		_, err := s.blog.RestoreRevision(ctx, s.postId, number)
+		s.Equal(http.StatusForbidden, httperr.HTTPStatusCode(err, -1), "existing and missing revisions should not be told apart")
+	}
+
+	s.mockStorage.AssertNotCalled(s.T(), "Revision", mock.Anything, mock.Anything, mock.Anything)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestCreateComment() {
+	comment := &domain.Comment{PostID: s.postId, Author: "Author", Content: "Content"}
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("CreateComment", s.ctx, comment).Return(domain.CommentId(1), nil)
+
+	id, err := s.blog.CreateComment(s.ctx, comment)
+
+	s.NoError(err)
+	s.Equal(domain.CommentId(1), id)
+	s.Equal(testNow, comment.CreatedAt)
+	s.Equal(testNow, comment.UpdatedAt)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestCommentThreads() {
+	comments := []*domain.Comment{
+		{ID: 1, PostID: s.postId, Content: "first"},
+		{ID: 2, PostID: s.postId, ParentID: 1, Content: "reply"},
+		{ID: 3, PostID: s.postId, Content: "second"},
+		{ID: 4, PostID: s.postId, ParentID: 2, Content: "reply to reply"},
+	}
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(&domain.Post{ID: s.postId, Status: domain.StatusPublished}, nil)
+	s.mockStorage.On("Comments", s.ctx, s.postId).Return(comments, nil)
+
+	threads, err := s.blog.CommentThreads(s.ctx, s.postId)
+
+	s.NoError(err)
+	noReplies := []*domain.CommentThread{}
+	s.Equal([]*domain.CommentThread{
+		{Comment: comments[0], Replies: []*domain.CommentThread{
+			{Comment: comments[1], Replies: []*domain.CommentThread{
+				{Comment: comments[3], Replies: noReplies},
+			}},
+		}},
+		{Comment: comments[2], Replies: noReplies},
+	}, threads)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestCommentThreads_PostNotFound() {
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+
+	_, err := s.blog.CommentThreads(s.ctx, s.postId)
+
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestUpdateComment() {
+	comment := &domain.Comment{ID: 1, PostID: s.postId, Content: "Updated"}
+	s.mockStorage.On("Comment", s.ctx, s.postId, domain.CommentId(1)).Return(&domain.Comment{ID: 1, PostID: s.postId, AuthorID: testUserId}, nil)
+	s.mockStorage.On("UpdateComment", s.ctx, comment).Return(nil)
+
+	s.NoError(s.blog.UpdateComment(s.ctx, comment))
+	s.Equal(testNow, comment.UpdatedAt)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestCreatePost_NormalizesTags() {
+	post := &domain.Post{Title: "Title", Tags: []string{" Go", "k8s", "", "go"}}
+	s.mockStorage.On("CreatePost", s.ctx, post).Return(s.postId, nil)
+	s.mockStorage.On("AddRevision", s.ctx, mock.Anything).Return(nil)
+
+	_, err := s.blog.CreatePost(s.ctx, post)
+
+	s.NoError(err)
+	s.Equal([]string{"go", "k8s"}, post.Tags)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestPostsPage_NormalizesTags() {
+	q := domain.PostsQuery{Limit: 10, Tags: []string{"K8s", "go"}, TagMatch: domain.MatchAnyTag}
+	expected := domain.PostsQuery{Limit: 10, Tags: []string{"go", "k8s"}, TagMatch: domain.MatchAnyTag, PublishedOnly: true, Viewer: testUserId}
+	s.mockStorage.On("PostsPage", s.ctx, expected).Return(&domain.Page{}, nil)
+
+	_, err := s.blog.PostsPage(s.ctx, q)
+
+	s.NoError(err)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestChanges_Unauthenticated() {
+	ctx := context.Background()
+
+	_, err := s.blog.CreatePost(ctx, &domain.Post{Title: "Title"})
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1))
+
+	err = s.blog.UpdatePost(ctx, &domain.Post{Title: "Title"}, s.postId)
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1))
+
+	err = s.blog.DeletePost(ctx, s.postId, domain.AnyVersion)
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1))
+
+	_, err = s.blog.CreateComment(ctx, &domain.Comment{PostID: s.postId})
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1))
+
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestChanges_UnknownUser() {
+	s.mockStorage.On("User", mock.Anything, domain.UserId(7)).Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+
+	for _, subject := range []string{"7", "alice"} {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject})
+		_, err := s.blog.CreatePost(ctx, &domain.Post{Title: "Title"})
+		s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1), subject)
+	}
+}
+
+func (s *BlogTestSuite) TestPost_CurrentAuthorName() {
+	stored := &domain.Post{ID: s.postId, AuthorID: testUserId, Author: "Old Name"}
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(stored, nil)
+
+	post, err := s.blog.Post(s.ctx, s.postId)
+
+	s.NoError(err)
+	s.Equal(testAuthor, post.Author)
+	s.Equal("Old Name", stored.Author, "the stored post should not be changed")
+}
+
+func (s *BlogTestSuite) TestCommentThreads_CurrentAuthorNames() {
+	comments := []*domain.Comment{
+		{ID: 1, PostID: s.postId, AuthorID: testUserId, Author: "Old Name"},
+		{ID: 2, PostID: s.postId, Author: "Imported"},
+	}
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(&domain.Post{ID: s.postId, Status: domain.StatusPublished}, nil)
+	s.mockStorage.On("Comments", s.ctx, s.postId).Return(comments, nil)
+
+	threads, err := s.blog.CommentThreads(s.ctx, s.postId)
+
+	s.Require().NoError(err)
+	s.Equal(testAuthor, threads[0].Comment.Author)
+	s.Equal("Imported", threads[1].Comment.Author, "comments without an author id keep the stored name")
+}
+
+func (s *BlogTestSuite) TestChanges_NotOwner() {
+	ctx := s.withUser(&domain.User{ID: 2, DisplayName: "Stranger"})
+	s.mockStorage.On("Post", ctx, s.postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("Comment", ctx, s.postId, domain.CommentId(1)).Return(&domain.Comment{ID: 1, PostID: s.postId, AuthorID: testUserId}, nil)
+
+	err := s.blog.UpdatePost(ctx, &domain.Post{Title: "Title"}, s.postId)
+	s.Equal(http.StatusForbidden, httperr.HTTPStatusCode(err, -1))
+
+	err = s.blog.DeletePost(ctx, s.postId, domain.AnyVersion)
+	s.Equal(http.StatusForbidden, httperr.HTTPStatusCode(err, -1))
+
+	err = s.blog.UpdateComment(ctx, &domain.Comment{ID: 1, PostID: s.postId, Content: "Updated"})
+	s.Equal(http.StatusForbidden, httperr.HTTPStatusCode(err, -1))
+
+	err = s.blog.DeleteComment(ctx, s.postId, 1)
+	s.Equal(http.StatusForbidden, httperr.HTTPStatusCode(err, -1))
+
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestUpdatePost_Admin() {
+	ctx := s.withUser(&domain.User{ID: 3, DisplayName: "Moderator"}, auth.RoleAdmin)
+	post := &domain.Post{Title: "Moderated", Content: "Content"}
+	s.mockStorage.On("Post", ctx, s.postId).Return(s.storedPost(), nil)
+	s.mockStorage.On("UpdatePost", ctx, post, s.postId).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Post).Version = 2
+	})
+	s.mockStorage.On("Revision", ctx, s.postId, 1).Return(&domain.Revision{Content: "Content"}, nil)
+	s.mockStorage.On("AddRevision", ctx, mock.MatchedBy(func(rev *domain.Revision) bool {
+		return rev.Author == "Moderator"
+	})).Return(nil)
+
+	s.NoError(s.blog.UpdatePost(ctx, post, s.postId))
+	s.Equal(testUserId, post.AuthorID, "the post should keep its author")
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestDeletePost_NotExisting() {
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+	s.mockStorage.On("DeletePost", s.ctx, s.postId, domain.AnyVersion).Return(nil)
+
+	s.NoError(s.blog.DeletePost(s.ctx, s.postId, domain.AnyVersion))
+	s.mockStorage.AssertExpectations(s.T())
+}
+
 func TestBlogTestSuite(t *testing.T) {
 	suite.Run(t, new(BlogTestSuite))
 }
@@ -0,0 +1,255 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/bulk"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/exp/slog"
+)
+
+// exportPageSize is the number of posts read from storage at once on export
+const exportPageSize = 500
+
+// maxImportErrors is the number of row errors the import reports, the rest of the rows are not checked
+const maxImportErrors = 100
+
+// ExportPosts passes all the posts to fn ordered by id. The posts are read from storage page by page,
+// so they are not kept in memory at once. It's allowed to admins only.
+func (b *Blog) ExportPosts(ctx context.Context, fn func(post *domain.Post) error) (err error) {
+	ctx, end := startSpan(ctx, "ExportPosts")
+	defer func() { end(err) }()
+
+	if _, err := b.currentAdmin(ctx); err != nil {
+		return err
+	}
+
+	q := domain.PostsQuery{Limit: exportPageSize}
+	for {
+		page, err := b.storage.PostsPage(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		posts, err := b.withAuthorNames(ctx, page.Posts)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range posts {
+			if err := fn(post); err != nil {
+				return err
+			}
+		}
+
+		if !page.HasMore || len(page.Posts) == 0 {
+			return nil
+		}
+		q.After = page.Posts[len(page.Posts)-1].ID
+	}
+}
+
+// ImportPosts creates the posts read from the decoders returned by open and returns their number.
+// open returns a new decoder reading the posts from the start. It's allowed to admins only.
+// The rows are checked all or nothing: the posts are read twice, all the rows are checked first and the posts are created
+// only if every row is valid, *bulk.ImportError lists the invalid rows otherwise. If storage fails on creation,
+// the posts created by the import are deleted.
+// The import is not atomic though. The posts are created one by one, so the readers see the imported posts before
+// the import is done or rolled back, and the posts created before the service is stopped are kept.
+// The posts get new ids and timestamps. A post is written by the user of its AuthorID, which should exist,
+// or by the admin if AuthorID is zero. The published posts keep their publish time.
+func (b *Blog) ImportPosts(ctx context.Context, open func() (bulk.Decoder, error)) (_ int, err error) {
+	ctx, end := startSpan(ctx, "ImportPosts")
+	defer func() { end(err) }()
+
+	admin, err := b.currentAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	im := &importer{blog: b, admin: admin, authors: map[domain.UserId]*domain.User{}}
+	if err := im.check(ctx, open); err != nil {
+		return 0, err
+	}
+
+	n, err := im.create(ctx, open)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("posts imported", "count", n, "admin", admin.user.ID)
+	return n, nil
+}
+
+// currentAdmin returns the authenticated user if it's an admin
+func (b *Blog) currentAdmin(ctx context.Context) (*editor, error) {
+	e, err := b.currentEditor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.principal.HasRole(auth.RoleAdmin) {
+		err := fmt.Errorf("only an admin can do it. user id: %v", e.user.ID)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusForbidden)
+	}
+
+	return e, nil
+}
+
+type importer struct {
+	blog  *Blog
+	admin *editor
+	// authors caches the authors of the imported posts
+	authors map[domain.UserId]*domain.User
+}
+
+// check reads all the posts of the import and returns *bulk.ImportError if any of them can not be imported
+func (im *importer) check(ctx context.Context, open func() (bulk.Decoder, error)) error {
+	dec, err := open()
+	if err != nil {
+		return err
+	}
+
+	importErr := &bulk.ImportError{}
+	for {
+		post, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowErr *bulk.RowError
+		switch {
+		case errors.As(err, &rowErr):
+		case err != nil:
+			return err
+		default:
+			if err := im.prepare(ctx, post); err != nil {
+				rowErr = &bulk.RowError{Row: dec.Row(), Err: err}
+			}
+		}
+
+		if rowErr == nil {
+			continue
+		}
+		if len(importErr.Rows) == maxImportErrors {
+			importErr.Truncated = true
+			break
+		}
+		importErr.Rows = append(importErr.Rows, rowErr)
+	}
+
+	if len(importErr.Rows) > 0 {
+		return httperr.WrapWithHttpCode(importErr, http.StatusUnprocessableEntity)
+	}
+	return nil
+}
+
+// create reads the posts of the checked import again and creates them
+func (im *importer) create(ctx context.Context, open func() (bulk.Decoder, error)) (int, error) {
+	dec, err := open()
+	if err != nil {
+		return 0, err
+	}
+
+	var created []domain.PostId
+	for {
+		post, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return len(created), nil
+		}
+
+		if err == nil {
+			err = im.prepare(ctx, post)
+		}
+		if err == nil {
+			_, err = im.blog.storage.CreatePost(ctx, post)
+		}
+		if err != nil {
+			return 0, im.rollback(created, err)
+		}
+
+		created = append(created, post.ID)
+		im.blog.indexPost(post)
+		if err := im.blog.addRevision(ctx, post, "", im.admin.user.DisplayName); err != nil {
+			return 0, im.rollback(created, err)
+		}
+	}
+}
+
+// rollback deletes the created posts and returns the error of the import. The deletion is not canceled
+// with the request, so the import is not left half done. The error lists the posts which are not deleted.
+func (im *importer) rollback(created []domain.PostId, importErr error) error {
+	ctx := context.Background()
+	var left []domain.PostId
+	for _, id := range created {
+		im.blog.index.Remove(id)
+		if err := im.blog.storage.DeletePost(ctx, id, domain.AnyVersion); err != nil {
+			slog.Error("can not delete imported post on rollback", "id", id, "error", err)
+			left = append(left, id)
+		}
+	}
+
+	if len(left) > 0 {
+		return fmt.Errorf("import is partially rolled back, posts %v are left. error: %w", left, importErr)
+	}
+	return fmt.Errorf("import is rolled back. error: %w", importErr)
+}
+
+// prepare checks the imported post and sets the fields the stored post gets
+func (im *importer) prepare(ctx context.Context, post *domain.Post) error {
+	if strings.TrimSpace(post.Title) == "" || strings.TrimSpace(post.Content) == "" {
+		return errors.New("title and content are required")
+	}
+
+	if post.Status != "" && !post.Status.IsValid() {
+		return fmt.Errorf("status '%s' should be one of draft, published, scheduled or archived", post.Status)
+	}
+
+	author, err := im.author(ctx, post.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	publishAt := post.PublishAt
+	if err := im.blog.setLifecycle(post, nil); err != nil {
+		return err
+	}
+	// The published and the archived posts keep the time they went live
+	if (post.Status == domain.StatusPublished || post.Status == domain.StatusArchived) && !publishAt.IsZero() {
+		post.PublishAt = publishAt.UTC()
+	}
+
+	post.ID, post.Version = 0, 0
+	post.AuthorID, post.Author = author.ID, author.DisplayName
+	post.Tags = domain.NormalizeTags(post.Tags)
+	return nil
+}
+
+// author returns the author of an imported post, the zero id is the admin
+func (im *importer) author(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	if id == 0 {
+		return im.admin.user, nil
+	}
+
+	if user, ok := im.authors[id]; ok {
+		return user, nil
+	}
+
+	user, err := im.blog.storage.User(ctx, id)
+	if httperr.HTTPStatusCode(err, 0) == http.StatusNotFound {
+		return nil, fmt.Errorf("author is not found. author id: %v", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	im.authors[id] = user
+	return user, nil
+}
@@ -0,0 +1,174 @@
+package blog
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/bulk"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+var testAdmin = &domain.User{ID: 7, Username: "admin", DisplayName: "Admin"}
+
+// openImport returns the opener of the JSON Lines import
+func openImport(lines ...string) func() (bulk.Decoder, error) {
+	return func() (bulk.Decoder, error) {
+		return bulk.NewDecoder(bulk.JSONLines, strings.NewReader(strings.Join(lines, "\n")))
+	}
+}
+
+func (s *BlogTestSuite) TestImportPosts() {
+	ctx := s.withUser(testAdmin, auth.RoleAdmin)
+	var created []*domain.Post
+	s.mockStorage.On("CreatePost", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		post := args.Get(1).(*domain.Post)
+		post.ID, post.Version = domain.PostId(len(created)+1), 1
+		created = append(created, post)
+	}).Return(domain.PostId(0), nil)
+	s.mockStorage.On("AddRevision", ctx, mock.Anything).Return(nil)
+
+	n, err := s.blog.ImportPosts(ctx, openImport(
+		`{"ID":100,"Title":"First","Content":"content","Tags":["Go"],"PublishAt":"2020-01-02T03:04:05Z","Version":5}`,
+		``,
+		`{"Title":"Second","Content":"content","AuthorID":1,"Status":"draft"}`,
+	))
+
+	s.Require().NoError(err)
+	s.Equal(2, n)
+	s.Require().Len(created, 2)
+
+	s.Equal(testAdmin.ID, created[0].AuthorID, "the post without author should be written by the admin")
+	s.Equal("Admin", created[0].Author)
+	s.Equal([]string{"go"}, created[0].Tags)
+	s.Equal(domain.StatusPublished, created[0].Status)
+	s.Equal("2020-01-02T03:04:05Z", created[0].PublishAt.Format("2006-01-02T15:04:05Z07:00"), "the publish time should be kept")
+
+	s.Equal(testUserId, created[1].AuthorID)
+	s.Equal(testAuthor, created[1].Author)
+	s.Equal(domain.StatusDraft, created[1].Status)
+	s.True(created[1].PublishAt.IsZero())
+}
+
+func (s *BlogTestSuite) TestImportPosts_InvalidRows() {
+	ctx := s.withUser(testAdmin, auth.RoleAdmin)
+	notFound := httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound)
+	s.mockStorage.On("User", mock.Anything, domain.UserId(100)).Return(nil, notFound)
+
+	_, err := s.blog.ImportPosts(ctx, openImport(
+		`{"Title":"Valid","Content":"content"}`,
+		`{"Title":"","Content":"content"}`,
+		`{"Title":"Broken"`,
+		`{"Title":"Unknown author","Content":"content","AuthorID":100}`,
+		`{"Title":"Scheduled","Content":"content","Status":"scheduled"}`,
+		`{"Title":"Unknown field","Content":"content","Body":"content"}`,
+	))
+
+	s.Equal(http.StatusUnprocessableEntity, httperr.HTTPStatusCode(err, 0))
+	var importErr *bulk.ImportError
+	s.Require().ErrorAs(err, &importErr)
+
+	var rows []int
+	for _, row := range importErr.Rows {
+		rows = append(rows, row.Row)
+	}
+	s.Equal([]int{2, 3, 4, 5, 6}, rows)
+	s.False(importErr.Truncated)
+	s.mockStorage.AssertNotCalled(s.T(), "CreatePost", mock.Anything, mock.Anything)
+}
+
+func (s *BlogTestSuite) TestImportPosts_TooManyErrors() {
+	ctx := s.withUser(testAdmin, auth.RoleAdmin)
+	lines := make([]string, maxImportErrors+10)
+	for i := range lines {
+		lines[i] = `{"Title":""}`
+	}
+
+	_, err := s.blog.ImportPosts(ctx, openImport(lines...))
+
+	var importErr *bulk.ImportError
+	s.Require().ErrorAs(err, &importErr)
+	s.Len(importErr.Rows, maxImportErrors)
+	s.True(importErr.Truncated)
+}
+
+func (s *BlogTestSuite) TestImportPosts_RollsBack() {
+	ctx := s.withUser(testAdmin, auth.RoleAdmin)
+	s.mockStorage.On("CreatePost", ctx, mock.MatchedBy(func(p *domain.Post) bool { return p.Title == "First" })).
+		Run(func(args mock.Arguments) { args.Get(1).(*domain.Post).ID = 10 }).
+		Return(domain.PostId(10), nil)
+	s.mockStorage.On("CreatePost", ctx, mock.MatchedBy(func(p *domain.Post) bool { return p.Title == "Second" })).
+		Return(domain.PostId(0), errors.New("storage is down"))
+	s.mockStorage.On("AddRevision", ctx, mock.Anything).Return(nil)
+	s.mockStorage.On("DeletePost", mock.Anything, domain.PostId(10), domain.AnyVersion).Return(nil)
+
+	n, err := s.blog.ImportPosts(ctx, openImport(
+		`{"Title":"First","Content":"content"}`,
+		`{"Title":"Second","Content":"content"}`,
+	))
+
+	s.ErrorContains(err, "storage is down")
+	s.Zero(n)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestImportPosts_PartialRollback() {
+	ctx := s.withUser(testAdmin, auth.RoleAdmin)
+	var id domain.PostId
+	s.mockStorage.On("CreatePost", ctx, mock.MatchedBy(func(p *domain.Post) bool { return p.Title != "Third" })).
+		Run(func(args mock.Arguments) {
+			id++
+			args.Get(1).(*domain.Post).ID = id
+		}).
+		Return(domain.PostId(0), nil)
+	s.mockStorage.On("CreatePost", ctx, mock.MatchedBy(func(p *domain.Post) bool { return p.Title == "Third" })).
+		Return(domain.PostId(0), errors.New("storage is down"))
+	s.mockStorage.On("AddRevision", ctx, mock.Anything).Return(nil)
+	s.mockStorage.On("DeletePost", mock.Anything, domain.PostId(1), domain.AnyVersion).Return(nil)
+	s.mockStorage.On("DeletePost", mock.Anything, domain.PostId(2), domain.AnyVersion).Return(errors.New("storage is down"))
+
+	_, err := s.blog.ImportPosts(ctx, openImport(
+		`{"Title":"First","Content":"content"}`,
+		`{"Title":"Second","Content":"content"}`,
+		`{"Title":"Third","Content":"content"}`,
+	))
+
+	// The import is not atomic, the post which can not be deleted is left and reported
+	s.ErrorContains(err, "posts [2] are left")
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestBulk_AdminOnly() {
+	_, err := s.blog.ImportPosts(s.ctx, openImport(`{"Title":"Title","Content":"content"}`))
+	s.Equal(http.StatusForbidden, httperr.HTTPStatusCode(err, 0))
+
+	err = s.blog.ExportPosts(s.ctx, func(*domain.Post) error { return nil })
+	s.Equal(http.StatusForbidden, httperr.HTTPStatusCode(err, 0))
+
+	s.mockStorage.AssertNotCalled(s.T(), "CreatePost", mock.Anything, mock.Anything)
+	s.mockStorage.AssertNotCalled(s.T(), "PostsPage", mock.Anything, mock.Anything)
+}
+
+func (s *BlogTestSuite) TestExportPosts() {
+	ctx := s.withUser(testAdmin, auth.RoleAdmin)
+	first := &domain.Post{ID: 1, Title: "First", AuthorID: testUserId, Author: "Old name", Status: domain.StatusDraft}
+	second := &domain.Post{ID: 2, Title: "Second", Status: domain.StatusPublished}
+	s.mockStorage.On("PostsPage", ctx, domain.PostsQuery{Limit: exportPageSize}).
+		Return(&domain.Page{Posts: []*domain.Post{first}, HasMore: true}, nil)
+	s.mockStorage.On("PostsPage", ctx, domain.PostsQuery{Limit: exportPageSize, After: 1}).
+		Return(&domain.Page{Posts: []*domain.Post{second}}, nil)
+
+	var exported []*domain.Post
+	err := s.blog.ExportPosts(ctx, func(post *domain.Post) error {
+		exported = append(exported, post)
+		return nil
+	})
+
+	s.Require().NoError(err)
+	s.Require().Len(exported, 2)
+	s.Equal(testAuthor, exported[0].Author, "the posts should have the current names of the authors")
+	s.Equal("Second", exported[1].Title)
+}
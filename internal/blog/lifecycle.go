@@ -0,0 +1,143 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/exp/slog"
+)
+
+// setLifecycle checks the status of the post and sets its publish time. stored is nil for a new post.
+// A post keeps its stored status if no status is set.
+func (b *Blog) setLifecycle(post, stored *domain.Post) error {
+	if post.Status == "" {
+		post.Status = domain.StatusPublished
+		if stored != nil {
+			post.Status = stored.Status
+		}
+	}
+
+	wasPublished := stored != nil && (stored.Status == domain.StatusPublished || stored.Status == domain.StatusArchived)
+	switch post.Status {
+	case domain.StatusPublished, domain.StatusArchived:
+		// The publish time is the time the post went live first
+		if wasPublished {
+			post.PublishAt = stored.PublishAt
+		} else if post.Status == domain.StatusPublished {
+			post.PublishAt = b.now().UTC()
+		} else {
+			post.PublishAt = time.Time{}
+		}
+	case domain.StatusScheduled:
+		if post.PublishAt.IsZero() {
+			err := errors.New("publish time is required for a scheduled post")
+			return httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+		}
+		post.PublishAt = post.PublishAt.UTC()
+	case domain.StatusDraft:
+		post.PublishAt = time.Time{}
+	default:
+		err := fmt.Errorf("unknown post status '%s'", post.Status)
+		return httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// viewer returns the authenticated user the request is made by, it's nil for anonymous readers
+func (b *Blog) viewer(ctx context.Context) (*editor, error) {
+	if _, ok := auth.PrincipalFrom(ctx); !ok {
+		return nil, nil
+	}
+
+	return b.currentEditor(ctx)
+}
+
+// visiblePost returns the post if the viewer can see it. The posts which are hidden from the viewer are not found.
+func (b *Blog) visiblePost(ctx context.Context, id domain.PostId) (*domain.Post, error) {
+	post, err := b.storage.Post(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.Status == domain.StatusPublished {
+		return post, nil
+	}
+
+	viewer, err := b.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if viewer == nil || !viewer.canChange(post.AuthorID) {
+		err := fmt.Errorf("blog not found. id: %v", id)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	return post, nil
+}
+
+// indexPost makes the post searchable while it's published
+func (b *Blog) indexPost(p *domain.Post) {
+	if p.Status == domain.StatusPublished {
+		b.index.Add(p)
+	} else {
+		b.index.Remove(p.ID)
+	}
+}
+
+// PublishScheduled publishes the scheduled posts which publish time has come
+func (b *Blog) PublishScheduled(ctx context.Context) (err error) {
+	ctx, end := startSpan(ctx, "PublishScheduled")
+	defer func() { end(err) }()
+
+	due, err := b.storage.ScheduledPosts(ctx, b.now())
+	if err != nil {
+		return fmt.Errorf("can not get scheduled posts. error: %w", err)
+	}
+
+	for _, p := range due {
+		published := *p
+		published.Status = domain.StatusPublished
+		err := b.storage.UpdatePost(ctx, &published, p.ID)
+		if httperr.HTTPStatusCode(err, 0) == http.StatusPreconditionFailed {
+			// The post is changed concurrently, it's published on the next run if it's still scheduled
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("can not publish scheduled post. id: %v. error: %w", p.ID, err)
+		}
+
+		b.indexPost(&published)
+		if err := b.addRevision(ctx, &published, p.Content, p.Author); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunScheduler publishes the scheduled posts every interval till ctx is done
+func (b *Blog) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.PublishScheduled(ctx); err != nil {
+			slog.Error("scheduled posts are not published", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+func (s *BlogTestSuite) TestCreatePost_Lifecycle() {
+	publishAt := testNow.Add(time.Hour)
+	tests := []struct {
+		name              string
+		post              *domain.Post
+		expectedStatus    domain.PostStatus
+		expectedPublishAt time.Time
+	}{
+		{name: "published by default", post: &domain.Post{}, expectedStatus: domain.StatusPublished, expectedPublishAt: testNow},
+		{name: "published now", post: &domain.Post{Status: domain.StatusPublished, PublishAt: publishAt}, expectedStatus: domain.StatusPublished, expectedPublishAt: testNow},
+		{name: "draft", post: &domain.Post{Status: domain.StatusDraft, PublishAt: publishAt}, expectedStatus: domain.StatusDraft},
+		{name: "scheduled", post: &domain.Post{Status: domain.StatusScheduled, PublishAt: publishAt}, expectedStatus: domain.StatusScheduled, expectedPublishAt: publishAt},
+	}
+
+	s.mockStorage.On("CreatePost", s.ctx, mock.Anything).Return(s.postId, nil)
+	s.mockStorage.On("AddRevision", s.ctx, mock.Anything).Return(nil)
+	for _, tt := range tests {
+		_, err := s.blog.CreatePost(s.ctx, tt.post)
+		s.Require().NoError(err, tt.name)
+		s.Equal(tt.expectedStatus, tt.post.Status, tt.name)
+		s.Equal(tt.expectedPublishAt, tt.post.PublishAt, tt.name)
+	}
+}
+
+func (s *BlogTestSuite) TestCreatePost_WrongLifecycle() {
+	_, err := s.blog.CreatePost(s.ctx, &domain.Post{Status: domain.StatusScheduled})
+	s.Equal(http.StatusBadRequest, httperr.HTTPStatusCode(err, -1), "scheduled post should have a publish time")
+
+	_, err = s.blog.CreatePost(s.ctx, &domain.Post{Status: "hidden"})
+	s.Equal(http.StatusBadRequest, httperr.HTTPStatusCode(err, -1))
+	s.mockStorage.AssertNotCalled(s.T(), "CreatePost", mock.Anything, mock.Anything)
+}
+
+func (s *BlogTestSuite) TestUpdatePost_KeepsLifecycle() {
+	stored := s.storedPost()
+	stored.PublishAt = testNow.Add(-time.Hour)
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(stored, nil)
+	s.mockStorage.On("UpdatePost", s.ctx, mock.Anything, s.postId).Return(nil)
+	s.mockStorage.On("Revision", s.ctx, s.postId, mock.Anything).Return(&domain.Revision{}, nil)
+	s.mockStorage.On("AddRevision", s.ctx, mock.Anything).Return(nil)
+
+	post := &domain.Post{Title: "Updated"}
+	s.Require().NoError(s.blog.UpdatePost(s.ctx, post, s.postId))
+	s.Equal(domain.StatusPublished, post.Status, "the stored status should be kept")
+	s.Equal(stored.PublishAt, post.PublishAt, "the first publish time should be kept")
+
+	post = &domain.Post{Title: "Archived", Status: domain.StatusArchived}
+	s.Require().NoError(s.blog.UpdatePost(s.ctx, post, s.postId))
+	s.Equal(stored.PublishAt, post.PublishAt)
+}
+
+func (s *BlogTestSuite) TestPost_NotPublished() {
+	draft := &domain.Post{ID: s.postId, AuthorID: testUserId, Status: domain.StatusDraft}
+	s.mockStorage.On("Post", mock.Anything, s.postId).Return(draft, nil)
+
+	_, err := s.blog.Post(context.Background(), s.postId)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1), "anonymous readers should not see drafts")
+
+	_, err = s.blog.Post(s.withUser(&domain.User{ID: 2}), s.postId)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1), "other users should not see drafts")
+
+	_, err = s.blog.Post(s.ctx, s.postId)
+	s.NoError(err, "the author should see own drafts")
+
+	_, err = s.blog.Post(s.withUser(&domain.User{ID: 3}, auth.RoleAdmin), s.postId)
+	s.NoError(err, "admins should see drafts")
+}
+
+func (s *BlogTestSuite) TestPostsPage_Visibility() {
+	s.mockStorage.On("PostsPage", mock.Anything, domain.PostsQuery{Limit: 10, PublishedOnly: true}).Return(&domain.Page{}, nil).Once()
+	_, err := s.blog.PostsPage(context.Background(), domain.PostsQuery{Limit: 10, Viewer: 5})
+	s.NoError(err)
+
+	admin := s.withUser(&domain.User{ID: 3}, auth.RoleAdmin)
+	s.mockStorage.On("PostsPage", admin, domain.PostsQuery{Limit: 10, Viewer: 3}).Return(&domain.Page{}, nil).Once()
+	_, err = s.blog.PostsPage(admin, domain.PostsQuery{Limit: 10, PublishedOnly: true})
+	s.NoError(err)
+
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestPublishScheduled() {
+	scheduled := &domain.Post{ID: s.postId, Title: "Go", Content: "Channels", Author: testAuthor, Status: domain.StatusScheduled, PublishAt: testNow, Version: 2}
+	s.mockStorage.On("ScheduledPosts", s.ctx, testNow).Return([]*domain.Post{scheduled}, nil)
+	s.mockStorage.On("UpdatePost", s.ctx, mock.MatchedBy(func(p *domain.Post) bool {
+		return p.Status == domain.StatusPublished && p.PublishAt == testNow && p.Version == 2
+	}), s.postId).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Post).Version = 3
+	})
+	s.mockStorage.On("AddRevision", s.ctx, mock.MatchedBy(func(rev *domain.Revision) bool {
+		return rev.Number == 3 && rev.Author == testAuthor && rev.Diff == ""
+	})).Return(nil)
+	s.mockStorage.On("Post", s.ctx, s.postId).Return(&domain.Post{ID: s.postId, Content: "Channels", Status: domain.StatusPublished}, nil)
+
+	s.Require().NoError(s.blog.PublishScheduled(s.ctx))
+
+	s.Equal(domain.StatusScheduled, scheduled.Status, "the stored post should not be changed")
+	results, err := s.blog.Search(s.ctx, "channels", 10)
+	s.Require().NoError(err)
+	s.Len(results, 1, "the published post should be searchable")
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestPublishScheduled_ChangedConcurrently() {
+	s.mockStorage.On("ScheduledPosts", s.ctx, testNow).Return([]*domain.Post{{ID: s.postId, Status: domain.StatusScheduled, Version: 2}}, nil)
+	s.mockStorage.On("UpdatePost", s.ctx, mock.Anything, s.postId).Return(httperr.WrapWithHttpCode(errors.New("version mismatch"), http.StatusPreconditionFailed))
+
+	s.NoError(s.blog.PublishScheduled(s.ctx))
+	s.mockStorage.AssertNotCalled(s.T(), "AddRevision", mock.Anything, mock.Anything)
+}
+
+func (s *BlogTestSuite) TestRunScheduler_StopsOnDone() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+	s.mockStorage.On("ScheduledPosts", ctx, testNow).Return([]*domain.Post{}, nil).Once()
+
+	s.blog.RunScheduler(ctx, time.Hour)
+	s.mockStorage.AssertExpectations(s.T())
+}
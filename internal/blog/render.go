@@ -0,0 +1,86 @@
+package blog
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/markdown"
+)
+
+// renderCacheSize is the number of rendered post versions kept
+const renderCacheSize = 1024
+
+// RenderPost returns the content of the post rendered to sanitized HTML.
+// The rendered content is cached by the post id, version and content hash, so repeat reads of a version are not
+// rendered again. The hash tells apart the posts recreated with the id and version of a deleted post.
+func (b *Blog) RenderPost(post *domain.Post) (*markdown.Document, error) {
+	key := renderKey{id: post.ID, version: post.Version, hash: sha256.Sum256([]byte(post.Content))}
+	if doc, ok := b.rendered.get(key); ok {
+		return doc, nil
+	}
+
+	doc, err := b.renderer.Render(post.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	b.rendered.put(key, doc)
+	return doc, nil
+}
+
+type renderKey struct {
+	id      domain.PostId
+	version int
+	hash    [sha256.Size]byte
+}
+
+// renderCache keeps the recently used rendered posts. It's safe for concurrent use.
+type renderCache struct {
+	mtx      sync.Mutex
+	capacity int
+	// order keeps the entries, the recently used go first
+	order   *list.List
+	entries map[renderKey]*list.Element
+}
+
+type renderEntry struct {
+	key renderKey
+	doc *markdown.Document
+}
+
+func newRenderCache(capacity int) *renderCache {
+	return &renderCache{capacity: capacity, order: list.New(), entries: map[renderKey]*list.Element{}}
+}
+
+func (c *renderCache) get(key renderKey) (*markdown.Document, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	return e.Value.(*renderEntry).doc, true
+}
+
+func (c *renderCache) put(key renderKey, doc *markdown.Document) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*renderEntry).doc = doc
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&renderEntry{key: key, doc: doc})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*renderEntry).key)
+	}
+}
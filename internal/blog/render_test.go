@@ -0,0 +1,53 @@
+package blog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/markdown"
+)
+
+func (s *BlogTestSuite) TestRenderPost() {
+	doc, err := s.blog.RenderPost(&domain.Post{ID: 1, Content: "# Title\n\n*text*", Version: 1})
+	s.Require().NoError(err)
+	s.Contains(doc.HTML, "<em>text</em>")
+	s.Equal([]*markdown.Heading{{Level: 1, ID: "title", Title: "Title"}}, doc.TOC)
+}
+
+func (s *BlogTestSuite) TestRenderPost_CachedPerVersion() {
+	doc, err := s.blog.RenderPost(&domain.Post{ID: 1, Content: "first", Version: 1})
+	s.Require().NoError(err)
+
+	cached, err := s.blog.RenderPost(&domain.Post{ID: 1, Content: "first", Version: 1})
+	s.Require().NoError(err)
+	s.Same(doc, cached, "the same version should not be rendered again")
+
+	updated, err := s.blog.RenderPost(&domain.Post{ID: 1, Content: "second", Version: 2})
+	s.Require().NoError(err)
+	s.Contains(updated.HTML, "second")
+
+	recreated, err := s.blog.RenderPost(&domain.Post{ID: 1, Content: "recreated", Version: 1})
+	s.Require().NoError(err)
+	s.Contains(recreated.HTML, "recreated", "the post recreated with the id and version should not get the cached content")
+}
+
+func TestRenderCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newRenderCache(2)
+	first, second, third := &markdown.Document{}, &markdown.Document{}, &markdown.Document{}
+	c.put(renderKey{id: 1, version: 1}, first)
+	c.put(renderKey{id: 2, version: 1}, second)
+	c.get(renderKey{id: 1, version: 1})
+	c.put(renderKey{id: 3, version: 1}, third)
+
+	_, ok := c.get(renderKey{id: 2, version: 1})
+	assert.False(t, ok, "the least recently used entry should be evicted")
+
+	doc, ok := c.get(renderKey{id: 1, version: 1})
+	assert.True(t, ok)
+	assert.Same(t, first, doc)
+
+	doc, ok = c.get(renderKey{id: 3, version: 1})
+	assert.True(t, ok)
+	assert.Same(t, third, doc)
+}
@@ -0,0 +1,29 @@
+package blog
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracingScope is the instrumentation scope of the blog spans
+const tracingScope = "github.com/voltento/go-blog-project/internal/blog"
+
+// startSpan starts the span of the method.
+// The returned function records the error the method returns and ends the span.
+// The context is kept as is if tracing is off, there is no span to pass to the storage then.
+func startSpan(ctx context.Context, name string) (context.Context, func(err error)) {
+	spanCtx, span := otel.Tracer(tracingScope).Start(ctx, "Blog."+name)
+	if !span.SpanContext().IsValid() {
+		return ctx, func(error) {}
+	}
+
+	return spanCtx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,161 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLen = 8
+	// maxPasswordLen is the longest password bcrypt hashes completely
+	maxPasswordLen      = 72
+	maxDisplayNameLen   = 64
+	maxBioLen           = 1024
+	defaultPasswordCost = bcrypt.DefaultCost
+)
+
+var usernameRe = regexp.MustCompile(`^[a-z0-9_-]{3,32}$`)
+
+// dummyHash is compared against on login of an unknown user, so the response time does not reveal the usernames
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), defaultPasswordCost)
+	return hash
+})
+
+// Signup registers a new user with the password. The display name defaults to the username.
+func (b *Blog) Signup(ctx context.Context, user *domain.User, password string) (_ domain.UserId, err error) {
+	ctx, end := startSpan(ctx, "Signup")
+	defer func() { end(err) }()
+
+	user.Username = strings.ToLower(strings.TrimSpace(user.Username))
+	if !usernameRe.MatchString(user.Username) {
+		err := errors.New("username should be 3 to 32 latin letters, digits, '_' or '-'")
+		return 0, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		err := fmt.Errorf("password should be %v to %v bytes long", minPasswordLen, maxPasswordLen)
+		return 0, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	user.DisplayName = strings.TrimSpace(user.DisplayName)
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
+
+	if err := validateProfile(user); err != nil {
+		return 0, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.passwordCost)
+	if err != nil {
+		return 0, fmt.Errorf("can not hash password. error: %w", err)
+	}
+
+	user.PasswordHash = string(hash)
+	user.CreatedAt = b.now().UTC()
+	return b.storage.CreateUser(ctx, user)
+}
+
+// Login returns the user with the username if the password matches.
+// Unknown users and placeholder users that can not log in are reported as a wrong password.
+func (b *Blog) Login(ctx context.Context, username, password string) (_ *domain.User, err error) {
+	ctx, end := startSpan(ctx, "Login")
+	defer func() { end(err) }()
+
+	invalid := httperr.WrapWithHttpCode(errors.New("invalid username or password"), http.StatusUnauthorized)
+
+	user, err := b.storage.UserByUsername(ctx, strings.ToLower(strings.TrimSpace(username)))
+	if httperr.HTTPStatusCode(err, 0) == http.StatusNotFound {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, invalid
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsPlaceholder() {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, invalid
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, invalid
+	}
+
+	return user, nil
+}
+
+func (b *Blog) User(ctx context.Context, id domain.UserId) (_ *domain.User, err error) {
+	ctx, end := startSpan(ctx, "User")
+	defer func() { end(err) }()
+
+	return b.storage.User(ctx, id)
+}
+
+// CurrentUser returns the authenticated user the request is made by
+func (b *Blog) CurrentUser(ctx context.Context) (_ *domain.User, err error) {
+	ctx, end := startSpan(ctx, "CurrentUser")
+	defer func() { end(err) }()
+
+	editor, err := b.currentEditor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return editor.user, nil
+}
+
+// UpdateProfile changes the display name and the bio of the authenticated user.
+// Posts and comments are rendered with the new display name.
+func (b *Blog) UpdateProfile(ctx context.Context, profile *domain.User) (_ *domain.User, err error) {
+	ctx, end := startSpan(ctx, "UpdateProfile")
+	defer func() { end(err) }()
+
+	current, err := b.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *current
+	updated.DisplayName = strings.TrimSpace(profile.DisplayName)
+	updated.Bio = profile.Bio
+	if updated.DisplayName == "" {
+		err := errors.New("display name should not be empty")
+		return nil, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	if err := validateProfile(&updated); err != nil {
+		return nil, err
+	}
+
+	if err := b.storage.UpdateUser(ctx, &updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func validateProfile(user *domain.User) error {
+	if len(user.DisplayName) > maxDisplayNameLen {
+		err := fmt.Errorf("display name should be at most %v bytes long", maxDisplayNameLen)
+		return httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	if len(user.Bio) > maxBioLen {
+		err := fmt.Errorf("bio should be at most %v bytes long", maxBioLen)
+		return httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (s *BlogTestSuite) TestSignup() {
+	user := &domain.User{Username: " Alice "}
+	s.mockStorage.On("CreateUser", s.ctx, user).Return(domain.UserId(2), nil)
+
+	id, err := s.blog.Signup(s.ctx, user, "password")
+
+	s.NoError(err)
+	s.Equal(domain.UserId(2), id)
+	s.Equal("alice", user.Username)
+	s.Equal("alice", user.DisplayName, "the display name should default to the username")
+	s.Equal(testNow, user.CreatedAt)
+	s.NoError(bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("password")))
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestSignup_Invalid() {
+	tests := []struct {
+		name     string
+		user     *domain.User
+		password string
+	}{
+		{name: "short username", user: &domain.User{Username: "al"}, password: "password"},
+		{name: "username with spaces", user: &domain.User{Username: "al ice"}, password: "password"},
+		{name: "placeholder username", user: &domain.User{Username: "imported:alice"}, password: "password"},
+		{name: "short password", user: &domain.User{Username: "alice"}, password: "short"},
+		{name: "long password", user: &domain.User{Username: "alice"}, password: strings.Repeat("p", 73)},
+		{name: "long display name", user: &domain.User{Username: "alice", DisplayName: strings.Repeat("a", 65)}, password: "password"},
+	}
+
+	for _, tt := range tests {
+		_, err := s.blog.Signup(s.ctx, tt.user, tt.password)
+		s.Equal(http.StatusBadRequest, httperr.HTTPStatusCode(err, -1), tt.name)
+	}
+	s.mockStorage.AssertNotCalled(s.T(), "CreateUser", mock.Anything, mock.Anything)
+}
+
+func (s *BlogTestSuite) TestLogin() {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	s.Require().NoError(err)
+	user := &domain.User{ID: 2, Username: "alice", PasswordHash: string(hash)}
+	s.mockStorage.On("UserByUsername", s.ctx, "alice").Return(user, nil)
+
+	logged, err := s.blog.Login(s.ctx, "Alice", "password")
+	s.NoError(err)
+	s.Equal(user, logged)
+
+	_, err = s.blog.Login(s.ctx, "alice", "wrong password")
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *BlogTestSuite) TestLogin_UnknownUser() {
+	s.mockStorage.On("UserByUsername", s.ctx, "bob").Return(nil, httperr.WrapWithHttpCode(errors.New("not found"), http.StatusNotFound))
+	s.mockStorage.On("UserByUsername", s.ctx, "imported:bob").Return(&domain.User{ID: 3, Username: "imported:bob"}, nil)
+
+	_, err := s.blog.Login(s.ctx, "bob", "password")
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1))
+
+	_, err = s.blog.Login(s.ctx, "imported:bob", "")
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1), "placeholder users should not log in")
+}
+
+func (s *BlogTestSuite) TestUpdateProfile() {
+	expected := &domain.User{ID: testUserId, Username: "author", DisplayName: "New Name", Bio: "Bio"}
+	s.mockStorage.On("UpdateUser", s.ctx, expected).Return(nil)
+
+	user, err := s.blog.UpdateProfile(s.ctx, &domain.User{DisplayName: " New Name ", Bio: "Bio"})
+
+	s.NoError(err)
+	s.Equal(expected, user)
+	s.mockStorage.AssertExpectations(s.T())
+}
+
+func (s *BlogTestSuite) TestUpdateProfile_Unauthenticated() {
+	_, err := s.blog.UpdateProfile(context.Background(), &domain.User{DisplayName: "Name"})
+
+	s.Equal(http.StatusUnauthorized, httperr.HTTPStatusCode(err, -1))
+}
@@ -0,0 +1,351 @@
+// Package bulk reads and writes posts in JSON Lines and CSV, one post per line or row.
+// The posts are read and written one by one, so the size of a dataset is not limited by memory.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+// Format is an encoding of the posts
+type Format string
+
+const (
+	// JSONLines keeps a post as a JSON object per line, the object has the fields of the post in the API
+	JSONLines Format = "jsonl"
+	// CSV keeps a post per row with the columns named in the header row, see Columns
+	CSV Format = "csv"
+)
+
+// Columns are the CSV columns in the order they are written.
+// On read the header names the columns in any order and the missing columns are left empty.
+var Columns = []string{"id", "title", "content", "author_id", "author", "tags", "status", "publish_at", "created_at", "updated_at", "version"}
+
+// tagSeparator joins the tags of a post in the tags column
+const tagSeparator = ","
+
+// ParseFormat returns the format by its name, the empty name is JSON Lines
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", JSONLines:
+		return JSONLines, nil
+	case CSV:
+		return CSV, nil
+	default:
+		err := fmt.Errorf("format '%s' should be one of jsonl or csv", name)
+		return "", httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// RowError is a post which can not be read or imported. Rows are counted from 1, the CSV header is row 1.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ImportError lists the rows which can not be imported
+type ImportError struct {
+	Rows []*RowError
+	// Truncated is set if more rows failed than listed
+	Truncated bool
+}
+
+// Error names the first invalid row, the rest are listed in Rows
+func (e *ImportError) Error() string {
+	if len(e.Rows) == 0 {
+		return "posts are not imported"
+	}
+
+	more := ""
+	if e.Truncated {
+		more = " or more"
+	}
+	return fmt.Sprintf("posts are not imported, %d%s rows are invalid. first: %v", len(e.Rows), more, e.Rows[0])
+}
+
+// Decoder reads the posts one by one
+type Decoder interface {
+	// Decode returns the next post or io.EOF after the last one.
+	// A post which can not be read is returned as *RowError, the decoding goes on with the next post.
+	Decode() (*domain.Post, error)
+	// Row returns the row of the last decoded post
+	Row() int
+}
+
+// Encoder writes the posts one by one
+type Encoder interface {
+	Encode(post *domain.Post) error
+	// Flush writes the buffered posts
+	Flush() error
+}
+
+// NewDecoder returns the decoder of the posts read from r. The CSV header is read right away.
+func NewDecoder(f Format, r io.Reader) (Decoder, error) {
+	if f == CSV {
+		return newCSVDecoder(r)
+	}
+	return &jsonDecoder{r: bufio.NewReader(r)}, nil
+}
+
+// NewEncoder returns the encoder of the posts written to w. The CSV header is written with the first post.
+func NewEncoder(f Format, w io.Writer) Encoder {
+	if f == CSV {
+		return &csvEncoder{w: csv.NewWriter(w)}
+	}
+	bw := bufio.NewWriter(w)
+	return &jsonEncoder{w: bw, enc: json.NewEncoder(bw)}
+}
+
+type jsonDecoder struct {
+	r   *bufio.Reader
+	row int
+}
+
+func (d *jsonDecoder) Decode() (*domain.Post, error) {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if len(line) == 0 && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		d.row++
+		line = bytes.TrimSpace(line)
+		// The blank lines are skipped, e.g. the one after the last post
+		if len(line) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		var post domain.Post
+		if err := dec.Decode(&post); err != nil {
+			return nil, &RowError{Row: d.row, Err: fmt.Errorf("can not parse post. error: %w", err)}
+		}
+		if dec.More() {
+			return nil, &RowError{Row: d.row, Err: errors.New("a line should keep one post")}
+		}
+
+		return &post, nil
+	}
+}
+
+func (d *jsonDecoder) Row() int {
+	return d.row
+}
+
+type jsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (e *jsonEncoder) Encode(post *domain.Post) error {
+	return e.enc.Encode(post)
+}
+
+func (e *jsonEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+type csvDecoder struct {
+	r *csv.Reader
+	// columns maps the column name to its position in a row
+	columns map[string]int
+	row     int
+}
+
+func newCSVDecoder(r io.Reader) (*csvDecoder, error) {
+	d := &csvDecoder{r: csv.NewReader(r), columns: map[string]int{}, row: 1}
+	d.r.FieldsPerRecord = -1
+
+	header, err := d.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, httperr.WrapWithHttpCode(errors.New("csv header is required"), http.StatusBadRequest)
+	}
+	if err != nil {
+		err = fmt.Errorf("can not read csv header. error: %w", err)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	known := map[string]bool{}
+	for _, c := range Columns {
+		known[c] = true
+	}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			err := fmt.Errorf("unknown csv column '%s', the columns are %s", name, strings.Join(Columns, ", "))
+			return nil, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+		}
+		if _, ok := d.columns[name]; ok {
+			err := fmt.Errorf("csv column '%s' is repeated", name)
+			return nil, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+		}
+		d.columns[name] = i
+	}
+
+	return d, nil
+}
+
+func (d *csvDecoder) Decode() (*domain.Post, error) {
+	record, err := d.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+
+	d.row++
+	if err != nil {
+		return nil, &RowError{Row: d.row, Err: err}
+	}
+	if len(record) != len(d.columns) {
+		err := fmt.Errorf("row has %d fields, the header has %d", len(record), len(d.columns))
+		return nil, &RowError{Row: d.row, Err: err}
+	}
+
+	post, err := d.post(record)
+	if err != nil {
+		return nil, &RowError{Row: d.row, Err: err}
+	}
+
+	return post, nil
+}
+
+func (d *csvDecoder) Row() int {
+	return d.row
+}
+
+// post maps the fields of the row to the post
+func (d *csvDecoder) post(record []string) (*domain.Post, error) {
+	field := func(name string) string {
+		if i, ok := d.columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	post := &domain.Post{
+		Title:   field("title"),
+		Content: field("content"),
+		Author:  field("author"),
+		Status:  domain.PostStatus(field("status")),
+	}
+	if tags := field("tags"); tags != "" {
+		post.Tags = strings.Split(tags, tagSeparator)
+	}
+
+	ints := []struct {
+		name string
+		set  func(v int)
+	}{
+		{"id", func(v int) { post.ID = domain.PostId(v) }},
+		{"author_id", func(v int) { post.AuthorID = domain.UserId(v) }},
+		{"version", func(v int) { post.Version = v }},
+	}
+	for _, f := range ints {
+		if v := field(f.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("can not parse %s '%s'. error: %w", f.name, v, err)
+			}
+			f.set(n)
+		}
+	}
+
+	times := []struct {
+		name string
+		t    *time.Time
+	}{
+		{"publish_at", &post.PublishAt},
+		{"created_at", &post.CreatedAt},
+		{"updated_at", &post.UpdatedAt},
+	}
+	for _, f := range times {
+		if v := field(f.name); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("can not parse %s '%s'. error: %w", f.name, v, err)
+			}
+			*f.t = t
+		}
+	}
+
+	return post, nil
+}
+
+type csvEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvEncoder) Encode(post *domain.Post) error {
+	if !e.headerWritten {
+		if err := e.w.Write(Columns); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	return e.w.Write([]string{
+		strconv.Itoa(int(post.ID)),
+		post.Title,
+		post.Content,
+		strconv.Itoa(int(post.AuthorID)),
+		post.Author,
+		strings.Join(post.Tags, tagSeparator),
+		string(post.Status),
+		csvTime(post.PublishAt),
+		csvTime(post.CreatedAt),
+		csvTime(post.UpdatedAt),
+		strconv.Itoa(post.Version),
+	})
+}
+
+// Flush writes the buffered rows, the header is written even if there are no posts
+func (e *csvEncoder) Flush() error {
+	if !e.headerWritten {
+		if err := e.w.Write(Columns); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvTime formats the time as RFC 3339, the zero time is left empty
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
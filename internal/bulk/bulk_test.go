@@ -0,0 +1,131 @@
+package bulk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+func testPosts() []*domain.Post {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	return []*domain.Post{
+		{
+			ID: 1, Title: "First, \"quoted\"", Content: "line\nline", AuthorID: 2, Author: "Alice",
+			Tags: []string{"go", "web"}, Status: domain.StatusPublished, PublishAt: at, CreatedAt: at, UpdatedAt: at, Version: 3,
+		},
+		{ID: 2, Title: "Second", Content: "content", Status: domain.StatusDraft, Version: 1},
+	}
+}
+
+// decodeAll returns the decoded posts and the row errors
+func decodeAll(t *testing.T, dec Decoder) ([]*domain.Post, []*RowError) {
+	var posts []*domain.Post
+	var rowErrs []*RowError
+	for {
+		post, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return posts, rowErrs
+		}
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			rowErrs = append(rowErrs, rowErr)
+			continue
+		}
+		require.NoError(t, err)
+		posts = append(posts, post)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range []Format{JSONLines, CSV} {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := NewEncoder(f, &buf)
+			for _, post := range testPosts() {
+				require.NoError(t, enc.Encode(post))
+			}
+			require.NoError(t, enc.Flush())
+
+			dec, err := NewDecoder(f, &buf)
+			require.NoError(t, err)
+			posts, rowErrs := decodeAll(t, dec)
+			assert.Empty(t, rowErrs)
+			assert.Equal(t, testPosts(), posts)
+		})
+	}
+}
+
+func TestJSONLines_RowErrors(t *testing.T) {
+	input := "{\"Title\":\"First\"}\n\n{\"Title\":\n{\"Title\":\"Second\"} {}\n{\"Unknown\":1}\n{\"Title\":\"Last\"}"
+	dec, err := NewDecoder(JSONLines, strings.NewReader(input))
+	require.NoError(t, err)
+
+	posts, rowErrs := decodeAll(t, dec)
+	require.Len(t, posts, 2)
+	assert.Equal(t, "First", posts[0].Title)
+	assert.Equal(t, "Last", posts[1].Title)
+	assert.Equal(t, 6, dec.Row())
+
+	rows := make([]int, 0, len(rowErrs))
+	for _, e := range rowErrs {
+		rows = append(rows, e.Row)
+	}
+	assert.Equal(t, []int{3, 4, 5}, rows, "the blank line should be counted")
+}
+
+func TestCSV_Columns(t *testing.T) {
+	input := "Content,TITLE,tags\ncontent,First,go\nbroken\n\"bare\"quote\",x,y\ncontent,Second,\n"
+	dec, err := NewDecoder(CSV, strings.NewReader(input))
+	require.NoError(t, err)
+
+	posts, rowErrs := decodeAll(t, dec)
+	require.Len(t, posts, 2)
+	assert.Equal(t, &domain.Post{Title: "First", Content: "content", Tags: []string{"go"}}, posts[0])
+	assert.Equal(t, &domain.Post{Title: "Second", Content: "content"}, posts[1])
+
+	require.Len(t, rowErrs, 2)
+	assert.Equal(t, 3, rowErrs[0].Row, "the header should be row 1")
+	assert.Equal(t, 4, rowErrs[1].Row)
+}
+
+func TestCSV_WrongValues(t *testing.T) {
+	input := "title,id,publish_at\nFirst,abc,\nSecond,1,yesterday\n"
+	dec, err := NewDecoder(CSV, strings.NewReader(input))
+	require.NoError(t, err)
+
+	posts, rowErrs := decodeAll(t, dec)
+	assert.Empty(t, posts)
+	require.Len(t, rowErrs, 2)
+	assert.ErrorContains(t, rowErrs[0], "can not parse id 'abc'")
+	assert.ErrorContains(t, rowErrs[1], "can not parse publish_at 'yesterday'")
+}
+
+func TestCSV_WrongHeader(t *testing.T) {
+	for _, input := range []string{"", "title,body\n", "title,title\n"} {
+		_, err := NewDecoder(CSV, strings.NewReader(input))
+		assert.Equal(t, http.StatusBadRequest, httperr.HTTPStatusCode(err, 0), input)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, JSONLines, f)
+
+	f, err = ParseFormat("csv")
+	require.NoError(t, err)
+	assert.Equal(t, CSV, f)
+
+	_, err = ParseFormat("xml")
+	assert.Equal(t, http.StatusBadRequest, httperr.HTTPStatusCode(err, 0))
+}
@@ -0,0 +1,103 @@
+// Package diff compares texts line by line
+package diff
+
+import "strings"
+
+// maxCells limits the table of the longest common subsequence of the changed lines, so the memory a diff takes
+// is bounded whatever the texts are
+const maxCells = 1 << 20
+
+// Lines returns a line diff turning text a into text b.
+// Every line of the diff is prefixed with "-" if it's removed, with "+" if it's added and with " " if it's kept.
+// The lines the texts start and end with are kept. If the changed lines between them are too many to compare,
+// they are all removed and added, as if the content was replaced.
+func Lines(a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	linesA, linesB := splitLines(a), splitLines(b)
+
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix &&
+		linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+
+	var sb strings.Builder
+	for _, line := range linesA[:prefix] {
+		writeLine(&sb, " ", line)
+	}
+	changedA, changedB := linesA[prefix:len(linesA)-suffix], linesB[prefix:len(linesB)-suffix]
+	if (len(changedA)+1)*(len(changedB)+1) > maxCells {
+		replace(&sb, changedA, changedB)
+	} else {
+		compare(&sb, changedA, changedB)
+	}
+	for _, line := range linesA[len(linesA)-suffix:] {
+		writeLine(&sb, " ", line)
+	}
+
+	return sb.String()
+}
+
+// compare writes the diff of the lines made with their longest common subsequence
+func compare(sb *strings.Builder, linesA, linesB []string) {
+	// lcs[i][j] is the length of the longest common subsequence of linesA[i:] and linesB[j:]
+	lcs := make([][]int32, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(linesA) || j < len(linesB) {
+		switch {
+		case i < len(linesA) && j < len(linesB) && linesA[i] == linesB[j]:
+			writeLine(sb, " ", linesA[i])
+			i++
+			j++
+		case i < len(linesA) && (j == len(linesB) || lcs[i+1][j] >= lcs[i][j+1]):
+			writeLine(sb, "-", linesA[i])
+			i++
+		default:
+			writeLine(sb, "+", linesB[j])
+			j++
+		}
+	}
+}
+
+// replace writes the diff removing all the lines of a and adding all the lines of b
+func replace(sb *strings.Builder, linesA, linesB []string) {
+	for _, line := range linesA {
+		writeLine(sb, "-", line)
+	}
+	for _, line := range linesB {
+		writeLine(sb, "+", line)
+	}
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func writeLine(sb *strings.Builder, prefix, line string) {
+	sb.WriteString(prefix)
+	sb.WriteString(line)
+	sb.WriteString("\n")
+}
@@ -0,0 +1,56 @@
+package diff
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{"Equal texts", "a\nb", "a\nb", ""},
+		{"Added to empty", "", "a\nb", "+a\n+b\n"},
+		{"Removed all", "a\nb", "", "-a\n-b\n"},
+		{"Changed line", "a\nb\nc", "a\nB\nc", " a\n-b\n+B\n c\n"},
+		{"Added line", "a\nc", "a\nb\nc", " a\n+b\n c\n"},
+		{"Removed line", "a\nb\nc", "a\nc", " a\n-b\n c\n"},
+		{"Changed lines in the middle", "a\nb\nc\nd\ne", "a\nc\nx\ne", " a\n-b\n c\n-d\n+x\n e\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Lines(tt.a, tt.b))
+		})
+	}
+}
+
+func TestLines_Large(t *testing.T) {
+	// The texts have no common lines, so the lines are too many to compare and the content is replaced
+	a := strings.Repeat("a\n", 50000) + "end"
+	b := strings.Repeat("b\n", 50000) + "end"
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	d := Lines(a, b)
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	assert.Equal(t, strings.Repeat("-a\n", 50000)+strings.Repeat("+b\n", 50000)+" end\n", d)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64<<20), "the diff should not allocate the table of all the lines")
+}
+
+func TestLines_LargeSmallChange(t *testing.T) {
+	a := strings.Repeat("line\n", 50000) + "old\n" + strings.Repeat("line\n", 50000)
+	b := strings.Repeat("line\n", 50000) + "new\n" + strings.Repeat("line\n", 50000)
+
+	d := Lines(a, b)
+	assert.Contains(t, d, " line\n-old\n+new\n line\n")
+	assert.Equal(t, 2, strings.Count(d, "old")+strings.Count(d, "new"))
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+type CommentId int
+
+// Comment is a reader's comment on a post. Comments are threaded, a reply references the comment it answers.
+type Comment struct {
+	ID     CommentId
+	PostID PostId
+	// ParentID is the comment this one replies to, it's zero for a top-level comment
+	ParentID CommentId
+	AuthorID UserId
+	// Author is the display name of the author
+	Author    string
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CommentThread is a comment with the replies to it
+type CommentThread struct {
+	Comment *Comment
+	Replies []*CommentThread
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// MigrationRecord is a data migration applied to the storage. The records make a ledger, so a migration is applied once.
+type MigrationRecord struct {
+	// ID names the migration, it's the checksum of the migration file if the file does not name it
+	ID string
+	// Checksum is the SHA-256 of the migration file, so a changed file is not taken for the applied one
+	Checksum  string
+	AppliedAt time.Time
+	// Operations is the number of the changes the migration made
+	Operations int
+}
@@ -1,10 +1,210 @@
 package domain
 
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type PostId int
 
+// MaxSafePostId is the greatest id a float64 keeps exactly. The JSON clients parsing numbers as float64,
+// as JavaScript does, lose the precision of the greater ids, so they are rendered to JSON as strings.
+const MaxSafePostId = 1<<53 - 1
+
+func (id PostId) MarshalJSON() ([]byte, error) {
+	n := strconv.Itoa(int(id))
+	if id > MaxSafePostId || id < -MaxSafePostId {
+		return []byte(`"` + n + `"`), nil
+	}
+	return []byte(n), nil
+}
+
+// UnmarshalJSON accepts the id as a number or as a string of digits
+func (id *PostId) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return fmt.Errorf("post id should be an integer, got %s", data)
+	}
+
+	*id = PostId(n)
+	return nil
+}
+
+// AnyVersion disables the version check of conditional changes of a post
+const AnyVersion = 0
+
 type Post struct {
 	ID      PostId
 	Title   string
 	Content string
-	Author  string
+	// AuthorID is the user who wrote the post, it's zero for posts stored before users were introduced
+	AuthorID UserId
+	// Author is the display name of the author
+	Author string
+	// Tags are lower-cased and sorted, a post has every tag once
+	Tags   []string
+	Status PostStatus
+	// PublishAt is the time a scheduled post is published at or the time a published post was published at.
+	// It's zero for drafts.
+	PublishAt time.Time
+	// CreatedAt and UpdatedAt are set by storage, they are zero for posts stored before timestamps were introduced
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Version is incremented by storage on every update starting from 1.
+	// On update it's the version the change is based on, the update is rejected if the stored post has another one.
+	Version int
+}
+
+// PostStatus is a stage of the post lifecycle. Only published posts are shown to the readers.
+type PostStatus string
+
+const (
+	StatusDraft     PostStatus = "draft"
+	StatusPublished PostStatus = "published"
+	// StatusScheduled posts are published at PublishAt
+	StatusScheduled PostStatus = "scheduled"
+	StatusArchived  PostStatus = "archived"
+)
+
+// IsValid reports whether the status is one of the known statuses
+func (s PostStatus) IsValid() bool {
+	switch s {
+	case StatusDraft, StatusPublished, StatusScheduled, StatusArchived:
+		return true
+	}
+	return false
+}
+
+// NormalizeTags lower-cases and sorts the tags dropping the empty and the repeated ones
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// PostsQuery describes a page of posts ordered by Sort
+type PostsQuery struct {
+	// After is the id of the last post of the previous page, zero value starts from the first post
+	After PostId
+	// AfterKey is the sort key of the After post, it's not used for SortByID
+	AfterKey string
+	Sort     PostSort
+	// Desc reverses the order of the posts
+	Desc  bool
+	Limit int
+	// Tags filters the posts by tags, the posts are not filtered if it's empty
+	Tags     []string
+	TagMatch TagMatch
+	// Author filters the posts by the author, the posts are not filtered if it's zero
+	Author UserId
+	// PublishedOnly hides the posts which are not published, except the own posts of Viewer
+	PublishedOnly bool
+	// Viewer is the user the posts are listed for, it's zero for anonymous readers
+	Viewer UserId
+}
+
+// Shows reports whether the post passes the status filter of the query
+func (q PostsQuery) Shows(p *Post) bool {
+	return !q.PublishedOnly || p.Status == StatusPublished || (q.Viewer != 0 && p.AuthorID == q.Viewer)
+}
+
+// PostSort is the order posts are listed in. Posts with equal sort keys are ordered by ID.
+type PostSort int
+
+const (
+	SortByID PostSort = iota
+	SortByCreatedAt
+	SortByUpdatedAt
+	SortByTitle
+)
+
+// sortKeyTimeLayout has fixed width, so the keys of UTC times are ordered as the times
+const sortKeyTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Key returns the sort key of the post which a page following the post starts after
+func (s PostSort) Key(p *Post) string {
+	switch s {
+	case SortByCreatedAt:
+		return p.CreatedAt.UTC().Format(sortKeyTimeLayout)
+	case SortByUpdatedAt:
+		return p.UpdatedAt.UTC().Format(sortKeyTimeLayout)
+	case SortByTitle:
+		return p.Title
+	}
+	return ""
+}
+
+// Position returns a post with the id and the fields the key was made of, it stands for the post in Compare
+func (s PostSort) Position(id PostId, key string) (*Post, error) {
+	p := &Post{ID: id}
+	var err error
+	switch s {
+	case SortByCreatedAt:
+		p.CreatedAt, err = time.Parse(sortKeyTimeLayout, key)
+	case SortByUpdatedAt:
+		p.UpdatedAt, err = time.Parse(sortKeyTimeLayout, key)
+	case SortByTitle:
+		p.Title = key
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid sort key %q. error: %w", key, err)
+	}
+	return p, nil
+}
+
+// Compare orders the posts by the sort key and then by ID
+func (s PostSort) Compare(a, b *Post) int {
+	c := 0
+	switch s {
+	case SortByCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case SortByUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case SortByTitle:
+		c = strings.Compare(a.Title, b.Title)
+	}
+
+	if c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// TagMatch tells how posts are matched against the tags of PostsQuery
+type TagMatch int
+
+const (
+	// MatchAllTags matches posts having every tag
+	MatchAllTags TagMatch = iota
+	// MatchAnyTag matches posts having at least one of the tags
+	MatchAnyTag
+)
+
+// TagCount is a tag with the number of posts having it
+type TagCount struct {
+	Tag   string
+	Posts int
+}
+
+// Page is a slice of posts returned for PostsQuery
+type Page struct {
+	Posts []*Post
+	// HasMore reports whether there are posts after the last one of the page
+	HasMore bool
 }
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Revision is an immutable copy of a post made on every change of it
+type Revision struct {
+	PostID PostId
+	// Number is the version of the post the change produced
+	Number    int
+	Title     string
+	Content   string
+	Author    string
+	CreatedAt time.Time
+	// Diff is a line diff of the content against the previous revision
+	Diff string
+}
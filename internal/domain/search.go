@@ -0,0 +1,9 @@
+package domain
+
+// SearchResult is a post matching a search query
+type SearchResult struct {
+	Post  *Post
+	Score float64
+	// Snippet is an HTML fragment of the post content with the matching words highlighted
+	Snippet string
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+type UserId int
+
+// User is an account posts and comments are written by
+type User struct {
+	ID UserId
+	// Username is the unique name the user logs in with
+	Username string
+	// DisplayName is rendered as the author of the user's posts and comments
+	DisplayName string
+	Bio         string
+	// PasswordHash is empty for placeholder users imported together with posts, they can not log in
+	PasswordHash string
+	CreatedAt    time.Time
+}
+
+// IsPlaceholder reports whether the user is imported without credentials
+func (u *User) IsPlaceholder() bool {
+	return u.PasswordHash == ""
+}
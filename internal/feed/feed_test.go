@@ -0,0 +1,127 @@
+package feed
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	published = time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	updated   = time.Date(2024, 1, 3, 12, 30, 0, 0, time.UTC)
+)
+
+func testFeed() *Feed {
+	return &Feed{
+		Title:       "Blog",
+		Description: "The latest posts",
+		Link:        "https://blog.example.com/",
+		Self:        "https://blog.example.com/feed.atom",
+		Entries: []*Entry{
+			{
+				ID:        "https://blog.example.com/posts/2",
+				Title:     "Second <post>",
+				Link:      "https://blog.example.com/posts/2",
+				Author:    "Alice",
+				Tags:      []string{"go", "web"},
+				Published: published,
+				Updated:   updated,
+				Summary:   "The excerpt & more",
+			},
+			{
+				ID:        "https://blog.example.com/posts/1",
+				Title:     "First post",
+				Link:      "https://blog.example.com/posts/1",
+				Published: published.Add(-time.Hour),
+			},
+		},
+	}
+}
+
+func TestFeed_Updated(t *testing.T) {
+	assert.Equal(t, updated, testFeed().Updated())
+	assert.True(t, (&Feed{}).Updated().IsZero())
+}
+
+func TestFeed_RSS(t *testing.T) {
+	data, err := testFeed().RSS()
+	require.NoError(t, err)
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title string `xml:"title"`
+				GUID  struct {
+					IsPermaLink string `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+				PubDate     string   `xml:"pubDate"`
+				Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories  []string `xml:"category"`
+				Description string   `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &doc), string(data))
+
+	assert.Equal(t, "2.0", doc.Version)
+	assert.Equal(t, "Blog", doc.Channel.Title)
+	assert.Equal(t, "Wed, 03 Jan 2024 12:30:00 +0000", doc.Channel.LastBuildDate)
+	require.Len(t, doc.Channel.Items, 2)
+
+	item := doc.Channel.Items[0]
+	assert.Equal(t, "Second <post>", item.Title)
+	assert.Equal(t, "true", item.GUID.IsPermaLink)
+	assert.Equal(t, "https://blog.example.com/posts/2", item.GUID.Value)
+	assert.Equal(t, "Tue, 02 Jan 2024 10:00:00 +0000", item.PubDate)
+	assert.Equal(t, "Alice", item.Creator)
+	assert.Equal(t, []string{"go", "web"}, item.Categories)
+	assert.Equal(t, "The excerpt & more", item.Description)
+}
+
+func TestFeed_Atom(t *testing.T) {
+	data, err := testFeed().Atom()
+	require.NoError(t, err)
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Author    *struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+			Summary string `xml:"summary"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &doc), string(data))
+
+	assert.Equal(t, "https://blog.example.com/feed.atom", doc.ID)
+	assert.Equal(t, "2024-01-03T12:30:00Z", doc.Updated)
+	require.Len(t, doc.Links, 2)
+	assert.Equal(t, "self", doc.Links[1].Rel)
+	require.Len(t, doc.Entries, 2)
+
+	assert.Equal(t, "https://blog.example.com/posts/2", doc.Entries[0].ID)
+	assert.Equal(t, "2024-01-02T10:00:00Z", doc.Entries[0].Published)
+	assert.Equal(t, "2024-01-03T12:30:00Z", doc.Entries[0].Updated)
+	require.NotNil(t, doc.Entries[0].Author)
+	assert.Equal(t, "Alice", doc.Entries[0].Author.Name)
+	assert.Equal(t, "The excerpt & more", doc.Entries[0].Summary)
+
+	assert.Equal(t, "2024-01-02T09:00:00Z", doc.Entries[1].Updated, "the entry without the update time should use the publication time")
+	assert.Nil(t, doc.Entries[1].Author)
+}
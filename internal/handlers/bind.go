@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+// bindJSON decodes the request body to the DTO and checks it, the invalid fields are listed in the error
+func bindJSON(c *gin.Context, dto any) error {
+	fields, err := bindFields(c, dto)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) > 0 {
+		return httperr.Invalid(fields...)
+	}
+	return nil
+}
+
+// bindFields decodes the request body to the DTO and returns the fields which failed the checks.
+// The DTO is filled even if some of its fields are invalid, so the caller can check the rest of them.
+// The error is returned if the body can not be decoded at all.
+func bindFields(c *gin.Context, dto any) ([]httperr.FieldError, error) {
+	err := c.ShouldBindJSON(dto)
+	if err == nil {
+		return nil, nil
+	}
+
+	var invalid validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &invalid):
+		fields := make([]httperr.FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			fields = append(fields, httperr.FieldError{Field: jsonName(dto, fe.StructField()), Message: validationMessage(fe)})
+		}
+		return fields, nil
+	case errors.As(err, &typeErr):
+		return nil, httperr.Invalid(httperr.FieldError{Field: typeErr.Field, Message: "should be " + jsonType(typeErr.Type)})
+	case errors.As(err, &timeErr):
+		// time.Time does not tell the decoder which field it is, so the time field of the DTO is named
+		return nil, httperr.Invalid(httperr.FieldError{Field: timeField(dto), Message: "should be a time in RFC 3339 format"})
+	default:
+		// The decoder errors name Go types and offsets, so they are not shown
+		msg := errors.New("request body should be a JSON object")
+		return nil, httperr.WrapWithCode(msg, http.StatusBadRequest, httperr.CodeMalformedBody)
+	}
+}
+
+// validationMessage explains the failed check of the field
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		return fmt.Sprintf("should be at most %s characters long", fe.Param())
+	default:
+		return fmt.Sprintf("does not pass the '%s' check", fe.Tag())
+	}
+}
+
+// jsonName returns the JSON name of the field of the DTO
+func jsonName(dto any, field string) string {
+	f, ok := reflect.TypeOf(dto).Elem().FieldByName(field)
+	if !ok {
+		return field
+	}
+
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field
+	}
+	return name
+}
+
+// timeField returns the JSON name of the time field of the DTO
+func timeField(dto any) string {
+	t := reflect.TypeOf(dto).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type == reflect.TypeOf(time.Time{}) {
+			return jsonName(dto, t.Field(i).Name)
+		}
+	}
+	return ""
+}
+
+// jsonType names the JSON type the Go type is decoded from
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/bulk"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/exp/slog"
+)
+
+// Import creates the posts sent in JSON Lines or CSV, all of them or none.
+// The invalid rows are listed in the problem details of 422.
+func (s *server) Import(c *gin.Context) {
+	format, err := mapBulkFormat(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	body := &spool{body: c.Request.Body}
+	defer body.close()
+
+	open := func() (bulk.Decoder, error) {
+		r, err := body.open()
+		if err != nil {
+			return nil, err
+		}
+		return bulk.NewDecoder(format, r)
+	}
+
+	n, err := s.service.ImportPosts(c.Request.Context(), open)
+	var importErr *bulk.ImportError
+	if errors.As(err, &importErr) {
+		err = httperr.WithExtensions(httperr.WrapWithCode(err, http.StatusUnprocessableEntity, httperr.CodeImportFailed), importErrorResp(importErr))
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": n})
+}
+
+// Export streams all the posts in JSON Lines or CSV ordered by id
+func (s *server) Export(c *gin.Context) {
+	format, err := mapBulkFormat(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	enc := bulk.NewEncoder(format, c.Writer)
+	started := false
+	// The headers are sent with the first post, so an error before it is responded as usual
+	start := func() {
+		if !started {
+			c.Header("Content-Type", format.ContentType())
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="posts.%s"`, format))
+			c.Status(http.StatusOK)
+			started = true
+		}
+	}
+
+	err = s.service.ExportPosts(c.Request.Context(), func(post *domain.Post) error {
+		start()
+		return enc.Encode(post)
+	})
+	if err == nil {
+		start()
+		err = enc.Flush()
+	}
+
+	if err != nil && started {
+		// The status is sent already, the client sees the export cut
+		slog.Error("export is interrupted", "error", err)
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// spool keeps the request body in a temporary file, so the body is read more than once without keeping it in memory.
+// The body is copied on the first open, so nothing is written for the requests rejected before reading it.
+type spool struct {
+	body io.Reader
+	file *os.File
+}
+
+// open returns the reader of the body from the start
+func (s *spool) open() (io.Reader, error) {
+	if s.file == nil {
+		f, err := os.CreateTemp("", "blog-import-*")
+		if err != nil {
+			return nil, fmt.Errorf("can not create import file. error: %w", err)
+		}
+		s.file = f
+
+		if _, err := io.Copy(f, s.body); err != nil {
+			return nil, fmt.Errorf("can not read import. error: %w", err)
+		}
+	}
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("can not read import file. error: %w", err)
+	}
+	return s.file, nil
+}
+
+func (s *spool) close() {
+	if s.file == nil {
+		return
+	}
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
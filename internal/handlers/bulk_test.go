@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/voltento/go-blog-project/internal/bulk"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+// readImport returns the mock of ImportPosts which reads the import twice like the blog does
+func readImport(titles *[]string) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		open := args.Get(1).(func() (bulk.Decoder, error))
+		for i := 0; i < 2; i++ {
+			dec, err := open()
+			if err != nil {
+				panic(err)
+			}
+			*titles = nil
+			for {
+				post, err := dec.Decode()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					panic(err)
+				}
+				*titles = append(*titles, post.Title)
+			}
+		}
+	}
+}
+
+func (s *HandlersTestSuite) TestImport() {
+	var titles []string
+	s.mockBlog.On("ImportPosts", mock.Anything, mock.Anything).Run(readImport(&titles)).Return(2, nil)
+
+	s.expect.POST("/v1/admin/import").
+		WithText("{\"Title\":\"First\"}\n{\"Title\":\"Second\"}\n").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("imported").IsEqual(2)
+
+	s.Equal([]string{"First", "Second"}, titles, "the body should be read on every open")
+}
+
+func (s *HandlersTestSuite) TestImport_CSV() {
+	var titles []string
+	s.mockBlog.On("ImportPosts", mock.Anything, mock.Anything).Run(readImport(&titles)).Return(1, nil)
+
+	s.expect.POST("/v1/admin/import").
+		WithHeader("Content-Type", "text/csv").
+		WithText("title,content\nFirst,content\n").
+		Expect().
+		Status(http.StatusOK)
+
+	s.Equal([]string{"First"}, titles)
+}
+
+func (s *HandlersTestSuite) TestImport_InvalidRows() {
+	importErr := &bulk.ImportError{Rows: []*bulk.RowError{{Row: 2, Err: errors.New("title and content are required")}}}
+	s.mockBlog.On("ImportPosts", mock.Anything, mock.Anything).
+		Return(0, httperr.WrapWithHttpCode(importErr, http.StatusUnprocessableEntity))
+
+	obj := s.expect.POST("/v1/admin/import").
+		WithText("{}").
+		Expect().
+		Status(http.StatusUnprocessableEntity).
+		JSON(problemJSON).Object()
+	obj.Value("rows").IsEqual([]map[string]any{{"row": 2, "error": "title and content are required"}})
+	obj.Value("truncated").IsEqual(false)
+	obj.Value("code").IsEqual(httperr.CodeImportFailed)
+	obj.Value("detail").String().Contains("row 2")
+}
+
+func (s *HandlersTestSuite) TestImport_Forbidden() {
+	s.mockBlog.On("ImportPosts", mock.Anything, mock.Anything).
+		Return(0, httperr.WrapWithHttpCode(errors.New("only an admin can do it"), http.StatusForbidden))
+
+	s.expect.POST("/v1/admin/import").WithText("{}").Expect().Status(http.StatusForbidden)
+}
+
+func (s *HandlersTestSuite) TestImport_WrongFormat() {
+	s.expect.POST("/v1/admin/import").WithQuery("format", "xml").WithText("{}").Expect().Status(http.StatusBadRequest)
+}
+
+// exportPosts returns the mock of ExportPosts passing the posts to the callback
+func exportPosts(posts ...*domain.Post) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		fn := args.Get(1).(func(*domain.Post) error)
+		for _, post := range posts {
+			if err := fn(post); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+func (s *HandlersTestSuite) TestExport() {
+	posts := []*domain.Post{{ID: 1, Title: "First", Tags: []string{"go", "web"}, Version: 1}, {ID: 2, Title: "Second", Version: 3}}
+	s.mockBlog.On("ExportPosts", mock.Anything, mock.Anything).Run(exportPosts(posts...)).Return(nil)
+
+	resp := s.expect.GET("/v1/admin/export").Expect().Status(http.StatusOK)
+	resp.Header("Content-Type").IsEqual("application/x-ndjson")
+	resp.Header("Content-Disposition").IsEqual(`attachment; filename="posts.jsonl"`)
+	resp.Body().IsEqual(
+		"{\"ID\":1,\"Title\":\"First\",\"Content\":\"\",\"AuthorID\":0,\"Author\":\"\",\"Tags\":[\"go\",\"web\"],\"Status\":\"\",\"PublishAt\":\"0001-01-01T00:00:00Z\",\"CreatedAt\":\"0001-01-01T00:00:00Z\",\"UpdatedAt\":\"0001-01-01T00:00:00Z\",\"Version\":1}\n" +
+			"{\"ID\":2,\"Title\":\"Second\",\"Content\":\"\",\"AuthorID\":0,\"Author\":\"\",\"Tags\":null,\"Status\":\"\",\"PublishAt\":\"0001-01-01T00:00:00Z\",\"CreatedAt\":\"0001-01-01T00:00:00Z\",\"UpdatedAt\":\"0001-01-01T00:00:00Z\",\"Version\":3}\n")
+}
+
+func (s *HandlersTestSuite) TestExport_CSV() {
+	posts := []*domain.Post{{ID: 1, Title: "First, second", Content: "line\nline", Tags: []string{"go", "web"}, Status: domain.StatusPublished, Version: 1}}
+	s.mockBlog.On("ExportPosts", mock.Anything, mock.Anything).Run(exportPosts(posts...)).Return(nil)
+
+	resp := s.expect.GET("/v1/admin/export").WithQuery("format", "csv").Expect().Status(http.StatusOK)
+	resp.Header("Content-Type").IsEqual("text/csv; charset=utf-8")
+	resp.Body().IsEqual("id,title,content,author_id,author,tags,status,publish_at,created_at,updated_at,version\n" +
+		"1,\"First, second\",\"line\nline\",0,,\"go,web\",published,,,,1\n")
+}
+
+func (s *HandlersTestSuite) TestExport_Empty() {
+	s.mockBlog.On("ExportPosts", mock.Anything, mock.Anything).Return(nil)
+
+	s.expect.GET("/v1/admin/export").WithQuery("format", "csv").Expect().
+		Status(http.StatusOK).
+		Body().IsEqual("id,title,content,author_id,author,tags,status,publish_at,created_at,updated_at,version\n")
+}
+
+func (s *HandlersTestSuite) TestExport_Forbidden() {
+	s.mockBlog.On("ExportPosts", mock.Anything, mock.Anything).
+		Return(httperr.WrapWithHttpCode(errors.New("only an admin can do it"), http.StatusForbidden))
+
+	s.expect.GET("/v1/admin/export").Expect().
+		Status(http.StatusForbidden).
+		JSON(problemJSON).Object().Value("detail").IsEqual("only an admin can do it")
+}
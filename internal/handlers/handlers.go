@@ -3,32 +3,76 @@ package handlers
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/bulk"
 	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/markdown"
 	"net/http"
+	"time"
 )
 
 // RegisterHandlers binds all the handlers to the http router
-func RegisterHandlers(r *gin.Engine, blog BlogService) {
-	s := server{service: blog}
-	r.GET("v1/posts/:id", s.GetPostByID)
-	r.GET("v1/posts", s.Posts)
-	r.POST("v1/posts", s.CreatePost)
-	r.DELETE("v1/posts/:id", s.DeletePost)
-	r.PUT("v1/posts/:id", s.UpdatePost)
+func RegisterHandlers(r *gin.Engine, blog BlogService, tokens TokenIssuer) {
+	s := server{service: blog, tokens: tokens}
+	r.GET("v1/posts/search", traced("Search", s.Search))
+	r.GET("v1/posts/:id", traced("GetPostByID", s.GetPostByID))
+	r.GET("v1/posts", traced("Posts", s.Posts))
+	r.POST("v1/posts", traced("CreatePost", s.CreatePost))
+	r.DELETE("v1/posts/:id", traced("DeletePost", s.DeletePost))
+	r.PUT("v1/posts/:id", traced("UpdatePost", s.UpdatePost))
+	r.GET("v1/tags", traced("Tags", s.Tags))
+	r.GET("v1/posts/:id/revisions", traced("Revisions", s.Revisions))
+	r.GET("v1/posts/:id/revisions/:rev", traced("Revision", s.Revision))
+	r.POST("v1/posts/:id/revisions/:rev/restore", traced("RestoreRevision", s.RestoreRevision))
+	r.GET("v1/posts/:id/comments", traced("Comments", s.Comments))
+	r.POST("v1/posts/:id/comments", traced("CreateComment", s.CreateComment))
+	r.PUT("v1/posts/:id/comments/:commentId", traced("UpdateComment", s.UpdateComment))
+	r.DELETE("v1/posts/:id/comments/:commentId", traced("DeleteComment", s.DeleteComment))
+	r.POST("v1/users", traced("Signup", s.Signup))
+	r.POST("v1/users/login", traced("Login", s.Login))
+	r.GET("v1/users/me", traced("CurrentUser", s.CurrentUser))
+	r.PUT("v1/users/me", traced("UpdateProfile", s.UpdateProfile))
+	r.GET("v1/users/:id", traced("User", s.User))
+	r.POST("v1/admin/import", traced("Import", s.Import))
+	r.GET("v1/admin/export", traced("Export", s.Export))
 }
 
 type BlogService interface {
 	CreatePost(ctx context.Context, p *domain.Post) (domain.PostId, error)
 	Post(ctx context.Context, id domain.PostId) (*domain.Post, error)
-	DeletePost(ctx context.Context, id domain.PostId) error
+	DeletePost(ctx context.Context, id domain.PostId, version int) error
 	UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error
-	Posts(ctx context.Context) []*domain.Post
+	Posts(ctx context.Context) ([]*domain.Post, error)
+	PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error)
+	RenderPost(post *domain.Post) (*markdown.Document, error)
+	Search(ctx context.Context, query string, limit int) ([]*domain.SearchResult, error)
+	Tags(ctx context.Context) ([]*domain.TagCount, error)
+	Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error)
+	Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error)
+	RestoreRevision(ctx context.Context, id domain.PostId, number int) (*domain.Post, error)
+	CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error)
+	CommentThreads(ctx context.Context, postId domain.PostId) ([]*domain.CommentThread, error)
+	UpdateComment(ctx context.Context, comment *domain.Comment) error
+	DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error
+	Signup(ctx context.Context, user *domain.User, password string) (domain.UserId, error)
+	Login(ctx context.Context, username, password string) (*domain.User, error)
+	User(ctx context.Context, id domain.UserId) (*domain.User, error)
+	CurrentUser(ctx context.Context) (*domain.User, error)
+	UpdateProfile(ctx context.Context, profile *domain.User) (*domain.User, error)
+	ImportPosts(ctx context.Context, open func() (bulk.Decoder, error)) (int, error)
+	ExportPosts(ctx context.Context, fn func(post *domain.Post) error) error
+}
+
+// TokenIssuer issues the bearer tokens users log in with
+type TokenIssuer interface {
+	IssueToken(subject string) (string, time.Time, error)
 }
 
 type server struct {
 	service BlogService
+	tokens  TokenIssuer
 }
 
+// GetPostByID returns the post, the content is rendered to HTML if render=html is passed
 func (s *server) GetPostByID(c *gin.Context) {
 	id, err := mapPostId(c)
 	if err != nil {
@@ -36,20 +80,79 @@ func (s *server) GetPostByID(c *gin.Context) {
 		return
 	}
 
+	render, err := mapRender(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	post, err := s.service.Post(c.Request.Context(), id)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	tag := etag(post.Version)
+	if render {
+		tag = renderedETag(post.Version)
+	}
+	c.Header("ETag", tag)
+	if etagMatches(c.GetHeader("If-None-Match"), tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if !render {
+		c.JSON(http.StatusOK, post)
+		return
+	}
+
+	doc, err := s.service.RenderPost(post)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, renderedPostResp(post, doc))
 }
 
-// Posts returns as the available posts.
-// The API does not support pagination for sake of
-// simplicity of the Storage
+// Posts returns a page of posts ordered by ID or by the sort parameter, optionally filtered by tags.
+// The next page is requested with the cursor returned in the response and the same filter and order.
+// The content of the posts is rendered to HTML if render=html is passed.
 func (s *server) Posts(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"posts": s.service.Posts(c)})
+	q, err := mapPostsQuery(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render, err := mapRender(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	page, err := s.service.PostsPage(c.Request.Context(), q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	resp := postsPageResp(page, q.Sort)
+	if render {
+		posts := make([]*renderedPost, 0, len(page.Posts))
+		for _, post := range page.Posts {
+			doc, err := s.service.RenderPost(post)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			posts = append(posts, renderedPostResp(post, doc))
+		}
+		resp["posts"] = posts
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 func (s *server) CreatePost(c *gin.Context) {
@@ -65,6 +168,7 @@ func (s *server) CreatePost(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag(newPost.Version))
 	c.JSON(http.StatusCreated, postIdResp(id))
 }
 
@@ -75,7 +179,13 @@ func (s *server) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := s.service.DeletePost(c.Request.Context(), id); err != nil {
+	version, err := mapIfMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := s.service.DeletePost(c.Request.Context(), id, version); err != nil {
 		c.Error(err)
 		return
 	}
@@ -90,16 +200,203 @@ func (s *server) UpdatePost(c *gin.Context) {
 		return
 	}
 
+	version, err := mapIfMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	post, err := mapToPost(c)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
+	post.Version = version
 	if err := s.service.UpdatePost(c.Request.Context(), post, id); err != nil {
 		c.Error(err)
 		return
 	}
 
+	c.Header("ETag", etag(post.Version))
+	c.JSON(http.StatusOK, postIdResp(id))
+}
+
+// Search returns posts matching the query ordered by relevance
+func (s *server) Search(c *gin.Context) {
+	query, limit, err := mapSearchQuery(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	results, err := s.service.Search(c.Request.Context(), query, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, searchResultsResp(results))
+}
+
+// Tags returns all the tags with the number of posts having them
+func (s *server) Tags(c *gin.Context) {
+	counts, err := s.service.Tags(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tagsResp(counts))
+}
+
+// Revisions returns the list of the post revisions without their content
+func (s *server) Revisions(c *gin.Context) {
+	id, err := mapPostId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	revisions, err := s.service.Revisions(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisionsResp(revisions))
+}
+
+func (s *server) Revision(c *gin.Context) {
+	id, err := mapPostId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	number, err := mapRevisionNumber(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	rev, err := s.service.Revision(c.Request.Context(), id, number)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisionResp(rev))
+}
+
+// RestoreRevision makes the content of the revision the current content of the post
+func (s *server) RestoreRevision(c *gin.Context) {
+	id, err := mapPostId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	number, err := mapRevisionNumber(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	post, err := s.service.RestoreRevision(c.Request.Context(), id, number)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", etag(post.Version))
 	c.JSON(http.StatusOK, postIdResp(id))
 }
+
+// Comments returns the comments of the post as a tree, the replies are nested into the comments they answer
+func (s *server) Comments(c *gin.Context) {
+	postId, err := mapPostId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	threads, err := s.service.CommentThreads(c.Request.Context(), postId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, commentThreadsResp(threads))
+}
+
+func (s *server) CreateComment(c *gin.Context) {
+	postId, err := mapPostId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	comment, err := mapToComment(c, postId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	id, err := s.service.CreateComment(c.Request.Context(), comment)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, commentIdResp(id))
+}
+
+func (s *server) UpdateComment(c *gin.Context) {
+	postId, err := mapPostId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	id, err := mapCommentId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	comment, err := mapToCommentUpdate(c, postId, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := s.service.UpdateComment(c.Request.Context(), comment); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, commentIdResp(id))
+}
+
+// DeleteComment deletes the comment with all the replies to it
+func (s *server) DeleteComment(c *gin.Context) {
+	postId, err := mapPostId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	id, err := mapCommentId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := s.service.DeleteComment(c.Request.Context(), postId, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, msgStatusOk)
+}
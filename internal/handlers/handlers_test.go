@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"github.com/gavv/httpexpect/v2"
 	"github.com/gin-gonic/gin"
@@ -8,17 +9,26 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/voltento/go-blog-project/internal/domain"
 	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/markdown"
 	"github.com/voltento/go-blog-project/internal/middlewares"
+	"github.com/voltento/go-blog-project/internal/tracing/tracingtest"
 	"github.com/voltento/go-blog-project/mocks"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
+// problemJSON expects the problem details of an error
+var problemJSON = httpexpect.ContentOpts{MediaType: httperr.ContentType}
+
 type HandlersTestSuite struct {
 	suite.Suite
 	server   *httptest.Server
 	mockBlog *mocks.BlogService
+	tokens   *mocks.TokenIssuer
 	router   *gin.Engine
 
 	expect *httpexpect.Expect
@@ -26,24 +36,59 @@ type HandlersTestSuite struct {
 
 func (s *HandlersTestSuite) SetupTest() {
 	s.mockBlog = new(mocks.BlogService)
+	s.tokens = new(mocks.TokenIssuer)
 
 	gin.SetMode(gin.TestMode)
 	s.router = gin.Default()
 
 	middlewares.Setup(s.router)
-	RegisterHandlers(s.router, s.mockBlog)
+	RegisterHandlers(s.router, s.mockBlog, s.tokens)
 	s.server = httptest.NewServer(s.router)
 
 	s.expect = httpexpect.Default(s.T(), s.server.URL)
 }
 
 func (s *HandlersTestSuite) TestGetPostByID() {
-	s.mockBlog.On("Post", mock.Anything, domain.PostId(1)).Return(&domain.Post{ID: 1, Title: "Test Title", Content: "Test Content", Author: "Test Author"}, nil)
+	s.mockBlog.On("Post", mock.Anything, domain.PostId(1)).Return(&domain.Post{ID: 1, Title: "Test Title", Content: "Test Content", Author: "Test Author", Tags: []string{"go"}, Status: domain.StatusPublished, PublishAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), CreatedAt: time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC), Version: 3}, nil)
 
-	s.expect.GET("/v1/posts/1").
+	resp := s.expect.GET("/v1/posts/1").
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("ETag").IsEqual(`"3"`)
+	resp.Body().IsEqual("{\"ID\":1,\"Title\":\"Test Title\",\"Content\":\"Test Content\",\"AuthorID\":0,\"Author\":\"Test Author\",\"Tags\":[\"go\"],\"Status\":\"published\",\"PublishAt\":\"2024-05-01T10:00:00Z\",\"CreatedAt\":\"2024-04-30T09:00:00Z\",\"UpdatedAt\":\"2024-05-01T09:30:00Z\",\"Version\":3}")
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestGetPostByID_RenderHtml() {
+	post := &domain.Post{ID: 1, Title: "title", Content: "# Heading", Version: 2}
+	doc := &markdown.Document{HTML: `<h1 id="heading">Heading</h1>`, TOC: []*markdown.Heading{{Level: 1, ID: "heading", Title: "Heading"}}}
+	s.mockBlog.On("Post", mock.Anything, domain.PostId(1)).Return(post, nil)
+	s.mockBlog.On("RenderPost", post).Return(doc, nil)
+
+	obj := s.expect.GET("/v1/posts/1").
+		WithQuery("render", "html").
 		Expect().
 		Status(http.StatusOK).
-		Body().IsEqual("{\"ID\":1,\"Title\":\"Test Title\",\"Content\":\"Test Content\",\"Author\":\"Test Author\"}")
+		JSON().Object()
+	obj.Value("Content").IsEqual("# Heading")
+	obj.Value("HTML").IsEqual(doc.HTML)
+	obj.Value("TOC").IsEqual([]map[string]any{{"Level": 1, "ID": "heading", "Title": "Heading"}})
+
+	s.expect.GET("/v1/posts/1").WithQuery("render", "pdf").Expect().Status(http.StatusBadRequest)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestGetPostByID_LargeId() {
+	// The allocated ids are greater than 2^53, so the clients parsing numbers as float64 get them as strings
+	const id = domain.PostId(1<<55 + 1)
+	s.mockBlog.On("Post", mock.Anything, id).Return(&domain.Post{ID: id, Title: "title", Version: 1}, nil)
+
+	s.expect.GET("/v1/posts/36028797018963969").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("ID").IsEqual("36028797018963969")
 
 	s.mockBlog.AssertExpectations(s.T())
 }
@@ -55,12 +100,11 @@ func (s *HandlersTestSuite) TestGetPostByID_WrongPostIdFormat() {
 }
 
 func (s *HandlersTestSuite) TestCreatePost() {
-	newPost := &domain.Post{Title: "New Post", Content: "New Content", Author: "New Author"}
+	newPost := &domain.Post{Title: "New Post", Content: "New Content"}
 	s.mockBlog.On("CreatePost", mock.Anything, mock.Anything).Return(domain.PostId(1), nil).Run(func(args mock.Arguments) {
 		post := args.Get(1).(*domain.Post)
 		s.Equal(newPost.Title, post.Title)
 		s.Equal(newPost.Content, post.Content)
-		s.Equal(newPost.Author, post.Author)
 	})
 
 	s.expect.POST("/v1/posts").
@@ -94,7 +138,7 @@ func (s *HandlersTestSuite) TestCreatePost_ServiceCreateRequestFailed() {
 }
 
 func (s *HandlersTestSuite) TestDeletePost() {
-	s.mockBlog.On("DeletePost", mock.Anything, domain.PostId(1)).Return(nil)
+	s.mockBlog.On("DeletePost", mock.Anything, domain.PostId(1), domain.AnyVersion).Return(nil)
 
 	s.expect.DELETE("/v1/posts/1").Expect().
 		Status(http.StatusNoContent)
@@ -111,7 +155,7 @@ func (s *HandlersTestSuite) TestDeletePost_WrongPostIdFormat() {
 
 func (s *HandlersTestSuite) TestDeletePost_ServiceReturnsError() {
 	err := httperr.WrapWithHttpCode(errors.New(""), http.StatusConflict)
-	s.mockBlog.On("DeletePost", mock.Anything, domain.PostId(1)).Return(err)
+	s.mockBlog.On("DeletePost", mock.Anything, domain.PostId(1), domain.AnyVersion).Return(err)
 
 	s.expect.DELETE("/v1/posts/1").Expect().
 		Status(http.StatusConflict)
@@ -151,16 +195,75 @@ func (s *HandlersTestSuite) TestUpdatePost_ServiceReturnsError() {
 }
 
 func (s *HandlersTestSuite) TestUpdatePost_WrongPostFormat() {
-	s.expect.PUT("/v1/posts/1").
+	obj := s.expect.PUT("/v1/posts/1").
 		WithBytes([]byte(`{wrong format}`)).Expect().
-		Status(http.StatusBadRequest)
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object()
+	obj.Value("code").IsEqual(httperr.CodeMalformedBody)
+	obj.Value("detail").IsEqual("request body should be a JSON object")
+}
+
+func (s *HandlersTestSuite) TestCreatePost_InvalidFields() {
+	obj := s.expect.POST("/v1/posts").
+		WithBytes([]byte(`{"content":"","status":"hidden"}`)).Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object()
+	obj.IsEqual(map[string]any{
+		"type":     "urn:blog:problem:validation_failed",
+		"title":    "Bad Request",
+		"status":   400,
+		"code":     "validation_failed",
+		"detail":   "request is invalid: title is required, content is required, status should be one of draft, published, scheduled or archived, got 'hidden'",
+		"instance": "/v1/posts",
+		"errors": []map[string]any{
+			{"field": "title", "message": "is required"},
+			{"field": "content", "message": "is required"},
+			{"field": "status", "message": "should be one of draft, published, scheduled or archived, got 'hidden'"},
+		},
+	})
+}
+
+func (s *HandlersTestSuite) TestCreatePost_WrongFieldType() {
+	s.expect.POST("/v1/posts").
+		WithBytes([]byte(`{"title":1,"content":"Content"}`)).Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object().
+		Value("errors").IsEqual([]map[string]any{{"field": "title", "message": "should be a string"}})
+
+	s.expect.POST("/v1/posts").
+		WithBytes([]byte(`{"title":"Title","content":"Content","publish_at":"tomorrow"}`)).Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object().
+		Value("errors").IsEqual([]map[string]any{{"field": "publish_at", "message": "should be a time in RFC 3339 format"}})
 }
 
 func (s *HandlersTestSuite) TestGetPostByID_NotFound() {
 	s.mockBlog.On("Post", mock.Anything, domain.PostId(1)).Return(&domain.Post{}, httperr.WrapWithHttpCode(errors.New("post not found"), http.StatusNotFound))
 
-	s.expect.GET("/v1/posts/1").Expect().
-		Status(http.StatusNotFound)
+	obj := s.expect.GET("/v1/posts/1").Expect().
+		Status(http.StatusNotFound).
+		JSON(problemJSON).Object()
+	obj.Value("code").IsEqual(httperr.CodeNotFound)
+	obj.Value("detail").IsEqual("post not found")
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestGetPostByID_Traced() {
+	exporter := tracingtest.Record(s.T())
+	var serviceSpan trace.SpanContext
+	s.mockBlog.On("Post", mock.Anything, domain.PostId(1)).
+		Run(func(args mock.Arguments) {
+			serviceSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+		}).
+		Return(nil, httperr.WrapWithHttpCode(errors.New("post not found"), http.StatusNotFound))
+
+	s.expect.GET("/v1/posts/1").Expect().Status(http.StatusNotFound)
+
+	span := tracingtest.Span(s.T(), exporter, "handlers.GetPostByID")
+	s.Equal(span.SpanContext, serviceSpan, "the service should be called in the span of the handler")
+	s.Equal(codes.Error, span.Status.Code)
+	s.Equal("post not found", span.Status.Description)
 
 	s.mockBlog.AssertExpectations(s.T())
 }
@@ -181,12 +284,570 @@ func (s *HandlersTestSuite) TestPosts() {
 		&domain.Post{ID: 1, Title: "title", Content: "content", Author: "author"},
 		&domain.Post{ID: 1, Title: "title", Content: "content", Author: "author"},
 	}
-	s.mockBlog.On("Posts", mock.Anything).Return(posts)
+	s.mockBlog.On("PostsPage", mock.Anything, domain.PostsQuery{Limit: defaultPageLimit}).Return(&domain.Page{Posts: posts}, nil)
 
 	s.expect.GET("/v1/posts").
 		Expect().
 		Status(http.StatusOK).
-		Body().IsEqual(`{"posts":[{"ID":1,"Title":"title","Content":"content","Author":"author"},{"ID":1,"Title":"title","Content":"content","Author":"author"}]}`)
+		Body().IsEqual(`{"next_cursor":"","posts":[{"ID":1,"Title":"title","Content":"content","AuthorID":0,"Author":"author","Tags":null,"Status":"","PublishAt":"0001-01-01T00:00:00Z","CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","Version":0},{"ID":1,"Title":"title","Content":"content","AuthorID":0,"Author":"author","Tags":null,"Status":"","PublishAt":"0001-01-01T00:00:00Z","CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","Version":0}]}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestPosts_RenderHtml() {
+	posts := []*domain.Post{
+		{ID: 1, Title: "title", Content: "*first*"},
+		{ID: 2, Title: "title", Content: "*second*"},
+	}
+	s.mockBlog.On("PostsPage", mock.Anything, domain.PostsQuery{Limit: defaultPageLimit}).Return(&domain.Page{Posts: posts}, nil)
+	s.mockBlog.On("RenderPost", posts[0]).Return(&markdown.Document{HTML: "<p><em>first</em></p>"}, nil)
+	s.mockBlog.On("RenderPost", posts[1]).Return(&markdown.Document{HTML: "<p><em>second</em></p>"}, nil)
+
+	rendered := s.expect.GET("/v1/posts").
+		WithQuery("render", "html").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("posts").Array()
+	rendered.Length().IsEqual(2)
+	rendered.Value(0).Object().Value("HTML").IsEqual("<p><em>first</em></p>")
+	rendered.Value(1).Object().Value("HTML").IsEqual("<p><em>second</em></p>")
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestPosts_NextPage() {
+	posts := []*domain.Post{
+		{ID: 3, Title: "title", Content: "content", Author: "author"},
+		{ID: 4, Title: "title", Content: "content", Author: "author"},
+	}
+	s.mockBlog.On("PostsPage", mock.Anything, domain.PostsQuery{Limit: 2}).Return(&domain.Page{Posts: posts, HasMore: true}, nil)
+	s.mockBlog.On("PostsPage", mock.Anything, domain.PostsQuery{After: 4, Limit: 2}).Return(&domain.Page{}, nil)
+
+	nextCursor := s.expect.GET("/v1/posts").
+		WithQuery("limit", 2).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("next_cursor").String().NotEmpty().Raw()
+
+	s.expect.GET("/v1/posts").
+		WithQuery("limit", 2).
+		WithQuery("cursor", nextCursor).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("next_cursor").String().IsEmpty()
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestPosts_SortedNextPage() {
+	posts := []*domain.Post{
+		{ID: 3, Title: "title", Content: "content", Author: "author", CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{ID: 4, Title: "title", Content: "content", Author: "author", CreatedAt: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	first := domain.PostsQuery{Sort: domain.SortByCreatedAt, Desc: true, Limit: 2}
+	next := first
+	next.After, next.AfterKey = 4, domain.SortByCreatedAt.Key(posts[1])
+	s.mockBlog.On("PostsPage", mock.Anything, first).Return(&domain.Page{Posts: posts, HasMore: true}, nil)
+	s.mockBlog.On("PostsPage", mock.Anything, next).Return(&domain.Page{}, nil)
+
+	nextCursor := s.expect.GET("/v1/posts").
+		WithQuery("limit", 2).
+		WithQuery("sort", "created_at").
+		WithQuery("order", "desc").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("next_cursor").String().NotEmpty().Raw()
+
+	s.expect.GET("/v1/posts").
+		WithQuery("limit", 2).
+		WithQuery("sort", "created_at").
+		WithQuery("order", "desc").
+		WithQuery("cursor", nextCursor).
+		Expect().
+		Status(http.StatusOK)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestPosts_WrongQuery() {
+	s.expect.GET("/v1/posts").WithQuery("limit", 0).Expect().Status(http.StatusBadRequest)
+	s.expect.GET("/v1/posts").WithQuery("limit", maxPageLimit+1).Expect().Status(http.StatusBadRequest)
+	s.expect.GET("/v1/posts").WithQuery("cursor", "wrong cursor").Expect().Status(http.StatusBadRequest)
+	s.expect.GET("/v1/posts").WithQuery("sort", "author").Expect().Status(http.StatusBadRequest)
+	s.expect.GET("/v1/posts").WithQuery("order", "random").Expect().Status(http.StatusBadRequest)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestPosts_ServiceReturnsError() {
+	err := httperr.WrapWithHttpCode(errors.New(""), http.StatusServiceUnavailable)
+	s.mockBlog.On("PostsPage", mock.Anything, mock.Anything).Return(nil, err)
+
+	s.expect.GET("/v1/posts").Expect().Status(http.StatusServiceUnavailable)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestSearch() {
+	results := []*domain.SearchResult{
+		{Post: &domain.Post{ID: 2, Title: "title", Content: "go content", Author: "author"}, Score: 1.5, Snippet: "<mark>go</mark> content"},
+	}
+	s.mockBlog.On("Search", mock.Anything, "go", 5).Return(results, nil)
+
+	s.expect.GET("/v1/posts/search").
+		WithQuery("q", "go").
+		WithQuery("limit", 5).
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual(`{"results":[{"post":{"ID":2,"Title":"title","Content":"go content","AuthorID":0,"Author":"author","Tags":null,"Status":"","PublishAt":"0001-01-01T00:00:00Z","CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","Version":0},"score":1.5,"snippet":"\u003cmark\u003ego\u003c/mark\u003e content"}]}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestSearch_WrongQuery() {
+	s.expect.GET("/v1/posts/search").Expect().Status(http.StatusBadRequest)
+	s.expect.GET("/v1/posts/search").WithQuery("q", "  ").Expect().Status(http.StatusBadRequest)
+	s.expect.GET("/v1/posts/search").WithQuery("q", "go").WithQuery("limit", "abc").Expect().Status(http.StatusBadRequest)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestSearch_ServiceReturnsError() {
+	s.mockBlog.On("Search", mock.Anything, "go", defaultPageLimit).Return(nil, errors.New("error"))
+
+	obj := s.expect.GET("/v1/posts/search").WithQuery("q", "go").Expect().
+		Status(http.StatusInternalServerError).
+		JSON(problemJSON).Object()
+	obj.Value("code").IsEqual(httperr.CodeInternal)
+	obj.NotContainsKey("detail")
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestGetPostByID_NotModified() {
+	s.mockBlog.On("Post", mock.Anything, domain.PostId(1)).Return(&domain.Post{ID: 1, Version: 3}, nil)
+
+	s.expect.GET("/v1/posts/1").
+		WithHeader("If-None-Match", `"2", "3"`).
+		Expect().
+		Status(http.StatusNotModified).
+		Body().IsEmpty()
+
+	s.expect.GET("/v1/posts/1").
+		WithHeader("If-None-Match", `"2"`).
+		Expect().
+		Status(http.StatusOK)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestGetPostByID_NotModifiedRendered() {
+	post := &domain.Post{ID: 1, Title: "Title", Content: "# Title", Version: 3}
+	s.mockBlog.On("Post", mock.Anything, domain.PostId(1)).Return(post, nil)
+	s.mockBlog.On("RenderPost", post).Return(&markdown.Document{HTML: "<h1>Title</h1>"}, nil)
+
+	s.expect.GET("/v1/posts/1").WithQuery("render", "html").
+		WithHeader("If-None-Match", `"3"`).
+		Expect().
+		Status(http.StatusOK).
+		Header("ETag").IsEqual(`"3-html"`)
+
+	s.expect.GET("/v1/posts/1").WithQuery("render", "html").
+		WithHeader("If-None-Match", `"3-html"`).
+		Expect().
+		Status(http.StatusNotModified)
+
+	s.expect.GET("/v1/posts/1").
+		WithHeader("If-None-Match", `"3-html"`).
+		Expect().
+		Status(http.StatusOK).
+		Header("ETag").IsEqual(`"3"`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestUpdatePost_IfMatch() {
+	s.mockBlog.On("UpdatePost", mock.Anything, mock.Anything, domain.PostId(1)).Return(nil).Run(func(args mock.Arguments) {
+		post := args.Get(1).(*domain.Post)
+		s.Equal(3, post.Version, "the version from If-Match should be passed")
+		post.Version++
+	})
+
+	s.expect.PUT("/v1/posts/1").
+		WithHeader("If-Match", `"3"`).
+		WithBytes([]byte(`{"title":"Updated Title","content":"Updated Content","author":"Updated Author"}`)).
+		Expect().
+		Status(http.StatusOK).
+		Header("ETag").IsEqual(`"4"`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestUpdatePost_PreconditionFailed() {
+	err := httperr.WrapWithHttpCode(errors.New("version mismatch"), http.StatusPreconditionFailed)
+	s.mockBlog.On("UpdatePost", mock.Anything, mock.Anything, domain.PostId(1)).Return(err)
+
+	s.expect.PUT("/v1/posts/1").
+		WithHeader("If-Match", `"2"`).
+		WithBytes([]byte(`{"title":"Updated Title","content":"Updated Content","author":"Updated Author"}`)).
+		Expect().
+		Status(http.StatusPreconditionFailed)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestDeletePost_IfMatch() {
+	s.mockBlog.On("DeletePost", mock.Anything, domain.PostId(1), 2).Return(nil)
+	s.mockBlog.On("DeletePost", mock.Anything, domain.PostId(2), domain.AnyVersion).Return(nil)
+
+	s.expect.DELETE("/v1/posts/1").WithHeader("If-Match", `"2"`).Expect().Status(http.StatusNoContent)
+	s.expect.DELETE("/v1/posts/2").WithHeader("If-Match", "*").Expect().Status(http.StatusNoContent)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestDeletePost_WrongIfMatch() {
+	for _, header := range []string{"2", `"abc"`, `"1", "2"`, `W/"1"`, `"0"`} {
+		s.expect.DELETE("/v1/posts/1").WithHeader("If-Match", header).Expect().Status(http.StatusBadRequest)
+	}
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestRevisions() {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	revisions := []*domain.Revision{
+		{PostID: 1, Number: 1, Author: "Author", CreatedAt: createdAt, Content: "content"},
+		{PostID: 1, Number: 2, Author: "Editor", CreatedAt: createdAt.Add(time.Hour), Content: "updated"},
+	}
+	s.mockBlog.On("Revisions", mock.Anything, domain.PostId(1)).Return(revisions, nil)
+
+	s.expect.GET("/v1/posts/1/revisions").
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual(`{"revisions":[{"author":"Author","created_at":"2024-05-01T10:00:00Z","number":1},{"author":"Editor","created_at":"2024-05-01T11:00:00Z","number":2}]}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestRevisions_NotFound() {
+	s.mockBlog.On("Revisions", mock.Anything, domain.PostId(1)).Return(nil, httperr.WrapWithHttpCode(errors.New("post not found"), http.StatusNotFound))
+
+	s.expect.GET("/v1/posts/1/revisions").
+		Expect().
+		Status(http.StatusNotFound)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestRevision() {
+	rev := &domain.Revision{
+		PostID:    1,
+		Number:    2,
+		Title:     "Title",
+		Content:   "updated",
+		Author:    "Editor",
+		CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Diff:      "-content\n+updated\n",
+	}
+	s.mockBlog.On("Revision", mock.Anything, domain.PostId(1), 2).Return(rev, nil)
+
+	s.expect.GET("/v1/posts/1/revisions/2").
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual(`{"author":"Editor","content":"updated","created_at":"2024-05-01T10:00:00Z","diff":"-content\n+updated\n","number":2,"title":"Title"}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestRevision_WrongNumberFormat() {
+	s.expect.GET("/v1/posts/1/revisions/last").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func (s *HandlersTestSuite) TestRestoreRevision() {
+	s.mockBlog.On("RestoreRevision", mock.Anything, domain.PostId(1), 2).Return(&domain.Post{ID: 1, Version: 4}, nil)
+
+	resp := s.expect.POST("/v1/posts/1/revisions/2/restore").
+		Expect().
+		Status(http.StatusOK)
+	resp.Header("ETag").IsEqual(`"4"`)
+	resp.Body().IsEqual(`{"postId":1}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestRestoreRevision_NotFound() {
+	s.mockBlog.On("RestoreRevision", mock.Anything, domain.PostId(1), 5).Return(nil, httperr.WrapWithHttpCode(errors.New("revision not found"), http.StatusNotFound))
+
+	s.expect.POST("/v1/posts/1/revisions/5/restore").
+		Expect().
+		Status(http.StatusNotFound)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestComments() {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	threads := []*domain.CommentThread{
+		{
+			Comment: &domain.Comment{ID: 1, PostID: 1, Author: "Author", Content: "first", CreatedAt: createdAt, UpdatedAt: createdAt},
+			Replies: []*domain.CommentThread{
+				{Comment: &domain.Comment{ID: 2, PostID: 1, ParentID: 1, Author: "Reader", Content: "reply", CreatedAt: createdAt, UpdatedAt: createdAt}},
+			},
+		},
+	}
+	s.mockBlog.On("CommentThreads", mock.Anything, domain.PostId(1)).Return(threads, nil)
+
+	s.expect.GET("/v1/posts/1/comments").
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual(`{"comments":[{"author":"Author","content":"first","created_at":"2024-05-01T10:00:00Z","id":1,"parent_id":0,"replies":[` +
+		`{"author":"Reader","content":"reply","created_at":"2024-05-01T10:00:00Z","id":2,"parent_id":1,"replies":[],"updated_at":"2024-05-01T10:00:00Z"}` +
+		`],"updated_at":"2024-05-01T10:00:00Z"}]}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestComments_PostNotFound() {
+	s.mockBlog.On("CommentThreads", mock.Anything, domain.PostId(1)).Return(nil, httperr.WrapWithHttpCode(errors.New("post not found"), http.StatusNotFound))
+
+	s.expect.GET("/v1/posts/1/comments").
+		Expect().
+		Status(http.StatusNotFound)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestCreateComment() {
+	expected := &domain.Comment{PostID: 1, ParentID: 2, Content: "reply"}
+	s.mockBlog.On("CreateComment", mock.Anything, expected).Return(domain.CommentId(3), nil)
+
+	s.expect.POST("/v1/posts/1/comments").
+		WithJSON(map[string]any{"parent_id": 2, "content": "reply"}).
+		Expect().
+		Status(http.StatusCreated).
+		Body().IsEqual(`{"commentId":3}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestCreateComment_WrongCommentFormat() {
+	s.expect.POST("/v1/posts/1/comments").
+		WithJSON(map[string]any{"parent_id": 2}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func (s *HandlersTestSuite) TestCreateComment_ParentNotFound() {
+	err := httperr.WrapWithHttpCode(errors.New("parent comment not found"), http.StatusUnprocessableEntity)
+	s.mockBlog.On("CreateComment", mock.Anything, mock.Anything).Return(domain.CommentId(0), err)
+
+	s.expect.POST("/v1/posts/1/comments").
+		WithJSON(map[string]any{"parent_id": 5, "author": "Reader", "content": "reply"}).
+		Expect().
+		Status(http.StatusUnprocessableEntity)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestUpdateComment() {
+	s.mockBlog.On("UpdateComment", mock.Anything, &domain.Comment{ID: 2, PostID: 1, Content: "updated"}).Return(nil)
+
+	s.expect.PUT("/v1/posts/1/comments/2").
+		WithJSON(map[string]string{"content": "updated"}).
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual(`{"commentId":2}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestUpdateComment_WrongCommentIdFormat() {
+	s.expect.PUT("/v1/posts/1/comments/wrongId").
+		WithJSON(map[string]string{"content": "updated"}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func (s *HandlersTestSuite) TestDeleteComment() {
+	s.mockBlog.On("DeleteComment", mock.Anything, domain.PostId(1), domain.CommentId(2)).Return(nil)
+
+	s.expect.DELETE("/v1/posts/1/comments/2").
+		Expect().
+		Status(http.StatusNoContent)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestCreatePost_Tags() {
+	s.mockBlog.On("CreatePost", mock.Anything, mock.Anything).Return(domain.PostId(1), nil).Run(func(args mock.Arguments) {
+		s.Equal([]string{"go", "k8s"}, args.Get(1).(*domain.Post).Tags)
+	})
+
+	s.expect.POST("/v1/posts").
+		WithJSON(map[string]any{"title": "Title", "content": "Content", "author": "Author", "tags": []string{"go", "k8s"}}).
+		Expect().
+		Status(http.StatusCreated)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestPosts_FilterByTags() {
+	s.mockBlog.On("PostsPage", mock.Anything, domain.PostsQuery{Limit: defaultPageLimit, Tags: []string{"go", "k8s"}}).Return(&domain.Page{}, nil)
+	s.mockBlog.On("PostsPage", mock.Anything, domain.PostsQuery{Limit: defaultPageLimit, Tags: []string{"go", "k8s"}, TagMatch: domain.MatchAnyTag}).Return(&domain.Page{}, nil)
+
+	s.expect.GET("/v1/posts").
+		WithQuery("tag", "go").
+		WithQuery("tag", "k8s").
+		Expect().
+		Status(http.StatusOK)
+
+	s.expect.GET("/v1/posts").
+		WithQuery("tag", "go").
+		WithQuery("tag", "k8s").
+		WithQuery("match", "any").
+		Expect().
+		Status(http.StatusOK)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestPosts_WrongTagMatch() {
+	s.expect.GET("/v1/posts").
+		WithQuery("tag", "go").
+		WithQuery("match", "some").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func (s *HandlersTestSuite) TestTags() {
+	counts := []*domain.TagCount{{Tag: "go", Posts: 3}, {Tag: "k8s", Posts: 1}}
+	s.mockBlog.On("Tags", mock.Anything).Return(counts, nil)
+
+	s.expect.GET("/v1/tags").
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual(`{"tags":[{"posts":3,"tag":"go"},{"posts":1,"tag":"k8s"}]}`)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestCreatePost_Scheduled() {
+	publishAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	s.mockBlog.On("CreatePost", mock.Anything, &domain.Post{Title: "Title", Content: "Content", Status: domain.StatusScheduled, PublishAt: publishAt}).
+		Return(domain.PostId(1), nil)
+
+	s.expect.POST("/v1/posts").
+		WithJSON(map[string]string{"title": "Title", "content": "Content", "status": "scheduled", "publish_at": "2024-05-02T10:00:00Z"}).
+		Expect().
+		Status(http.StatusCreated)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestCreatePost_WrongStatus() {
+	s.expect.POST("/v1/posts").
+		WithJSON(map[string]string{"title": "Title", "content": "Content", "status": "hidden"}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestSignup() {
+	s.mockBlog.On("Signup", mock.Anything, &domain.User{Username: "alice", DisplayName: "Alice"}, "password").Return(domain.UserId(2), nil)
+
+	s.expect.POST("/v1/users").
+		WithJSON(map[string]string{"username": "alice", "password": "password", "display_name": "Alice"}).
+		Expect().
+		Status(http.StatusCreated).
+		Body().IsEqual("{\"userId\":2}")
+
+	s.mockBlog.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestSignup_WrongFormat() {
+	s.expect.POST("/v1/users").
+		WithJSON(map[string]string{"username": "alice"}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func (s *HandlersTestSuite) TestSignup_UsernameTaken() {
+	err := httperr.WrapWithHttpCode(errors.New("username is taken"), http.StatusConflict)
+	s.mockBlog.On("Signup", mock.Anything, mock.Anything, "password").Return(domain.UserId(0), err)
+
+	s.expect.POST("/v1/users").
+		WithJSON(map[string]string{"username": "alice", "password": "password"}).
+		Expect().
+		Status(http.StatusConflict)
+}
+
+func (s *HandlersTestSuite) TestLogin() {
+	expiresAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	s.mockBlog.On("Login", mock.Anything, "alice", "password").Return(&domain.User{ID: 2, Username: "alice"}, nil)
+	s.tokens.On("IssueToken", "2").Return("token", expiresAt, nil)
+
+	s.expect.POST("/v1/users/login").
+		WithJSON(map[string]string{"username": "alice", "password": "password"}).
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual("{\"expires_at\":\"2024-05-02T10:00:00Z\",\"token\":\"token\"}")
+
+	s.mockBlog.AssertExpectations(s.T())
+	s.tokens.AssertExpectations(s.T())
+}
+
+func (s *HandlersTestSuite) TestLogin_InvalidPassword() {
+	err := httperr.WrapWithHttpCode(errors.New("invalid username or password"), http.StatusUnauthorized)
+	s.mockBlog.On("Login", mock.Anything, "alice", "wrong").Return(nil, err)
+
+	s.expect.POST("/v1/users/login").
+		WithJSON(map[string]string{"username": "alice", "password": "wrong"}).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	s.tokens.AssertNotCalled(s.T(), "IssueToken", mock.Anything)
+}
+
+func (s *HandlersTestSuite) TestUser() {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s.mockBlog.On("User", mock.Anything, domain.UserId(2)).Return(&domain.User{
+		ID: 2, Username: "alice", DisplayName: "Alice", Bio: "Bio", PasswordHash: "hash", CreatedAt: createdAt,
+	}, nil)
+
+	s.expect.GET("/v1/users/2").
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual("{\"bio\":\"Bio\",\"created_at\":\"2024-05-01T10:00:00Z\",\"display_name\":\"Alice\",\"id\":2,\"username\":\"alice\"}")
+}
+
+func (s *HandlersTestSuite) TestUser_WrongIdFormat() {
+	s.expect.GET("/v1/users/alice").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func (s *HandlersTestSuite) TestCurrentUser() {
+	s.mockBlog.On("CurrentUser", mock.Anything).Return(&domain.User{ID: 2, Username: "alice"}, nil)
+
+	s.expect.GET("/v1/users/me").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().HasValue("id", 2).HasValue("username", "alice")
+}
+
+func (s *HandlersTestSuite) TestUpdateProfile() {
+	s.mockBlog.On("UpdateProfile", mock.Anything, &domain.User{DisplayName: "Alice", Bio: "Bio"}).
+		Return(&domain.User{ID: 2, Username: "alice", DisplayName: "Alice", Bio: "Bio"}, nil)
+
+	s.expect.PUT("/v1/users/me").
+		WithJSON(map[string]string{"display_name": "Alice", "bio": "Bio"}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().HasValue("display_name", "Alice").NotContainsKey("PasswordHash")
 
 	s.mockBlog.AssertExpectations(s.T())
 }
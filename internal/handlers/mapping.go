@@ -1,12 +1,24 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/bulk"
 	"github.com/voltento/go-blog-project/internal/domain"
 	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/markdown"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
 )
 
 var msgStatusOk = gin.H{"status": "ok"}
@@ -15,35 +27,468 @@ func postIdResp(id domain.PostId) gin.H {
 	return gin.H{"postId": id}
 }
 
+func commentIdResp(id domain.CommentId) gin.H {
+	return gin.H{"commentId": id}
+}
+
+func userIdResp(id domain.UserId) gin.H {
+	return gin.H{"userId": id}
+}
+
+func tokenResp(token string, expiresAt time.Time) gin.H {
+	return gin.H{"token": token, "expires_at": expiresAt}
+}
+
+// userResp renders the public profile of the user, the password hash is never rendered
+func userResp(user *domain.User) gin.H {
+	return gin.H{
+		"id":           user.ID,
+		"username":     user.Username,
+		"display_name": user.DisplayName,
+		"bio":          user.Bio,
+		"created_at":   user.CreatedAt,
+	}
+}
+
+func postsPageResp(page *domain.Page, sort domain.PostSort) gin.H {
+	nextCursor := ""
+	if page.HasMore && len(page.Posts) > 0 {
+		nextCursor = encodeCursor(page.Posts[len(page.Posts)-1], sort)
+	}
+
+	return gin.H{"posts": page.Posts, "next_cursor": nextCursor}
+}
+
+// renderedPost is a post with the content rendered to HTML, it's rendered with the fields of the post
+type renderedPost struct {
+	*domain.Post
+	HTML string
+	TOC  []*markdown.Heading
+}
+
+func renderedPostResp(post *domain.Post, doc *markdown.Document) *renderedPost {
+	return &renderedPost{Post: post, HTML: doc.HTML, TOC: doc.TOC}
+}
+
+func searchResultsResp(results []*domain.SearchResult) gin.H {
+	resp := make([]gin.H, 0, len(results))
+	for _, r := range results {
+		resp = append(resp, gin.H{"post": r.Post, "score": r.Score, "snippet": r.Snippet})
+	}
+
+	return gin.H{"results": resp}
+}
+
+func tagsResp(counts []*domain.TagCount) gin.H {
+	resp := make([]gin.H, 0, len(counts))
+	for _, c := range counts {
+		resp = append(resp, gin.H{"tag": c.Tag, "posts": c.Posts})
+	}
+
+	return gin.H{"tags": resp}
+}
+
+func revisionSummaryResp(rev *domain.Revision) gin.H {
+	return gin.H{"number": rev.Number, "author": rev.Author, "created_at": rev.CreatedAt}
+}
+
+func revisionsResp(revisions []*domain.Revision) gin.H {
+	resp := make([]gin.H, 0, len(revisions))
+	for _, rev := range revisions {
+		resp = append(resp, revisionSummaryResp(rev))
+	}
+
+	return gin.H{"revisions": resp}
+}
+
+func revisionResp(rev *domain.Revision) gin.H {
+	resp := revisionSummaryResp(rev)
+	resp["title"] = rev.Title
+	resp["content"] = rev.Content
+	resp["diff"] = rev.Diff
+	return resp
+}
+
+func commentThreadResp(thread *domain.CommentThread) gin.H {
+	c := thread.Comment
+	replies := make([]gin.H, 0, len(thread.Replies))
+	for _, reply := range thread.Replies {
+		replies = append(replies, commentThreadResp(reply))
+	}
+
+	return gin.H{
+		"id":         c.ID,
+		"parent_id":  c.ParentID,
+		"author":     c.Author,
+		"content":    c.Content,
+		"created_at": c.CreatedAt,
+		"updated_at": c.UpdatedAt,
+		"replies":    replies,
+	}
+}
+
+func commentThreadsResp(threads []*domain.CommentThread) gin.H {
+	resp := make([]gin.H, 0, len(threads))
+	for _, thread := range threads {
+		resp = append(resp, commentThreadResp(thread))
+	}
+
+	return gin.H{"comments": resp}
+}
+
+// cursor is a position in the posts listing. It's passed to clients as an opaque string
+type cursor struct {
+	After domain.PostId `json:"after"`
+	// Key is the sort key of the After post
+	Key string `json:"key,omitempty"`
+}
+
+func encodeCursor(last *domain.Post, sort domain.PostSort) string {
+	data, _ := json.Marshal(cursor{After: last.ID, Key: sort.Key(last)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var cur cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &cur)
+	}
+
+	if err != nil {
+		return cursor{}, httperr.Invalid(httperr.FieldError{Field: "cursor", Message: "should be a cursor returned by the listing"})
+	}
+
+	return cur, nil
+}
+
+// PostDTO is a post written by a client. The author is the authenticated user, so it's not accepted from the client.
+// The lengths are counted in characters.
 type PostDTO struct {
 	ID      domain.PostId `json:"id"`
-	Title   string        `json:"title" binding:"required"`
-	Content string        `json:"content" binding:"required"`
-	Author  string        `json:"author" binding:"required"`
+	Title   string        `json:"title" binding:"required,max=200"`
+	Content string        `json:"content" binding:"required,max=100000"`
+	Tags    []string      `json:"tags"`
+	// Status is one of draft, published, scheduled or archived. A new post is published and a changed post keeps
+	// its status if it's omitted.
+	Status string `json:"status"`
+	// PublishAt is the time a scheduled post is published at
+	PublishAt time.Time `json:"publish_at"`
 }
 
-func mapPostId(c *gin.Context) (domain.PostId, error) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+// CommentDTO is a comment written by a client, its author is the authenticated user
+type CommentDTO struct {
+	// ParentID is the comment to reply to, it's omitted for a top-level comment
+	ParentID domain.CommentId `json:"parent_id"`
+	Content  string           `json:"content" binding:"required"`
+}
+
+// CommentUpdateDTO is the editable part of a comment
+type CommentUpdateDTO struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// SignupDTO is a new user account, the display name defaults to the username
+type SignupDTO struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+}
+
+type LoginDTO struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ProfileDTO is the editable part of a user
+type ProfileDTO struct {
+	DisplayName string `json:"display_name" binding:"required"`
+	Bio         string `json:"bio"`
+}
+
+// mapNumberParam parses the path parameter as a number
+func mapNumberParam(c *gin.Context, name string) (int, error) {
+	str := c.Param(name)
+	n, err := strconv.Atoi(str)
 	if err != nil {
-		err = fmt.Errorf("can not parse post id '%s'. error: %w", idStr, err)
-		return 0, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+		return 0, httperr.Invalid(httperr.FieldError{Field: name, Message: fmt.Sprintf("should be a number, got '%s'", str)})
+	}
+
+	return n, nil
+}
+
+func mapPostId(c *gin.Context) (domain.PostId, error) {
+	n, err := mapNumberParam(c, "id")
+	return domain.PostId(n), err
+}
+
+func mapRevisionNumber(c *gin.Context) (int, error) {
+	return mapNumberParam(c, "rev")
+}
+
+func mapCommentId(c *gin.Context) (domain.CommentId, error) {
+	n, err := mapNumberParam(c, "commentId")
+	return domain.CommentId(n), err
+}
+
+func mapUserId(c *gin.Context) (domain.UserId, error) {
+	n, err := mapNumberParam(c, "id")
+	return domain.UserId(n), err
+}
+
+func mapToSignup(c *gin.Context) (*domain.User, string, error) {
+	var signup SignupDTO
+	if err := bindJSON(c, &signup); err != nil {
+		return nil, "", err
+	}
+
+	user := &domain.User{Username: signup.Username, DisplayName: signup.DisplayName, Bio: signup.Bio}
+	return user, signup.Password, nil
+}
+
+func mapToLogin(c *gin.Context) (LoginDTO, error) {
+	var login LoginDTO
+	if err := bindJSON(c, &login); err != nil {
+		return LoginDTO{}, err
+	}
+
+	return login, nil
+}
+
+func mapToProfile(c *gin.Context) (*domain.User, error) {
+	var profile ProfileDTO
+	if err := bindJSON(c, &profile); err != nil {
+		return nil, err
 	}
 
-	return domain.PostId(id), nil
+	return &domain.User{DisplayName: profile.DisplayName, Bio: profile.Bio}, nil
+}
+
+func mapToComment(c *gin.Context, postId domain.PostId) (*domain.Comment, error) {
+	var newComment CommentDTO
+	if err := bindJSON(c, &newComment); err != nil {
+		return nil, err
+	}
+
+	return &domain.Comment{
+		PostID:   postId,
+		ParentID: newComment.ParentID,
+		Content:  newComment.Content,
+	}, nil
+}
+
+func mapToCommentUpdate(c *gin.Context, postId domain.PostId, id domain.CommentId) (*domain.Comment, error) {
+	var update CommentUpdateDTO
+	if err := bindJSON(c, &update); err != nil {
+		return nil, err
+	}
+
+	return &domain.Comment{ID: id, PostID: postId, Content: update.Content}, nil
 }
 
 func mapToPost(c *gin.Context) (*domain.Post, error) {
 	var newPost PostDTO
-	if err := c.BindJSON(&newPost); err != nil {
-		err = httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	fields, err := bindFields(c, &newPost)
+	if err != nil {
 		return nil, err
 	}
 
+	status := domain.PostStatus(newPost.Status)
+	if status != "" && !status.IsValid() {
+		fields = append(fields, httperr.FieldError{
+			Field:   "status",
+			Message: fmt.Sprintf("should be one of draft, published, scheduled or archived, got '%s'", newPost.Status),
+		})
+	}
+
+	if len(fields) > 0 {
+		return nil, httperr.Invalid(fields...)
+	}
+
 	return &domain.Post{
-		ID:      newPost.ID,
-		Title:   newPost.Title,
-		Content: newPost.Content,
-		Author:  newPost.Author,
+		ID:        newPost.ID,
+		Title:     newPost.Title,
+		Content:   newPost.Content,
+		Tags:      newPost.Tags,
+		Status:    status,
+		PublishAt: newPost.PublishAt,
 	}, nil
 }
+
+func mapLimit(c *gin.Context) (int, error) {
+	limitStr, ok := c.GetQuery("limit")
+	if !ok {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err == nil && (limit < 1 || limit > maxPageLimit) {
+		err = errors.New("out of range")
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("should be a number from 1 to %d, got '%s'", maxPageLimit, limitStr)
+		return 0, httperr.Invalid(httperr.FieldError{Field: "limit", Message: msg})
+	}
+
+	return limit, nil
+}
+
+// postSorts are the values of the sort query parameter, posts are ordered by ID without it
+var postSorts = map[string]domain.PostSort{
+	"created_at": domain.SortByCreatedAt,
+	"updated_at": domain.SortByUpdatedAt,
+	"title":      domain.SortByTitle,
+}
+
+func mapPostsQuery(c *gin.Context) (domain.PostsQuery, error) {
+	limit, err := mapLimit(c)
+	if err != nil {
+		return domain.PostsQuery{}, err
+	}
+	q := domain.PostsQuery{Limit: limit, Tags: c.QueryArray("tag")}
+
+	switch match := c.DefaultQuery("match", "all"); match {
+	case "all":
+		q.TagMatch = domain.MatchAllTags
+	case "any":
+		q.TagMatch = domain.MatchAnyTag
+	default:
+		msg := fmt.Sprintf("should be either 'all' or 'any', got '%s'", match)
+		return domain.PostsQuery{}, httperr.Invalid(httperr.FieldError{Field: "match", Message: msg})
+	}
+
+	if sortStr, ok := c.GetQuery("sort"); ok {
+		sort, known := postSorts[sortStr]
+		if !known {
+			msg := fmt.Sprintf("should be one of 'created_at', 'updated_at' or 'title', got '%s'", sortStr)
+			return domain.PostsQuery{}, httperr.Invalid(httperr.FieldError{Field: "sort", Message: msg})
+		}
+		q.Sort = sort
+	}
+
+	switch order := c.DefaultQuery("order", "asc"); order {
+	case "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		msg := fmt.Sprintf("should be either 'asc' or 'desc', got '%s'", order)
+		return domain.PostsQuery{}, httperr.Invalid(httperr.FieldError{Field: "order", Message: msg})
+	}
+
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cur, err := decodeCursor(cursorStr)
+		if err != nil {
+			return domain.PostsQuery{}, err
+		}
+
+		// A cursor of another sort has a key of another kind
+		if _, err := q.Sort.Position(cur.After, cur.Key); err != nil {
+			return domain.PostsQuery{}, httperr.Invalid(httperr.FieldError{Field: "cursor", Message: "does not match the sort"})
+		}
+		q.After, q.AfterKey = cur.After, cur.Key
+	}
+
+	return q, nil
+}
+
+// mapRender reports whether the content of the posts is requested rendered to HTML
+func mapRender(c *gin.Context) (bool, error) {
+	switch render := c.Query("render"); render {
+	case "":
+		return false, nil
+	case "html":
+		return true, nil
+	default:
+		msg := fmt.Sprintf("should be 'html', got '%s'", render)
+		return false, httperr.Invalid(httperr.FieldError{Field: "render", Message: msg})
+	}
+}
+
+// mapBulkFormat returns the format of an import or export from the format parameter.
+// An import without the parameter is CSV if it's sent as text/csv.
+func mapBulkFormat(c *gin.Context) (bulk.Format, error) {
+	name := c.Query("format")
+	if name == "" && c.Request.Method == http.MethodPost && c.ContentType() == "text/csv" {
+		name = string(bulk.CSV)
+	}
+	return bulk.ParseFormat(name)
+}
+
+// importErrorResp is the extension of the problem details listing the invalid rows of the import
+func importErrorResp(err *bulk.ImportError) gin.H {
+	rows := make([]gin.H, 0, len(err.Rows))
+	for _, row := range err.Rows {
+		rows = append(rows, gin.H{"row": row.Row, "error": row.Err.Error()})
+	}
+	return gin.H{"rows": rows, "truncated": err.Truncated}
+}
+
+func mapSearchQuery(c *gin.Context) (string, int, error) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return "", 0, httperr.Invalid(httperr.FieldError{Field: "q", Message: "is required"})
+	}
+
+	limit, err := mapLimit(c)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return query, limit, nil
+}
+
+// renderedSuffix tells the entity tag of the rendered post from the tag of the post of the same version
+const renderedSuffix = "-html"
+
+// etag returns the entity tag of the post version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// renderedETag returns the entity tag of the post version rendered to HTML.
+// The rendered post is another representation of the post, so it has another tag.
+func renderedETag(version int) string {
+	return `"` + strconv.Itoa(version) + renderedSuffix + `"`
+}
+
+// etagMatches reports whether the list of entity tags from If-None-Match has the tag.
+// Weak tags are compared by their value as weak comparison is used for If-None-Match.
+func etagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// mapIfMatch returns the version a change is based on from If-Match header.
+// It's domain.AnyVersion if the header is not set or it's "*". The tag of the rendered post names its version as well.
+func mapIfMatch(c *gin.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return domain.AnyVersion, nil
+	}
+
+	var version int
+	var err error
+	if len(header) > 2 && strings.HasPrefix(header, `"`) && strings.HasSuffix(header, `"`) {
+		version, err = strconv.Atoi(strings.TrimSuffix(header[1:len(header)-1], renderedSuffix))
+	} else {
+		err = errors.New("not a quoted entity tag")
+	}
+
+	if err == nil && version == domain.AnyVersion {
+		err = errors.New("unknown version")
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("should be a single entity tag returned as ETag, got '%s'", header)
+		return 0, httperr.Invalid(httperr.FieldError{Field: "If-Match", Message: msg})
+	}
+
+	return version, nil
+}
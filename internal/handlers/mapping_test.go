@@ -54,8 +54,15 @@ func TestMapToPost(t *testing.T) {
 	}{
 		{
 			"Valid JSON Payload",
+			`{"title":"New Post","content":"New Content"}`,
+			&domain.Post{Title: "New Post", Content: "New Content"},
+			false,
+			0,
+		},
+		{
+			"Author is ignored",
 			`{"title":"New Post","content":"New Content","author":"New Author"}`,
-			&domain.Post{Title: "New Post", Content: "New Content", Author: "New Author"},
+			&domain.Post{Title: "New Post", Content: "New Content"},
 			false,
 			0,
 		},
@@ -67,22 +74,22 @@ func TestMapToPost(t *testing.T) {
 			http.StatusBadRequest,
 		},
 		{
-			"Missing author",
-			`{"title":"New Post","content":"New Content"}`,
+			"Missing title",
+			`{"content":"New Content","author":"New Author"}`,
 			nil,
 			true,
 			http.StatusBadRequest,
 		},
 		{
-			"Missing title",
-			`{"content":"New Content","author":"New Author"}`,
+			"Missing content",
+			`{"title":"New Post", "author":"New Author"}`,
 			nil,
 			true,
 			http.StatusBadRequest,
 		},
 		{
-			"Missing content",
-			`{"title":"New Post", "author":"New Author"}`,
+			"Too long title",
+			`{"title":"` + strings.Repeat("t", 201) + `","content":"New Content"}`,
 			nil,
 			true,
 			http.StatusBadRequest,
@@ -108,3 +115,47 @@ func TestMapToPost(t *testing.T) {
 		})
 	}
 }
+
+func TestMapPostsQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedQuery domain.PostsQuery
+		expectedErr   bool
+	}{
+		{"Default limit", "", domain.PostsQuery{Limit: defaultPageLimit}, false},
+		{"Custom limit", "limit=5", domain.PostsQuery{Limit: 5}, false},
+		{"Cursor", "cursor=" + encodeCursor(&domain.Post{ID: 7}, domain.SortByID), domain.PostsQuery{After: 7, Limit: defaultPageLimit}, false},
+		{"Sort", "sort=updated_at&order=desc", domain.PostsQuery{Sort: domain.SortByUpdatedAt, Desc: true, Limit: defaultPageLimit}, false},
+		{
+			"Sorted cursor",
+			"sort=title&cursor=" + encodeCursor(&domain.Post{ID: 7, Title: "title"}, domain.SortByTitle),
+			domain.PostsQuery{After: 7, AfterKey: "title", Sort: domain.SortByTitle, Limit: defaultPageLimit},
+			false,
+		},
+		{"Wrong sort", "sort=author", domain.PostsQuery{}, true},
+		{"Wrong order", "order=up", domain.PostsQuery{}, true},
+		{"Cursor of another sort", "sort=created_at&cursor=" + encodeCursor(&domain.Post{ID: 7}, domain.SortByID), domain.PostsQuery{}, true},
+		{"Wrong limit", "limit=abc", domain.PostsQuery{}, true},
+		{"Negative limit", "limit=-1", domain.PostsQuery{}, true},
+		{"Too big limit", "limit=1000", domain.PostsQuery{}, true},
+		{"Wrong cursor", "cursor=abc", domain.PostsQuery{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+
+			q, err := mapPostsQuery(c)
+
+			if tt.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, httperr.HTTPStatusCode(err, -1))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedQuery, q)
+			}
+		})
+	}
+}
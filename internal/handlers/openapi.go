@@ -0,0 +1,552 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/openapi"
+)
+
+const (
+	apiTitle   = "Blog API"
+	apiVersion = "1.0"
+
+	bearerAuth    = "bearerAuth"
+	jsonMediaType = "application/json"
+)
+
+// OpenAPI returns the OpenAPI document of the routes RegisterHandlers binds. It's built once and should not be changed.
+var OpenAPI = sync.OnceValue(newOpenAPI)
+
+// RegisterDocs serves the OpenAPI document at /openapi.json and Swagger UI showing it at /docs
+func RegisterDocs(r *gin.Engine) {
+	spec, err := json.Marshal(OpenAPI())
+	if err != nil {
+		panic(fmt.Sprintf("can not render OpenAPI document. error: %v", err))
+	}
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, jsonMediaType, spec)
+	})
+	r.GET("/docs/*file", gin.WrapH(http.StripPrefix("/docs", openapi.DocsHandler(apiTitle, "/openapi.json"))))
+}
+
+// The security requirements of the operations. The readers are anonymous, a token shows them their own drafts.
+var (
+	authRequired = []openapi.SecurityRequirement{{bearerAuth: {}}}
+	authOptional = []openapi.SecurityRequirement{{}, {bearerAuth: {}}}
+)
+
+func newOpenAPI() *openapi.Document {
+	d := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       apiTitle,
+			Version:     apiVersion,
+			Description: "This is a Simple Blog Server. The errors are responded as RFC 7807 problem details.",
+		},
+		Tags: []openapi.Tag{
+			{Name: "posts", Description: "Posts, their revisions and tags"},
+			{Name: "comments", Description: "Threaded comments on posts"},
+			{Name: "users", Description: "Accounts and bearer tokens"},
+			{Name: "admin", Description: "Bulk import and export of posts, allowed to admins only"},
+		},
+		Components: openapi.Components{
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Token returned on login"},
+			},
+		},
+	}
+
+	addSchemas(d)
+	addProblems(d)
+	addPostOperations(d)
+	addCommentOperations(d)
+	addUserOperations(d)
+	addAdminOperations(d)
+	return d
+}
+
+var postStatuses = []any{domain.StatusDraft, domain.StatusPublished, domain.StatusScheduled, domain.StatusArchived}
+
+func addSchemas(d *openapi.Document) {
+	d.Define(domain.PostId(0), postId())
+	post := d.Component(domain.Post{})
+	post.Required = []string{"ID", "Title", "Content", "AuthorID", "Author", "Tags", "Status", "PublishAt", "CreatedAt", "UpdatedAt", "Version"}
+	post.Properties["Status"].Enum = postStatuses
+	post.Properties["Author"].Description = "Display name of the author"
+	post.Properties["PublishAt"].Description = "Time a scheduled post is published at or a published post was published at, zero for drafts"
+	post.Properties["Version"].Description = "Version of the post, it's returned as ETag"
+
+	rendered := d.Component(renderedPost{})
+	rendered.Required = append(slices.Clone(post.Required), "HTML", "TOC")
+	rendered.Properties["Status"].Enum = postStatuses
+	rendered.Properties["HTML"].Description = "Content rendered to sanitized HTML"
+	rendered.Properties["TOC"].Description = "Table of contents of the headings of the content"
+
+	dto := d.Component(PostDTO{})
+	dto.Properties["status"].Enum = postStatuses
+	dto.Properties["status"].Description = "A new post is published and a changed post keeps its status if it's omitted"
+	dto.Properties["publish_at"].Description = "Time a scheduled post is published at"
+	dto.Properties["tags"].Description = "Tags are lower-cased and sorted, a post has every tag once"
+
+	d.Component(CommentDTO{}).Properties["parent_id"].Description = "Comment to reply to, it's omitted for a top-level comment"
+	d.SchemaOf(CommentUpdateDTO{})
+	d.Component(SignupDTO{}).Properties["display_name"].Description = "Display name, the username by default"
+	d.SchemaOf(LoginDTO{})
+	d.SchemaOf(ProfileDTO{})
+
+	// The requests with unknown fields are rejected, so a misspelled field is not silently ignored
+	for _, request := range []any{PostDTO{}, CommentDTO{}, CommentUpdateDTO{}, SignupDTO{}, LoginDTO{}, ProfileDTO{}} {
+		d.Component(request).AdditionalProperties = ptr(false)
+	}
+	d.SchemaOf(httperr.FieldError{})
+
+	schemas := d.Components.Schemas
+	schemas["PostId"] = object(props{"postId": postId()}, "postId")
+	schemas["CommentId"] = object(props{"commentId": integer()}, "commentId")
+	schemas["UserId"] = object(props{"userId": integer()}, "userId")
+	schemas["Token"] = object(props{"token": str(), "expires_at": dateTime()}, "token", "expires_at")
+	schemas["User"] = object(props{
+		"id":           integer(),
+		"username":     str(),
+		"display_name": str(),
+		"bio":          str(),
+		"created_at":   dateTime(),
+	}, "id", "username", "display_name", "bio", "created_at")
+	schemas["PostsPage"] = object(props{
+		"posts": withDescription(arrayOf(openapi.Ref("Post")), "Posts of the page, they have HTML and TOC with render=html"),
+		"next_cursor": withDescription(str(),
+			"Cursor of the next page, it's empty on the last page"),
+	}, "posts", "next_cursor")
+	schemas["SearchResults"] = object(props{
+		"results": arrayOf(object(props{
+			"post":    openapi.Ref("Post"),
+			"score":   {Type: openapi.Types{"number"}},
+			"snippet": withDescription(str(), "HTML fragment of the content with the matching words highlighted"),
+		}, "post", "score", "snippet")),
+	}, "results")
+	schemas["Tags"] = object(props{
+		"tags": arrayOf(object(props{"tag": str(), "posts": integer()}, "tag", "posts")),
+	}, "tags")
+
+	revision := props{"number": integer(), "author": str(), "created_at": dateTime()}
+	schemas["Revisions"] = object(props{
+		"revisions": arrayOf(object(revision, "number", "author", "created_at")),
+	}, "revisions")
+	revision["title"] = str()
+	revision["content"] = str()
+	revision["diff"] = withDescription(str(), "Line diff of the content against the previous revision")
+	schemas["Revision"] = object(revision, "number", "author", "created_at", "title", "content", "diff")
+
+	schemas["CommentThread"] = object(props{
+		"id":         integer(),
+		"parent_id":  withDescription(integer(), "Comment this one replies to, zero for a top-level comment"),
+		"author":     str(),
+		"content":    str(),
+		"created_at": dateTime(),
+		"updated_at": dateTime(),
+		"replies":    arrayOf(openapi.Ref("CommentThread")),
+	}, "id", "parent_id", "author", "content", "created_at", "updated_at", "replies")
+	schemas["Comments"] = object(props{"comments": arrayOf(openapi.Ref("CommentThread"))}, "comments")
+	schemas["ImportResult"] = object(props{"imported": integer()}, "imported")
+}
+
+// problemResponses are the names of the component responses of the problems by status
+var problemResponses = map[int]string{
+	http.StatusBadRequest:          "BadRequest",
+	http.StatusUnauthorized:        "Unauthorized",
+	http.StatusForbidden:           "Forbidden",
+	http.StatusNotFound:            "NotFound",
+	http.StatusConflict:            "Conflict",
+	http.StatusPreconditionFailed:  "PreconditionFailed",
+	http.StatusInternalServerError: "InternalError",
+}
+
+// problemCodes are the codes of the problems, see httperr
+var problemCodes = []any{
+	httperr.CodeBadRequest, httperr.CodeValidationFailed, httperr.CodeMalformedBody,
+	httperr.CodeUnauthorized, httperr.CodeInvalidToken, httperr.CodeForbidden, httperr.CodeNotFound,
+	httperr.CodeNotAcceptable, httperr.CodeConflict, httperr.CodePostExists, httperr.CodeUsernameTaken,
+	httperr.CodePreconditionFailed, httperr.CodeVersionMismatch, httperr.CodeUnprocessable, httperr.CodeImportFailed,
+	httperr.CodeTooManyRequests, httperr.CodeInternal, httperr.CodeUnavailable,
+}
+
+func addProblems(d *openapi.Document) {
+	d.Components.Schemas["Problem"] = object(props{
+		"type":     withDescription(&openapi.Schema{Type: openapi.Types{"string"}, Format: "uri"}, "URI naming the kind of the problem, it ends with the code"),
+		"title":    str(),
+		"status":   integer(),
+		"detail":   withDescription(str(), "Explanation of the error, it's omitted for the server errors"),
+		"instance": withDescription(str(), "Path of the request"),
+		"code":     withDescription(&openapi.Schema{Type: openapi.Types{"string"}, Enum: problemCodes}, "Code of the error, clients match the errors on it"),
+		"errors":   withDescription(arrayOf(openapi.Ref("FieldError")), "Invalid fields of the request"),
+	}, "type", "title", "status", "code")
+	d.Components.Schemas["ImportProblem"] = &openapi.Schema{
+		AllOf: []*openapi.Schema{openapi.Ref("Problem"), object(props{
+			"rows": arrayOf(object(props{
+				"row":   withDescription(integer(), "Row of the import counted from 1, the CSV header is row 1"),
+				"error": str(),
+			}, "row", "error")),
+			"truncated": withDescription(&openapi.Schema{Type: openapi.Types{"boolean"}}, "Whether there are more invalid rows than listed"),
+		}, "rows", "truncated")},
+	}
+
+	d.Components.Responses = map[string]*openapi.Response{}
+	for status, name := range problemResponses {
+		d.Components.Responses[name] = problemResponse(http.StatusText(status), openapi.Ref("Problem"))
+	}
+}
+
+func problemResponse(description string, schema *openapi.Schema) *openapi.Response {
+	return &openapi.Response{
+		Description: description,
+		Content:     map[string]*openapi.MediaType{httperr.ContentType: {Schema: schema}},
+	}
+}
+
+func addPostOperations(d *openapi.Document) {
+	postID := pathParam("id", "Id of the post")
+	render := queryParam("render", "Renders the content to HTML", enum("html"))
+	limit := queryParam("limit", "Number of the posts to return", &openapi.Schema{
+		Type: openapi.Types{"integer"}, Minimum: ptr(1), Maximum: ptr(maxPageLimit),
+	})
+	ifMatch := &openapi.Parameter{
+		Name: "If-Match", In: "header", Schema: str(),
+		Description: "ETag of the post the change is based on, the change is rejected with 412 if the post is changed since",
+	}
+	postETag := map[string]*openapi.Header{"ETag": {Description: "Version of the post", Schema: str()}}
+
+	d.AddOperation(http.MethodGet, "/v1/posts", &openapi.Operation{
+		OperationID: "listPosts",
+		Summary:     "List posts",
+		Description: "Returns a page of published posts, the authors see their own posts in any status and the admins see all the posts. " +
+			"The next page is requested with the cursor of the response and the same filter and order.",
+		Tags: []string{"posts"},
+		Parameters: []*openapi.Parameter{
+			limit,
+			queryParam("tag", "Tags the posts should have, it's repeated for several tags", arrayOf(str())),
+			queryParam("match", "Whether the posts should have all the tags or any of them", withDefault(enum("all", "any"), "all")),
+			queryParam("sort", "Order of the posts, they are ordered by id without it", enum("created_at", "updated_at", "title")),
+			queryParam("order", "Direction of the order", withDefault(enum("asc", "desc"), "asc")),
+			queryParam("cursor", "Cursor of the page returned as next_cursor", str()),
+			render,
+		},
+		Responses: responses(jsonResponse("Page of posts", openapi.Ref("PostsPage")), http.StatusBadRequest),
+		Security:  authOptional,
+	})
+	d.AddOperation(http.MethodPost, "/v1/posts", &openapi.Operation{
+		OperationID: "createPost",
+		Summary:     "Create a post",
+		Description: "Creates the post written by the authenticated user",
+		Tags:        []string{"posts"},
+		RequestBody: jsonBody(openapi.Ref("PostDTO")),
+		Responses: withStatus(http.StatusCreated, withHeaders(jsonResponse("Id of the created post", openapi.Ref("PostId")), postETag),
+			http.StatusBadRequest, http.StatusUnauthorized),
+		Security: authRequired,
+	})
+	d.AddOperation(http.MethodGet, "/v1/posts/search", &openapi.Operation{
+		OperationID: "searchPosts",
+		Summary:     "Search posts",
+		Description: "Returns published posts matching the query ordered by relevance",
+		Tags:        []string{"posts"},
+		Parameters:  []*openapi.Parameter{required(queryParam("q", "Words to search for", str())), limit},
+		Responses:   responses(jsonResponse("Matching posts", openapi.Ref("SearchResults")), http.StatusBadRequest),
+	})
+
+	getETag := map[string]*openapi.Header{"ETag": {Description: `Version of the post, it's suffixed with "-html" with render=html`, Schema: str()}}
+	notModified := &openapi.Response{Description: "The post has the version of If-None-Match", Headers: getETag}
+	get := responses(withHeaders(jsonResponse("Post, it has HTML and TOC with render=html", openapi.Ref("Post")), getETag),
+		http.StatusBadRequest, http.StatusNotFound)
+	get[strconv.Itoa(http.StatusNotModified)] = notModified
+	d.AddOperation(http.MethodGet, "/v1/posts/{id}", &openapi.Operation{
+		OperationID: "getPost",
+		Summary:     "Get a post",
+		Tags:        []string{"posts"},
+		Parameters: []*openapi.Parameter{postID, render, {
+			Name: "If-None-Match", In: "header", Schema: str(),
+			Description: "ETags of the post versions the client has, 304 is responded if the post has one of them in the same representation",
+		}},
+		Responses: get,
+		Security:  authOptional,
+	})
+	d.AddOperation(http.MethodPut, "/v1/posts/{id}", &openapi.Operation{
+		OperationID: "updatePost",
+		Summary:     "Update a post",
+		Description: "Changes the post, it's allowed to its author and the admins",
+		Tags:        []string{"posts"},
+		Parameters:  []*openapi.Parameter{postID, ifMatch},
+		RequestBody: jsonBody(openapi.Ref("PostDTO")),
+		Responses: responses(withHeaders(jsonResponse("Id of the post", openapi.Ref("PostId")), postETag),
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed),
+		Security: authRequired,
+	})
+	d.AddOperation(http.MethodDelete, "/v1/posts/{id}", &openapi.Operation{
+		OperationID: "deletePost",
+		Summary:     "Delete a post",
+		Description: "Deletes the post, it's allowed to its author and the admins",
+		Tags:        []string{"posts"},
+		Parameters:  []*openapi.Parameter{postID, ifMatch},
+		Responses: withStatus(http.StatusNoContent, &openapi.Response{Description: "The post is deleted"},
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed),
+		Security: authRequired,
+	})
+
+	d.AddOperation(http.MethodGet, "/v1/tags", &openapi.Operation{
+		OperationID: "listTags",
+		Summary:     "List tags",
+		Description: "Returns all the tags with the number of the published posts having them",
+		Tags:        []string{"posts"},
+		Responses:   responses(jsonResponse("Tags", openapi.Ref("Tags"))),
+	})
+
+	revisionNumber := pathParam("rev", "Number of the revision, it's the version of the post the revision made")
+	d.AddOperation(http.MethodGet, "/v1/posts/{id}/revisions", &openapi.Operation{
+		OperationID: "listRevisions",
+		Summary:     "List revisions of a post",
+		Description: "Returns the revisions of the post without their content",
+		Tags:        []string{"posts"},
+		Parameters:  []*openapi.Parameter{postID},
+		Responses:   responses(jsonResponse("Revisions", openapi.Ref("Revisions")), http.StatusBadRequest, http.StatusNotFound),
+		Security:    authOptional,
+	})
+	d.AddOperation(http.MethodGet, "/v1/posts/{id}/revisions/{rev}", &openapi.Operation{
+		OperationID: "getRevision",
+		Summary:     "Get a revision of a post",
+		Tags:        []string{"posts"},
+		Parameters:  []*openapi.Parameter{postID, revisionNumber},
+		Responses:   responses(jsonResponse("Revision", openapi.Ref("Revision")), http.StatusBadRequest, http.StatusNotFound),
+		Security:    authOptional,
+	})
+	d.AddOperation(http.MethodPost, "/v1/posts/{id}/revisions/{rev}/restore", &openapi.Operation{
+		OperationID: "restoreRevision",
+		Summary:     "Restore a revision of a post",
+		Description: "Makes the content of the revision the current content of the post",
+		Tags:        []string{"posts"},
+		Parameters:  []*openapi.Parameter{postID, revisionNumber},
+		Responses: responses(withHeaders(jsonResponse("Id of the post", openapi.Ref("PostId")), postETag),
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+		Security: authRequired,
+	})
+}
+
+func addCommentOperations(d *openapi.Document) {
+	postID := pathParam("id", "Id of the post")
+	commentID := pathParam("commentId", "Id of the comment")
+
+	d.AddOperation(http.MethodGet, "/v1/posts/{id}/comments", &openapi.Operation{
+		OperationID: "listComments",
+		Summary:     "List comments of a post",
+		Description: "Returns the comments as a tree, the replies are nested into the comments they answer",
+		Tags:        []string{"comments"},
+		Parameters:  []*openapi.Parameter{postID},
+		Responses:   responses(jsonResponse("Comments", openapi.Ref("Comments")), http.StatusBadRequest, http.StatusNotFound),
+	})
+	d.AddOperation(http.MethodPost, "/v1/posts/{id}/comments", &openapi.Operation{
+		OperationID: "createComment",
+		Summary:     "Comment a post",
+		Description: "Creates the comment written by the authenticated user",
+		Tags:        []string{"comments"},
+		Parameters:  []*openapi.Parameter{postID},
+		RequestBody: jsonBody(openapi.Ref("CommentDTO")),
+		Responses: withStatus(http.StatusCreated, jsonResponse("Id of the created comment", openapi.Ref("CommentId")),
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound),
+		Security: authRequired,
+	})
+	d.AddOperation(http.MethodPut, "/v1/posts/{id}/comments/{commentId}", &openapi.Operation{
+		OperationID: "updateComment",
+		Summary:     "Update a comment",
+		Description: "Changes the content of the comment, it's allowed to its author and the admins",
+		Tags:        []string{"comments"},
+		Parameters:  []*openapi.Parameter{postID, commentID},
+		RequestBody: jsonBody(openapi.Ref("CommentUpdateDTO")),
+		Responses: responses(jsonResponse("Id of the comment", openapi.Ref("CommentId")),
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+		Security: authRequired,
+	})
+	d.AddOperation(http.MethodDelete, "/v1/posts/{id}/comments/{commentId}", &openapi.Operation{
+		OperationID: "deleteComment",
+		Summary:     "Delete a comment",
+		Description: "Deletes the comment with all the replies to it, it's allowed to its author and the admins",
+		Tags:        []string{"comments"},
+		Parameters:  []*openapi.Parameter{postID, commentID},
+		Responses: withStatus(http.StatusNoContent, &openapi.Response{Description: "The comment is deleted"},
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden),
+		Security: authRequired,
+	})
+}
+
+func addUserOperations(d *openapi.Document) {
+	d.AddOperation(http.MethodPost, "/v1/users", &openapi.Operation{
+		OperationID: "signup",
+		Summary:     "Sign up",
+		Description: "Registers a new user, the user logs in to get a token",
+		Tags:        []string{"users"},
+		RequestBody: jsonBody(openapi.Ref("SignupDTO")),
+		Responses: withStatus(http.StatusCreated, jsonResponse("Id of the user", openapi.Ref("UserId")),
+			http.StatusBadRequest, http.StatusConflict),
+	})
+	d.AddOperation(http.MethodPost, "/v1/users/login", &openapi.Operation{
+		OperationID: "login",
+		Summary:     "Log in",
+		Description: "Returns a bearer token of the user the changes are made with",
+		Tags:        []string{"users"},
+		RequestBody: jsonBody(openapi.Ref("LoginDTO")),
+		Responses:   responses(jsonResponse("Token", openapi.Ref("Token")), http.StatusBadRequest, http.StatusUnauthorized),
+	})
+	d.AddOperation(http.MethodGet, "/v1/users/me", &openapi.Operation{
+		OperationID: "currentUser",
+		Summary:     "Get the current user",
+		Description: "Returns the profile of the authenticated user",
+		Tags:        []string{"users"},
+		Responses:   responses(jsonResponse("User", openapi.Ref("User")), http.StatusUnauthorized),
+		Security:    authRequired,
+	})
+	d.AddOperation(http.MethodPut, "/v1/users/me", &openapi.Operation{
+		OperationID: "updateProfile",
+		Summary:     "Update the profile",
+		Description: "Changes the profile of the authenticated user",
+		Tags:        []string{"users"},
+		RequestBody: jsonBody(openapi.Ref("ProfileDTO")),
+		Responses:   responses(jsonResponse("User", openapi.Ref("User")), http.StatusBadRequest, http.StatusUnauthorized),
+		Security:    authRequired,
+	})
+	d.AddOperation(http.MethodGet, "/v1/users/{id}", &openapi.Operation{
+		OperationID: "getUser",
+		Summary:     "Get a user",
+		Tags:        []string{"users"},
+		Parameters:  []*openapi.Parameter{pathParam("id", "Id of the user")},
+		Responses:   responses(jsonResponse("User", openapi.Ref("User")), http.StatusBadRequest, http.StatusNotFound),
+	})
+}
+
+func addAdminOperations(d *openapi.Document) {
+	format := queryParam("format", "Format of the posts, an import sent as text/csv is CSV without it",
+		withDefault(enum("jsonl", "csv"), "jsonl"))
+	postLines := &openapi.Schema{Type: openapi.Types{"string"}, Description: "A post per line"}
+	bulkContent := map[string]*openapi.MediaType{
+		"application/x-ndjson": {Schema: postLines},
+		"text/csv":             {Schema: postLines},
+	}
+
+	d.AddOperation(http.MethodPost, "/v1/admin/import", &openapi.Operation{
+		OperationID: "importPosts",
+		Summary:     "Import posts",
+		Description: "Creates the posts sent in JSON Lines or CSV, all of them or none",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{format},
+		RequestBody: &openapi.RequestBody{Required: true, Content: bulkContent},
+		Responses: func() map[string]*openapi.Response {
+			r := responses(jsonResponse("Number of the imported posts", openapi.Ref("ImportResult")),
+				http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)
+			r[strconv.Itoa(http.StatusUnprocessableEntity)] = problemResponse("Invalid rows of the import", openapi.Ref("ImportProblem"))
+			return r
+		}(),
+		Security: authRequired,
+	})
+	d.AddOperation(http.MethodGet, "/v1/admin/export", &openapi.Operation{
+		OperationID: "exportPosts",
+		Summary:     "Export posts",
+		Description: "Streams all the posts ordered by id",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{format},
+		Responses: responses(&openapi.Response{Description: "Posts", Content: bulkContent},
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden),
+		Security: authRequired,
+	})
+}
+
+type props = map[string]*openapi.Schema
+
+func object(properties props, required ...string) *openapi.Schema {
+	return &openapi.Schema{Type: openapi.Types{"object"}, Properties: properties, Required: required}
+}
+
+func arrayOf(items *openapi.Schema) *openapi.Schema {
+	return &openapi.Schema{Type: openapi.Types{"array"}, Items: items}
+}
+
+func str() *openapi.Schema {
+	return &openapi.Schema{Type: openapi.Types{"string"}}
+}
+
+func integer() *openapi.Schema {
+	return &openapi.Schema{Type: openapi.Types{"integer"}}
+}
+
+// postId is the schema of domain.PostId, the ids greater than 2^53 are strings
+func postId() *openapi.Schema {
+	return &openapi.Schema{
+		Type:        openapi.Types{"integer", "string"},
+		Description: "Id of the post. The ids greater than 2^53 are strings of digits, so the clients parsing numbers as float64 keep them",
+	}
+}
+
+func dateTime() *openapi.Schema {
+	return &openapi.Schema{Type: openapi.Types{"string"}, Format: "date-time"}
+}
+
+func enum(values ...any) *openapi.Schema {
+	return &openapi.Schema{Type: openapi.Types{"string"}, Enum: values}
+}
+
+func withDescription(s *openapi.Schema, description string) *openapi.Schema {
+	s.Description = description
+	return s
+}
+
+func withDefault(s *openapi.Schema, value string) *openapi.Schema {
+	s.Description = "It's " + value + " by default"
+	return s
+}
+
+func pathParam(name, description string) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "path", Required: true, Description: description, Schema: integer()}
+}
+
+func queryParam(name, description string, schema *openapi.Schema) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func required(p *openapi.Parameter) *openapi.Parameter {
+	p.Required = true
+	return p
+}
+
+func jsonBody(schema *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{jsonMediaType: {Schema: schema}}}
+}
+
+func jsonResponse(description string, schema *openapi.Schema) *openapi.Response {
+	return &openapi.Response{Description: description, Content: map[string]*openapi.MediaType{jsonMediaType: {Schema: schema}}}
+}
+
+func withHeaders(r *openapi.Response, headers map[string]*openapi.Header) *openapi.Response {
+	r.Headers = headers
+	return r
+}
+
+// responses returns the responses of 200 and the problems of the statuses.
+// Any operation may fail with 500 and with 401 if the request has an invalid token.
+func responses(ok *openapi.Response, problems ...int) map[string]*openapi.Response {
+	return withStatus(http.StatusOK, ok, problems...)
+}
+
+func withStatus(status int, ok *openapi.Response, problems ...int) map[string]*openapi.Response {
+	r := map[string]*openapi.Response{strconv.Itoa(status): ok}
+	for _, status := range append(problems, http.StatusUnauthorized, http.StatusInternalServerError) {
+		r[strconv.Itoa(status)] = openapi.ResponseRef(problemResponses[status])
+	}
+	return r
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
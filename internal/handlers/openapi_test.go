@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/openapi"
+	"github.com/voltento/go-blog-project/mocks"
+)
+
+var (
+	routeParam    = regexp.MustCompile(`[:*](\w+)`)
+	templateParam = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+// specRoutes returns the operations of the document as "METHOD /path/{param}"
+func specRoutes(d *openapi.Document) []string {
+	var routes []string
+	for path, item := range d.Paths {
+		for method := range item {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+// TestOpenAPI_Routes fails when a route is added, changed or removed without changing the document
+func TestOpenAPI_Routes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterHandlers(r, new(mocks.BlogService), new(mocks.TokenIssuer))
+
+	var routes []string
+	for _, route := range r.Routes() {
+		routes = append(routes, route.Method+" "+routeParam.ReplaceAllString(route.Path, "{$1}"))
+	}
+	slices.Sort(routes)
+
+	d := OpenAPI()
+	assert.Equal(t, routes, specRoutes(d), "the routes and the OpenAPI document should have the same operations")
+
+	for path, item := range d.Paths {
+		var want []string
+		for _, m := range templateParam.FindAllStringSubmatch(path, -1) {
+			want = append(want, m[1])
+		}
+
+		for method, op := range item {
+			var params []string
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					assert.True(t, p.Required, "%s %s: path parameter %s should be required", method, path, p.Name)
+					params = append(params, p.Name)
+				}
+			}
+			assert.ElementsMatch(t, want, params, "%s %s should describe the parameters of the path", method, path)
+			assert.NotEmpty(t, op.Responses, "%s %s should have responses", method, path)
+		}
+	}
+}
+
+func TestOpenAPI_References(t *testing.T) {
+	d := OpenAPI()
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "3.1.0", doc["openapi"])
+
+	var refs []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				refs = append(refs, ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+	require.NotEmpty(t, refs)
+
+	components := doc["components"].(map[string]any)
+	for _, ref := range refs {
+		kind, name, found := strings.Cut(strings.TrimPrefix(ref, "#/components/"), "/")
+		require.True(t, found, ref)
+		section, _ := components[kind].(map[string]any)
+		assert.Contains(t, section, name, "%s should be defined", ref)
+	}
+}
+
+func TestOpenAPI_PostDTO(t *testing.T) {
+	dto := OpenAPI().Components.Schemas["PostDTO"]
+	require.NotNil(t, dto)
+	assert.ElementsMatch(t, []string{"title", "content"}, dto.Required)
+	assert.Equal(t, []any{domain.StatusDraft, domain.StatusPublished, domain.StatusScheduled, domain.StatusArchived}, dto.Properties["status"].Enum)
+	assert.Equal(t, "date-time", dto.Properties["publish_at"].Format)
+	assert.Equal(t, openapi.Types{"array", "null"}, dto.Properties["tags"].Type)
+}
+
+func TestRegisterDocs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterDocs(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	e := httpexpect.Default(t, server.URL)
+
+	spec := e.GET("/openapi.json").Expect().Status(http.StatusOK).JSON().Object()
+	spec.Value("openapi").IsEqual("3.1.0")
+	spec.Value("info").Object().Value("title").IsEqual("Blog API")
+	spec.Value("paths").Object().ContainsKey("/v1/posts/{id}")
+	spec.Path("$.components.responses.NotFound.content").Object().ContainsKey("application/problem+json")
+
+	page := e.GET("/docs/").Expect().Status(http.StatusOK)
+	page.Header("Content-Type").HasPrefix("text/html")
+	page.Body().Contains("swagger-ui-bundle.js").Contains(`url: "/openapi.json"`)
+
+	e.GET("/docs/swagger-ui-bundle.js").Expect().Status(http.StatusOK).Body().NotEmpty()
+	e.GET("/docs/swagger-ui.css").Expect().Status(http.StatusOK)
+}
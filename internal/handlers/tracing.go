@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracingScope is the instrumentation scope of the handler spans
+const tracingScope = "github.com/voltento/go-blog-project/internal/handlers"
+
+// traced handles the request in the span of the handler, the errors the handler adds are recorded to the span
+func traced(name string, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := otel.Tracer(tracingScope).Start(c.Request.Context(), "handlers."+name)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		errs := len(c.Errors)
+		h(c)
+
+		for _, err := range c.Errors[errs:] {
+			span.RecordError(err.Err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
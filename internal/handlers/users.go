@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Signup registers a new user, the user logs in to get a token
+func (s *server) Signup(c *gin.Context) {
+	user, password, err := mapToSignup(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	id, err := s.service.Signup(c.Request.Context(), user, password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, userIdResp(id))
+}
+
+// Login returns a bearer token of the user the changes are made with
+func (s *server) Login(c *gin.Context) {
+	login, err := mapToLogin(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := s.service.Login(c.Request.Context(), login.Username, login.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, expiresAt, err := s.tokens.IssueToken(strconv.Itoa(int(user.ID)))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResp(token, expiresAt))
+}
+
+func (s *server) User(c *gin.Context) {
+	id, err := mapUserId(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := s.service.User(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResp(user))
+}
+
+// CurrentUser returns the profile of the authenticated user
+func (s *server) CurrentUser(c *gin.Context) {
+	user, err := s.service.CurrentUser(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResp(user))
+}
+
+func (s *server) UpdateProfile(c *gin.Context) {
+	profile, err := mapToProfile(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := s.service.UpdateProfile(c.Request.Context(), profile)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResp(user))
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/middlewares"
+	"github.com/voltento/go-blog-project/mocks"
+	"golang.org/x/exp/slog"
+)
+
+// newValidatingServer serves the handlers with the requests and the responses checked against the OpenAPI document.
+// The returned buffer has the log lines written as JSON till the test ends.
+func newValidatingServer(t *testing.T, service *mocks.BlogService) (*httpexpect.Expect, *bytes.Buffer) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ResponseValidationMiddleware(OpenAPI()))
+	middlewares.Setup(r)
+	r.Use(middlewares.RequestValidationMiddleware(OpenAPI()))
+	RegisterHandlers(r, service, new(mocks.TokenIssuer))
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return httpexpect.Default(t, server.URL), &logs
+}
+
+func TestValidation_UnknownField(t *testing.T) {
+	service := new(mocks.BlogService)
+	e, _ := newValidatingServer(t, service)
+
+	problem := e.POST("/v1/posts").
+		WithJSON(map[string]string{"title": "New Post", "content": "New Content", "author": "New Author"}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object()
+	problem.Value("code").IsEqual("validation_failed")
+	problem.Value("errors").IsEqual([]map[string]string{{"field": "author", "message": "is not a known field"}})
+
+	service.AssertExpectations(t)
+}
+
+func TestValidation_Lengths(t *testing.T) {
+	service := new(mocks.BlogService)
+	e, _ := newValidatingServer(t, service)
+
+	problem := e.PUT("/v1/posts/1").
+		WithJSON(map[string]string{"title": strings.Repeat("t", 201), "content": strings.Repeat("c", 100001)}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object()
+	problem.Value("errors").IsEqual([]map[string]string{
+		{"field": "content", "message": "should be at most 100000 characters long"},
+		{"field": "title", "message": "should be at most 200 characters long"},
+	})
+
+	service.AssertExpectations(t)
+}
+
+func TestValidation_Formats(t *testing.T) {
+	service := new(mocks.BlogService)
+	e, _ := newValidatingServer(t, service)
+
+	problem := e.POST("/v1/posts").
+		WithJSON(map[string]any{"title": "New Post", "content": "New Content", "status": "hidden", "publish_at": "tomorrow", "tags": []any{"go", 1}}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object()
+	problem.Value("errors").IsEqual([]map[string]string{
+		{"field": "publish_at", "message": "should be a time in RFC 3339 format"},
+		{"field": "status", "message": "should be one of draft, published, scheduled or archived, got 'hidden'"},
+		{"field": "tags[1]", "message": "should be a string"},
+	})
+
+	e.POST("/v1/posts").WithText("title").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object().Value("code").IsEqual("malformed_body")
+
+	service.AssertExpectations(t)
+}
+
+func TestValidation_Parameters(t *testing.T) {
+	service := new(mocks.BlogService)
+	e, _ := newValidatingServer(t, service)
+
+	e.GET("/v1/posts/abc").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object().
+		Value("errors").IsEqual([]map[string]string{{"field": "id", "message": "should be an integer, got 'abc'"}})
+
+	e.GET("/v1/posts").WithQuery("limit", 500).WithQuery("order", "up").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON(problemJSON).Object().
+		Value("errors").IsEqual([]map[string]string{
+		{"field": "limit", "message": "should be at most 100, got '500'"},
+		{"field": "order", "message": "should be one of asc or desc, got 'up'"},
+	})
+
+	service.AssertExpectations(t)
+}
+
+func TestValidation_ValidRequests(t *testing.T) {
+	service := new(mocks.BlogService)
+	e, logs := newValidatingServer(t, service)
+
+	service.On("CreatePost", mock.Anything, mock.Anything).Return(domain.PostId(1), nil)
+	service.On("Post", mock.Anything, domain.PostId(1)).Return(&domain.Post{
+		ID: 1, Title: "New Post", Content: "New Content", Tags: []string{"go"}, Status: domain.StatusScheduled,
+		PublishAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Version: 1,
+	}, nil)
+
+	e.POST("/v1/posts").
+		WithJSON(map[string]any{"title": "New Post", "content": "New Content", "tags": []string{"go"}, "status": "scheduled", "publish_at": "2024-05-01T10:00:00Z"}).
+		Expect().
+		Status(http.StatusCreated)
+	e.GET("/v1/posts/1").Expect().Status(http.StatusOK)
+	e.GET("/v1/posts/1").WithHeader("If-None-Match", `"1"`).Expect().Status(http.StatusNotModified)
+
+	service.AssertExpectations(t)
+	assert.NotContains(t, logs.String(), "response does not match", "the responses should match the document")
+}
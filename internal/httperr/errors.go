@@ -1,16 +1,70 @@
 package httperr
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
 type httpError struct {
 	error
 	statusCode int
+	// code is the machine-readable code of the error, it's derived from the status code if it's empty
+	code string
+	// fields are the invalid fields of the request
+	fields []FieldError
+	// extensions are the members added to the problem details of the error
+	extensions map[string]any
+}
+
+// Unwrap returns the wrapped error, so it's seen by errors.Is and errors.As
+func (e *httpError) Unwrap() error {
+	return e.error
 }
 
 func WrapWithHttpCode(err error, code int) error {
 	return &httpError{error: err, statusCode: code}
 }
 
+// WrapWithCode is WrapWithHttpCode with the machine-readable code clients match the error on
+func WrapWithCode(err error, status int, code string) error {
+	return &httpError{error: err, statusCode: status, code: code}
+}
+
+// FieldError is a field of the request which failed the validation
+type FieldError struct {
+	// Field is the name of the field as it's sent, e.g. the JSON name or the query parameter
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	return e.Field + " " + e.Message
+}
+
+// Invalid returns the error of a request with the invalid fields, it's responded with 400
+func Invalid(fields ...FieldError) error {
+	msgs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		msgs = append(msgs, f.String())
+	}
+
+	err := fmt.Errorf("request is invalid: %s", strings.Join(msgs, ", "))
+	return &httpError{error: err, statusCode: http.StatusBadRequest, code: CodeValidationFailed, fields: fields}
+}
+
+// WithExtensions adds the members to the problem details of the error. The status and the code of err are kept.
+func WithExtensions(err error, extensions map[string]any) error {
+	e := &httpError{error: err, statusCode: HTTPStatusCode(err, http.StatusInternalServerError), extensions: extensions}
+	var inner *httpError
+	if errors.As(err, &inner) {
+		e.code = inner.code
+		e.fields = inner.fields
+	}
+	return e
+}
+
 // HTTPStatusCode returns http status code from error or default error if not provided
 func HTTPStatusCode(err error, defaultError int) int {
 	var errorWithCode *httpError
@@ -20,3 +74,13 @@ func HTTPStatusCode(err error, defaultError int) int {
 
 	return defaultError
 }
+
+// Code returns the machine-readable code of the error, it's derived from the status code if the error has no code
+func Code(err error) string {
+	var errorWithCode *httpError
+	if errors.As(err, &errorWithCode) && errorWithCode.code != "" {
+		return errorWithCode.code
+	}
+
+	return statusCodes[HTTPStatusCode(err, http.StatusInternalServerError)]
+}
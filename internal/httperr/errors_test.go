@@ -51,3 +51,31 @@ func TestHTTPStatusCodeUnknownErrorType(t *testing.T) {
 	result := HTTPStatusCode(originalError, defaultCode)
 	assert.Equal(t, defaultCode, result, "expected default status code when error is nil")
 }
+
+func TestWrapWithHttpCodeUnwraps(t *testing.T) {
+	wrappedError := WrapWithHttpCode(originalError, 400)
+
+	assert.ErrorIs(t, wrappedError, originalError, "expected wrapped error to be in the chain")
+}
+
+func TestCode(t *testing.T) {
+	assert.Equal(t, CodeNotFound, Code(WrapWithHttpCode(originalError, 404)), "expected code derived from the status code")
+	assert.Equal(t, CodeVersionMismatch, Code(WrapWithCode(originalError, 412, CodeVersionMismatch)))
+	assert.Equal(t, CodeInternal, Code(originalError), "expected internal code when error is not wrapped")
+}
+
+func TestInvalid(t *testing.T) {
+	err := Invalid(FieldError{Field: "title", Message: "is required"}, FieldError{Field: "limit", Message: "should be a number"})
+
+	assert.Equal(t, 400, HTTPStatusCode(err, 500))
+	assert.Equal(t, CodeValidationFailed, Code(err))
+	assert.EqualError(t, err, "request is invalid: title is required, limit should be a number")
+}
+
+func TestWithExtensions(t *testing.T) {
+	err := WithExtensions(WrapWithCode(originalError, 422, CodeImportFailed), map[string]any{"rows": 2})
+
+	assert.Equal(t, 422, HTTPStatusCode(err, 500), "expected status code of the wrapped error to be kept")
+	assert.Equal(t, CodeImportFailed, Code(err), "expected code of the wrapped error to be kept")
+	assert.ErrorIs(t, err, originalError)
+}
@@ -0,0 +1,120 @@
+package httperr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ContentType is the media type of the problem details
+const ContentType = "application/problem+json"
+
+// typePrefix starts the type URIs of the problems, the code of the error follows it
+const typePrefix = "urn:blog:problem:"
+
+// The codes of the errors. Clients match the errors on the codes, so the codes are never changed.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeMalformedBody      = "malformed_body"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidToken       = "invalid_token"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeNotAcceptable      = "not_acceptable"
+	CodeConflict           = "conflict"
+	CodePostExists         = "post_exists"
+	CodeUsernameTaken      = "username_taken"
+	CodePreconditionFailed = "precondition_failed"
+	CodeVersionMismatch    = "version_mismatch"
+	CodeUnprocessable      = "unprocessable"
+	CodeImportFailed       = "import_failed"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal"
+	CodeUnavailable        = "unavailable"
+)
+
+// statusCodes are the codes of the errors which have no own code
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusNotAcceptable:       CodeNotAcceptable,
+	http.StatusConflict:            CodeConflict,
+	http.StatusPreconditionFailed:  CodePreconditionFailed,
+	http.StatusUnprocessableEntity: CodeUnprocessable,
+	http.StatusTooManyRequests:     CodeTooManyRequests,
+	http.StatusInternalServerError: CodeInternal,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+}
+
+// Problem is the problem details of an error as RFC 7807 defines them
+type Problem struct {
+	// Type is the URI naming the kind of the problem, it ends with the code
+	Type   string
+	Title  string
+	Status int
+	// Detail explains the error. The details of the server errors are not shown, they are logged.
+	Detail string
+	// Instance is the path of the request
+	Instance string
+	Code     string
+	// Errors are the invalid fields of the request
+	Errors []FieldError
+	// Extensions are the members added by the error, e.g. the rows of a failed import
+	Extensions map[string]any
+}
+
+// NewProblem returns the problem details of the error of the request to the path
+func NewProblem(err error, instance string) *Problem {
+	status := HTTPStatusCode(err, http.StatusInternalServerError)
+	code := Code(err)
+	if code == "" {
+		code = CodeBadRequest
+		if status >= http.StatusInternalServerError {
+			code = CodeInternal
+		}
+	}
+
+	p := &Problem{
+		Type:     typePrefix + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: instance,
+		Code:     code,
+	}
+	if status < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+
+	var e *httpError
+	if errors.As(err, &e) {
+		p.Errors = e.fields
+		p.Extensions = e.extensions
+	}
+	return p
+}
+
+// MarshalJSON renders the extensions as the members of the problem
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+7)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	members["code"] = p.Code
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	if len(p.Errors) > 0 {
+		members["errors"] = p.Errors
+	}
+	return json.Marshal(members)
+}
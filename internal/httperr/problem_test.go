@@ -0,0 +1,48 @@
+package httperr
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProblem(t *testing.T) {
+	err := Invalid(FieldError{Field: "title", Message: "is required"})
+
+	p := NewProblem(err, "/v1/posts")
+	assert.Equal(t, &Problem{
+		Type:     "urn:blog:problem:validation_failed",
+		Title:    "Bad Request",
+		Status:   400,
+		Detail:   "request is invalid: title is required",
+		Instance: "/v1/posts",
+		Code:     CodeValidationFailed,
+		Errors:   []FieldError{{Field: "title", Message: "is required"}},
+	}, p)
+}
+
+func TestNewProblemHidesServerErrors(t *testing.T) {
+	p := NewProblem(errors.New("pq: connection refused"), "/v1/posts")
+
+	assert.Equal(t, 500, p.Status)
+	assert.Equal(t, CodeInternal, p.Code)
+	assert.Empty(t, p.Detail, "expected detail of a server error to be hidden")
+}
+
+func TestProblemMarshalJSON(t *testing.T) {
+	err := WithExtensions(WrapWithCode(errors.New("posts are not imported"), 422, CodeImportFailed), map[string]any{"truncated": true})
+
+	data, err := json.Marshal(NewProblem(err, ""))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "urn:blog:problem:import_failed",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"code": "import_failed",
+		"detail": "posts are not imported",
+		"truncated": true
+	}`, string(data))
+}
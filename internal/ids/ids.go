@@ -0,0 +1,141 @@
+// Package ids allocates post ids. The storages allocate sequential ids by default, the allocators of this package
+// replace the sequence with ids which are unique without coordination between the instances of the service.
+// Their ids are greater than 2^53, the greatest integer a float64 keeps exactly, so they are rendered to JSON
+// as strings, see domain.PostId.
+package ids
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+// Allocator hands out the ids of new posts. The storages retry an id which is taken already.
+type Allocator interface {
+	Next() domain.PostId
+}
+
+const (
+	// Sequential ids are allocated by the sequence of the storage which is kept with the posts
+	Sequential  = "sequential"
+	TimeOrdered = "time-ordered"
+	Snowflake   = "snowflake"
+)
+
+// New returns the allocator by its name. It's nil for Sequential, the storages use their own sequence then.
+// node is the number of the instance, it's used by Snowflake only.
+func New(kind string, node int64) (Allocator, error) {
+	switch kind {
+	case "", Sequential:
+		return nil, nil
+	case TimeOrdered:
+		return NewTimeOrdered(), nil
+	case Snowflake:
+		return NewSnowflake(node)
+	default:
+		return nil, fmt.Errorf("unknown id allocator '%s', it should be one of sequential, time-ordered or snowflake", kind)
+	}
+}
+
+// Epoch is the start of the time the Snowflake ids keep
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	nodeBits = 10
+	stepBits = 12
+	// MaxNode is the greatest node number of Snowflake
+	MaxNode = 1<<nodeBits - 1
+	maxStep = 1<<stepBits - 1
+)
+
+// SnowflakeAllocator allocates the ids of 41 bits of milliseconds since Epoch, 10 bits of the node number and
+// 12 bits of the sequence number within a millisecond. The ids of a node increase, the ids of different nodes
+// never clash. The ids are between 2^22 and 2^63 and exceed 2^53 about 25 days after Epoch.
+type SnowflakeAllocator struct {
+	mtx  sync.Mutex
+	node int64
+	now  func() time.Time
+	// lastMs and step are the time and the sequence number of the last id
+	lastMs int64
+	step   int64
+}
+
+func NewSnowflake(node int64) (*SnowflakeAllocator, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("snowflake node %d should be between 0 and %d", node, MaxNode)
+	}
+
+	return &SnowflakeAllocator{node: node, now: time.Now, lastMs: -1}, nil
+}
+
+func (a *SnowflakeAllocator) Next() domain.PostId {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	ms := a.now().Sub(Epoch).Milliseconds()
+	// The ids keep increasing if the clock goes back or the sequence of a millisecond is exhausted
+	if ms <= a.lastMs {
+		ms = a.lastMs
+		a.step++
+		if a.step > maxStep {
+			ms++
+			a.step = 0
+		}
+	} else {
+		a.step = 0
+	}
+	a.lastMs = ms
+
+	return domain.PostId(ms<<(nodeBits+stepBits) | a.node<<stepBits | a.step)
+}
+
+const (
+	counterBits = 15
+	maxCounter  = 1<<counterBits - 1
+)
+
+// TimeOrderedAllocator allocates the 63-bit ids of 48 bits of the Unix time in milliseconds followed by
+// a 15-bit counter. It's not a UUID, only the layout of the time and the counter is borrowed from UUIDv7 (RFC 9562).
+// The counter starts at a random value every millisecond and is incremented within it.
+// The ids are ordered by time and unlikely to clash between the instances. The ids of the current time
+// are about 2^55.6, they are greater than 2^53 since 1978.
+type TimeOrderedAllocator struct {
+	mtx sync.Mutex
+	now func() time.Time
+	// lastMs and counter are the time and the counter of the last id
+	lastMs  int64
+	counter int64
+}
+
+func NewTimeOrdered() *TimeOrderedAllocator {
+	return &TimeOrderedAllocator{now: time.Now, lastMs: -1}
+}
+
+func (a *TimeOrderedAllocator) Next() domain.PostId {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	ms := a.now().UnixMilli()
+	if ms <= a.lastMs {
+		ms = a.lastMs
+		a.counter++
+		if a.counter > maxCounter {
+			ms++
+			a.counter = randomCounter()
+		}
+	} else {
+		a.counter = randomCounter()
+	}
+	a.lastMs = ms
+
+	return domain.PostId(ms<<counterBits | a.counter)
+}
+
+// randomCounter returns the counter of a new millisecond. It's below the half of the range,
+// so the counter is not exhausted by the ids of a millisecond.
+func randomCounter() int64 {
+	return rand.Int64N(maxCounter / 2)
+}
@@ -0,0 +1,66 @@
+package ids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+func TestNew(t *testing.T) {
+	a, err := New(Sequential, 0)
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	a, err = New(TimeOrdered, 0)
+	require.NoError(t, err)
+	assert.IsType(t, &TimeOrderedAllocator{}, a)
+
+	a, err = New(Snowflake, 3)
+	require.NoError(t, err)
+	assert.IsType(t, &SnowflakeAllocator{}, a)
+
+	_, err = New("random", 0)
+	assert.Error(t, err)
+
+	_, err = New(Snowflake, MaxNode+1)
+	assert.Error(t, err)
+}
+
+func TestSnowflake(t *testing.T) {
+	now := Epoch.Add(5 * time.Millisecond)
+	a, err := NewSnowflake(3)
+	require.NoError(t, err)
+	a.now = func() time.Time { return now }
+
+	assert.Equal(t, domain.PostId(5<<22|3<<12), a.Next())
+	assert.Equal(t, domain.PostId(5<<22|3<<12|1), a.Next(), "ids of a millisecond should differ by the sequence")
+
+	now = now.Add(-time.Millisecond)
+	assert.Equal(t, domain.PostId(5<<22|3<<12|2), a.Next(), "ids should increase if the clock goes back")
+
+	a.step = maxStep
+	assert.Equal(t, domain.PostId(6<<22|3<<12), a.Next(), "exhausted sequence should move to the next millisecond")
+}
+
+func TestTimeOrdered(t *testing.T) {
+	now := time.UnixMilli(1714557600000)
+	a := NewTimeOrdered()
+	a.now = func() time.Time { return now }
+
+	first := a.Next()
+	assert.Equal(t, now.UnixMilli(), int64(first)>>counterBits, "id should start with the time")
+	assert.Less(t, int64(first)&maxCounter, int64(maxCounter/2))
+
+	prev := first
+	for i := 0; i < 3*maxCounter; i++ {
+		id := a.Next()
+		require.Greater(t, id, prev)
+		prev = id
+	}
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, now.UnixMilli(), int64(a.Next())>>counterBits)
+}
@@ -0,0 +1,184 @@
+// Package markdown renders post content written in Markdown to HTML which is safe to embed into a page.
+// Raw HTML written by authors is kept, the unsafe tags and attributes are stripped from the output.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Document is Markdown rendered to HTML
+type Document struct {
+	// HTML is sanitized
+	HTML string
+	// TOC is the table of contents, the headings go in the order they appear in
+	TOC []*Heading
+	// Excerpt is the plain text of the first paragraph cut to MaxExcerptLen runes
+	Excerpt string
+}
+
+// MaxExcerptLen is the longest excerpt in runes
+const MaxExcerptLen = 280
+
+// Heading is an entry of the table of contents
+type Heading struct {
+	Level int
+	// ID is the anchor of the heading, the heading is linked with "#" + ID
+	ID    string
+	Title string
+}
+
+// Renderer converts Markdown to sanitized HTML. It's safe for concurrent use.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+// headingIdRe matches the ids generated for headings
+var headingIdRe = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+
+func NewRenderer() *Renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// The output is sanitized, so raw HTML is passed to the policy rather than escaped
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").Matching(headingIdRe).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^anchor$`)).OnElements("a")
+
+	return &Renderer{md: md, policy: policy}
+}
+
+// Render returns the source rendered to HTML. Every heading gets an anchor link and an entry in the table of contents.
+func (r *Renderer) Render(source string) (*Document, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(&headingIds{used: map[string]bool{}}))
+	root := r.md.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	doc := &Document{TOC: make([]*Heading, 0)}
+	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		if paragraph, ok := n.(*ast.Paragraph); ok && doc.Excerpt == "" {
+			doc.Excerpt = excerpt(plainText(paragraph, src))
+		}
+
+		heading, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		attr, _ := heading.AttributeString("id")
+		id, _ := attr.([]byte)
+		doc.TOC = append(doc.TOC, &Heading{Level: heading.Level, ID: string(id), Title: plainText(heading, src)})
+
+		anchor := ast.NewLink()
+		anchor.Destination = append([]byte("#"), id...)
+		anchor.SetAttributeString("class", []byte("anchor"))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, anchor)
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, src, root); err != nil {
+		return nil, fmt.Errorf("can not render markdown. error: %w", err)
+	}
+
+	doc.HTML = r.policy.Sanitize(buf.String())
+	return doc, nil
+}
+
+// excerpt cuts the text to MaxExcerptLen runes at a word boundary
+func excerpt(text string) string {
+	runes := []rune(text)
+	if len(runes) <= MaxExcerptLen {
+		return text
+	}
+
+	cut := string(runes[:MaxExcerptLen])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// plainText returns the text of the node without the markup
+func plainText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.Text:
+			sb.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return strings.TrimSpace(sb.String())
+}
+
+// headingIds generates the ids of the headings of a document from their text.
+// The letters of every script are kept, so the ids of non-latin headings are readable.
+type headingIds struct {
+	used map[string]bool
+}
+
+func (ids *headingIds) Generate(value []byte, kind ast.NodeKind) []byte {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_':
+			sb.WriteRune(r)
+			dash = false
+		case (unicode.IsSpace(r) || r == '-') && sb.Len() > 0 && !dash:
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+
+	id := strings.TrimSuffix(sb.String(), "-")
+	if id == "" {
+		id = "heading"
+	}
+
+	unique := id
+	for i := 1; ids.used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	ids.used[unique] = true
+	return []byte(unique)
+}
+
+func (ids *headingIds) Put(value []byte) {
+	ids.used[string(value)] = true
+}
@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "Markdown",
+			source:   "Some *emphasis* and `code`",
+			expected: "<p>Some <em>emphasis</em> and <code>code</code></p>\n",
+		},
+		{
+			name:     "Safe HTML is kept",
+			source:   "Some <b>bold</b> text",
+			expected: "<p>Some <b>bold</b> text</p>\n",
+		},
+		{
+			name:     "Script is stripped",
+			source:   "text<script>alert(1)</script>",
+			expected: "<p>text</p>\n",
+		},
+		{
+			name:     "Event handlers are stripped",
+			source:   `<img src="a.png" onerror="alert(1)">`,
+			expected: `<img src="a.png">`,
+		},
+		{
+			name:     "Javascript links are stripped",
+			source:   "[link](javascript:alert(1)) <a href=\"javascript:alert(1)\">raw</a>",
+			expected: "<p>link raw</p>\n",
+		},
+		{
+			name:     "Heading anchor",
+			source:   "# Hello, *World*!",
+			expected: `<h1 id="hello-world">Hello, <em>World</em>!<a href="#hello-world" class="anchor" rel="nofollow">#</a></h1>` + "\n",
+		},
+	}
+
+	r := NewRenderer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := r.Render(tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, doc.HTML)
+		})
+	}
+}
+
+func TestRender_TOC(t *testing.T) {
+	doc, err := NewRenderer().Render("# Intro\n\ntext\n\n## Getting `started`\n\n## Intro\n\n### Привет, мир\n\n## !!!\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, []*Heading{
+		{Level: 1, ID: "intro", Title: "Intro"},
+		{Level: 2, ID: "getting-started", Title: "Getting started"},
+		{Level: 2, ID: "intro-1", Title: "Intro"},
+		{Level: 3, ID: "привет-мир", Title: "Привет, мир"},
+		{Level: 2, ID: "heading", Title: "!!!"},
+	}, doc.TOC)
+	assert.Contains(t, doc.HTML, `<h3 id="привет-мир">`)
+}
+
+func TestRender_NoHeadings(t *testing.T) {
+	doc, err := NewRenderer().Render("")
+	require.NoError(t, err)
+	assert.Empty(t, doc.HTML)
+	assert.NotNil(t, doc.TOC)
+	assert.Empty(t, doc.TOC)
+}
+
+func TestRender_Excerpt(t *testing.T) {
+	long := strings.Repeat("word ", MaxExcerptLen)
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{name: "First paragraph", source: "# Title\n\nThe *first* [paragraph](/a).\n\nThe second one.", expected: "The first paragraph."},
+		{name: "No paragraphs", source: "# Title", expected: ""},
+		{name: "Cut at a word boundary", source: long, expected: strings.TrimSpace(long[:MaxExcerptLen]) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewRenderer().Render(tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, doc.Excerpt)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/middlewares"
+	"github.com/voltento/go-blog-project/internal/storage"
+	"github.com/voltento/go-blog-project/mocks"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	metrics *Metrics
+	router  *gin.Engine
+	server  *httptest.Server
+	expect  *httpexpect.Expect
+}
+
+func (s *MetricsTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.metrics = New()
+	s.router = gin.New()
+	s.router.Use(s.metrics.Middleware())
+	middlewares.Setup(s.router)
+	s.router.GET("/metrics", gin.WrapH(s.metrics.Handler()))
+
+	s.server = httptest.NewServer(s.router)
+	s.expect = httpexpect.Default(s.T(), s.server.URL)
+}
+
+func (s *MetricsTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *MetricsTestSuite) scrape() *httpexpect.String {
+	return s.expect.GET("/metrics").Expect().Status(http.StatusOK).Body()
+}
+
+func (s *MetricsTestSuite) TestRequests_ByRouteTemplate() {
+	s.router.GET("/v1/posts/:id", func(c *gin.Context) {
+		if c.Param("id") == "0" {
+			c.Error(httperr.WrapWithHttpCode(errors.New("post not found"), http.StatusNotFound))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	s.router.GET("/v1/fail", func(c *gin.Context) {
+		c.Error(errors.New("storage is down"))
+	})
+
+	s.expect.GET("/v1/posts/1").Expect().Status(http.StatusOK)
+	s.expect.GET("/v1/posts/2").Expect().Status(http.StatusOK)
+	s.expect.GET("/v1/posts/0").Expect().Status(http.StatusNotFound)
+	s.expect.GET("/v1/fail").Expect().Status(http.StatusInternalServerError)
+	s.expect.GET("/v1/unknown/1").Expect().Status(http.StatusNotFound)
+
+	body := s.scrape()
+	body.Contains(`blog_http_requests_total{method="GET",route="/v1/posts/:id",status="200"} 2`)
+	body.Contains(`blog_http_requests_total{method="GET",route="/v1/posts/:id",status="404"} 1`)
+	body.Contains(`blog_http_requests_total{method="GET",route="/v1/fail",status="500"} 1`)
+	body.Contains(`blog_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	body.NotContains(`/v1/posts/1"`)
+	body.Contains(`blog_http_request_errors_total{method="GET",route="/v1/fail",status="500"} 1`)
+	body.NotContains(`blog_http_request_errors_total{method="GET",route="/v1/posts/:id"`)
+	body.Contains(`blog_http_request_duration_seconds_count{method="GET",route="/v1/posts/:id"} 3`)
+	// The scrape request is in flight while the metrics are collected
+	body.Contains("blog_http_requests_in_flight 1")
+}
+
+func (s *MetricsTestSuite) TestStorage() {
+	st := s.metrics.InstrumentStorage(storage.NewStorage())
+	ctx := context.Background()
+
+	_, err := st.CreatePost(ctx, &domain.Post{Title: "Published", Status: domain.StatusPublished})
+	s.Require().NoError(err)
+	id, err := st.CreatePost(ctx, &domain.Post{Title: "Draft", Status: domain.StatusDraft})
+	s.Require().NoError(err)
+	_, err = st.Post(ctx, id)
+	s.Require().NoError(err)
+	_, err = st.Post(ctx, id+1)
+	s.Require().Error(err)
+
+	body := s.scrape()
+	body.Contains(`blog_storage_operation_duration_seconds_count{operation="create_post",result="ok"} 2`)
+	body.Contains(`blog_storage_operation_duration_seconds_count{operation="post",result="ok"} 1`)
+	body.Contains(`blog_storage_operation_duration_seconds_count{operation="post",result="error"} 1`)
+	body.Contains(`blog_posts{status="published"} 1`)
+	body.Contains(`blog_posts{status="draft"} 1`)
+	body.Contains(`blog_posts{status="archived"} 0`)
+	// Counting the posts is not a storage operation of the service
+	body.NotContains(`operation="status_counts"`)
+
+	s.Require().NoError(st.DeletePost(ctx, id, domain.AnyVersion))
+	s.scrape().Contains(`blog_posts{status="draft"} 0`)
+}
+
+func (s *MetricsTestSuite) TestStorage_PostCountFailed() {
+	st := new(mocks.Storage)
+	st.On("StatusCounts", mock.Anything).Return(nil, errors.New("storage is down"))
+	s.metrics.InstrumentStorage(st)
+
+	body := s.scrape()
+	body.Contains("blog_http_requests_in_flight")
+	body.NotContains("blog_posts{")
+
+	st.AssertExpectations(s.T())
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+// postCountTimeout limits the time the posts are counted in on a scrape
+const postCountTimeout = 5 * time.Second
+
+// postCount reports the number of the posts in the storage by status.
+// The posts are counted by the storage on a scrape, so the number is right whatever changes the storage.
+type postCount struct {
+	storage blog.Storage
+	desc    *prometheus.Desc
+}
+
+func (p *postCount) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.desc
+}
+
+func (p *postCount) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), postCountTimeout)
+	defer cancel()
+
+	counts, err := p.storage.StatusCounts(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(p.desc, fmt.Errorf("can not count posts. error: %w", err))
+		return
+	}
+
+	for _, status := range []domain.PostStatus{domain.StatusDraft, domain.StatusPublished, domain.StatusScheduled, domain.StatusArchived} {
+		ch <- prometheus.MustNewConstMetric(p.desc, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
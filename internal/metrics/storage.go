@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+// InstrumentStorage returns the storage which records the time of the operations made with s.
+// The posts of s are counted on every scrape. It should be called once for the metrics.
+func (m *Metrics) InstrumentStorage(s blog.Storage) blog.Storage {
+	m.registry.MustRegister(&postCount{
+		storage: s,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "posts"),
+			"Number of the posts by status.",
+			[]string{"status"}, nil,
+		),
+	})
+	return &instrumentedStorage{next: s, duration: m.storageDuration}
+}
+
+// instrumentedStorage records the time of the storage operations by the operation name and the result
+type instrumentedStorage struct {
+	next     blog.Storage
+	duration *prometheus.HistogramVec
+}
+
+func (s *instrumentedStorage) observe(operation string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	s.duration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+func (s *instrumentedStorage) Post(ctx context.Context, id domain.PostId) (*domain.Post, error) {
+	start := time.Now()
+	post, err := s.next.Post(ctx, id)
+	s.observe("post", start, err)
+	return post, err
+}
+
+func (s *instrumentedStorage) CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error) {
+	start := time.Now()
+	id, err := s.next.CreatePost(ctx, post)
+	s.observe("create_post", start, err)
+	return id, err
+}
+
+func (s *instrumentedStorage) CreatePostWithID(ctx context.Context, post *domain.Post) error {
+	start := time.Now()
+	err := s.next.CreatePostWithID(ctx, post)
+	s.observe("create_post_with_id", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) DeletePost(ctx context.Context, id domain.PostId, version int) error {
+	start := time.Now()
+	err := s.next.DeletePost(ctx, id, version)
+	s.observe("delete_post", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error {
+	start := time.Now()
+	err := s.next.UpdatePost(ctx, post, id)
+	s.observe("update_post", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Posts(ctx context.Context) ([]*domain.Post, error) {
+	start := time.Now()
+	posts, err := s.next.Posts(ctx)
+	s.observe("posts", start, err)
+	return posts, err
+}
+
+func (s *instrumentedStorage) PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error) {
+	start := time.Now()
+	page, err := s.next.PostsPage(ctx, q)
+	s.observe("posts_page", start, err)
+	return page, err
+}
+
+func (s *instrumentedStorage) ScheduledPosts(ctx context.Context, until time.Time) ([]*domain.Post, error) {
+	start := time.Now()
+	posts, err := s.next.ScheduledPosts(ctx, until)
+	s.observe("scheduled_posts", start, err)
+	return posts, err
+}
+
+func (s *instrumentedStorage) Tags(ctx context.Context) ([]*domain.TagCount, error) {
+	start := time.Now()
+	tags, err := s.next.Tags(ctx)
+	s.observe("tags", start, err)
+	return tags, err
+}
+
+func (s *instrumentedStorage) StatusCounts(ctx context.Context) (map[domain.PostStatus]int, error) {
+	start := time.Now()
+	counts, err := s.next.StatusCounts(ctx)
+	s.observe("status_counts", start, err)
+	return counts, err
+}
+
+func (s *instrumentedStorage) AddRevision(ctx context.Context, rev *domain.Revision) error {
+	start := time.Now()
+	err := s.next.AddRevision(ctx, rev)
+	s.observe("add_revision", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error) {
+	start := time.Now()
+	revs, err := s.next.Revisions(ctx, id)
+	s.observe("revisions", start, err)
+	return revs, err
+}
+
+func (s *instrumentedStorage) Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error) {
+	start := time.Now()
+	rev, err := s.next.Revision(ctx, id, number)
+	s.observe("revision", start, err)
+	return rev, err
+}
+
+func (s *instrumentedStorage) CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error) {
+	start := time.Now()
+	id, err := s.next.CreateComment(ctx, comment)
+	s.observe("create_comment", start, err)
+	return id, err
+}
+
+func (s *instrumentedStorage) Comment(ctx context.Context, postId domain.PostId, id domain.CommentId) (*domain.Comment, error) {
+	start := time.Now()
+	comment, err := s.next.Comment(ctx, postId, id)
+	s.observe("comment", start, err)
+	return comment, err
+}
+
+func (s *instrumentedStorage) Comments(ctx context.Context, postId domain.PostId) ([]*domain.Comment, error) {
+	start := time.Now()
+	comments, err := s.next.Comments(ctx, postId)
+	s.observe("comments", start, err)
+	return comments, err
+}
+
+func (s *instrumentedStorage) UpdateComment(ctx context.Context, comment *domain.Comment) error {
+	start := time.Now()
+	err := s.next.UpdateComment(ctx, comment)
+	s.observe("update_comment", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	start := time.Now()
+	err := s.next.DeleteComment(ctx, postId, id)
+	s.observe("delete_comment", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error) {
+	start := time.Now()
+	id, err := s.next.CreateUser(ctx, user)
+	s.observe("create_user", start, err)
+	return id, err
+}
+
+func (s *instrumentedStorage) User(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.next.User(ctx, id)
+	s.observe("user", start, err)
+	return user, err
+}
+
+func (s *instrumentedStorage) UserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.next.UserByUsername(ctx, username)
+	s.observe("user_by_username", start, err)
+	return user, err
+}
+
+func (s *instrumentedStorage) UpdateUser(ctx context.Context, user *domain.User) error {
+	start := time.Now()
+	err := s.next.UpdateUser(ctx, user)
+	s.observe("update_user", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) MigrationRecord(ctx context.Context, id string) (*domain.MigrationRecord, error) {
+	start := time.Now()
+	rec, err := s.next.MigrationRecord(ctx, id)
+	s.observe("migration_record", start, err)
+	return rec, err
+}
+
+func (s *instrumentedStorage) AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error {
+	start := time.Now()
+	err := s.next.AddMigrationRecord(ctx, rec)
+	s.observe("add_migration_record", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) DeleteMigrationRecord(ctx context.Context, id string) error {
+	start := time.Now()
+	err := s.next.DeleteMigrationRecord(ctx, id)
+	s.observe("delete_migration_record", start, err)
+	return err
+}
@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+// JWTConfig keeps the keys bearer tokens are verified with. Only the algorithms with a configured key are accepted.
+type JWTConfig struct {
+	// HMACSecret verifies HS256 tokens
+	HMACSecret []byte
+	// RSAPublicKey verifies RS256 tokens
+	RSAPublicKey *rsa.PublicKey
+}
+
+// claims are the token claims the principal is built from
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+// AuthMiddleware verifies the bearer token of the request and puts the principal it carries on the request context.
+// Requests without a token are passed as anonymous, it's up to the handlers to reject them.
+// A request with an invalid token is rejected with 401.
+func AuthMiddleware(cfg JWTConfig) gin.HandlerFunc {
+	// Without keys no token is valid, so the methods list is not nil even if it's empty
+	methods := []string{}
+	if len(cfg.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.RSAPublicKey != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	parser := jwt.NewParser(jwt.WithValidMethods(methods), jwt.WithExpirationRequired())
+	keyFunc := func(t *jwt.Token) (any, error) {
+		switch t.Method {
+		case jwt.SigningMethodHS256:
+			return cfg.HMACSecret, nil
+		case jwt.SigningMethodRS256:
+			return cfg.RSAPublicKey, nil
+		default:
+			return nil, fmt.Errorf("unexpected signing method %v", t.Method.Alg())
+		}
+	}
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		p, err := verifyBearer(parser, keyFunc, header)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.Error(httperr.WrapWithCode(err, http.StatusUnauthorized, httperr.CodeInvalidToken))
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), p))
+		c.Next()
+	}
+}
+
+func verifyBearer(parser *jwt.Parser, keyFunc jwt.Keyfunc, header string) (*auth.Principal, error) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, errors.New("authorization should be a bearer token")
+	}
+
+	var cl claims
+	if _, err := parser.ParseWithClaims(strings.TrimSpace(token), &cl, keyFunc); err != nil {
+		return nil, fmt.Errorf("invalid bearer token. error: %w", err)
+	}
+
+	if cl.Subject == "" {
+		return nil, errors.New("invalid bearer token. error: subject is missing")
+	}
+
+	return &auth.Principal{Subject: cl.Subject, Roles: cl.Roles}, nil
+}
+
+// TokenIssuer signs HS256 tokens accepted by AuthMiddleware configured with the same secret
+type TokenIssuer struct {
+	Secret []byte
+	// TTL is the time a token is valid for
+	TTL time.Duration
+}
+
+// IssueToken returns a token of the subject and the time it expires at
+func (i *TokenIssuer) IssueToken(subject string) (string, time.Time, error) {
+	if len(i.Secret) == 0 {
+		err := errors.New("token issuing is not configured")
+		return "", time.Time{}, httperr.WrapWithHttpCode(err, http.StatusServiceUnavailable)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(i.TTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}})
+
+	signed, err := token.SignedString(i.Secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("can not sign token. error: %w", err)
+	}
+
+	return signed, expiresAt, nil
+}
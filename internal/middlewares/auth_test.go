@@ -0,0 +1,183 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+var testSecret = []byte("secret")
+
+func newAuthRouter(cfg JWTConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(HttpErrHandlerMiddleware())
+	r.Use(AuthMiddleware(cfg))
+	r.GET("/", func(c *gin.Context) {
+		p, ok := auth.PrincipalFrom(c.Request.Context())
+		if !ok {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, p.Subject+" %v", p.Roles)
+	})
+	return r
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "alice", "roles": []string{"admin"}, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherRsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	noExpiration := validClaims()
+	delete(noExpiration, "exp")
+	noSubject := validClaims()
+	delete(noSubject, "sub")
+
+	tests := []struct {
+		name          string
+		cfg           JWTConfig
+		authorization string
+		expectedCode  int
+		expectedBody  string
+	}{
+		{
+			name:         "Anonymous",
+			cfg:          JWTConfig{HMACSecret: testSecret},
+			expectedCode: http.StatusOK,
+			expectedBody: "anonymous",
+		},
+		{
+			name:          "HS256",
+			cfg:           JWTConfig{HMACSecret: testSecret},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodHS256, testSecret, validClaims()),
+			expectedCode:  http.StatusOK,
+			expectedBody:  "alice [admin]",
+		},
+		{
+			name:          "RS256",
+			cfg:           JWTConfig{RSAPublicKey: &rsaKey.PublicKey},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, validClaims()),
+			expectedCode:  http.StatusOK,
+			expectedBody:  "alice [admin]",
+		},
+		{
+			name:          "Wrong secret",
+			cfg:           JWTConfig{HMACSecret: testSecret},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte("other"), validClaims()),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Wrong RSA key",
+			cfg:           JWTConfig{RSAPublicKey: &rsaKey.PublicKey},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodRS256, otherRsaKey, validClaims()),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Not configured algorithm",
+			cfg:           JWTConfig{RSAPublicKey: &rsaKey.PublicKey},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodHS256, testSecret, validClaims()),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "No keys",
+			cfg:           JWTConfig{},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodHS256, testSecret, validClaims()),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Unsigned",
+			cfg:           JWTConfig{HMACSecret: testSecret},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims()),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Expired",
+			cfg:           JWTConfig{HMACSecret: testSecret},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodHS256, testSecret, expired),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "No expiration",
+			cfg:           JWTConfig{HMACSecret: testSecret},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodHS256, testSecret, noExpiration),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "No subject",
+			cfg:           JWTConfig{HMACSecret: testSecret},
+			authorization: "Bearer " + signToken(t, jwt.SigningMethodHS256, testSecret, noSubject),
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Not bearer",
+			cfg:           JWTConfig{HMACSecret: testSecret},
+			authorization: "Basic YWxpY2U6c2VjcmV0",
+			expectedCode:  http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			newAuthRouter(tt.cfg).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			} else {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestTokenIssuer(t *testing.T) {
+	issuer := TokenIssuer{Secret: testSecret, TTL: time.Hour}
+	token, expiresAt, err := issuer.IssueToken("42")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	newAuthRouter(JWTConfig{HMACSecret: testSecret}).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "42 []", w.Body.String())
+}
+
+func TestTokenIssuer_NoSecret(t *testing.T) {
+	issuer := TokenIssuer{TTL: time.Hour}
+	_, _, err := issuer.IssueToken("42")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, httperr.HTTPStatusCode(err, 0))
+}
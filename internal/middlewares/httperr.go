@@ -1,8 +1,10 @@
 package middlewares
 
 import (
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/exp/slog"
 	"net/http"
 )
 
@@ -10,7 +12,8 @@ const (
 	invalidStatusCode = 0
 )
 
-// HttpErrHandlerMiddleware handles http errors to extract status code
+// HttpErrHandlerMiddleware renders the first error of the request as RFC 7807 problem details.
+// The status code is extracted from the error, the details of the server errors are logged instead of rendered.
 func HttpErrHandlerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
@@ -25,8 +28,21 @@ func HttpErrHandlerMiddleware() gin.HandlerFunc {
 			}
 		}
 
-		if statusCode != 0 {
-			c.JSON(statusCode, gin.H{"error": httpErr.Error()})
+		if statusCode == invalidStatusCode {
+			return
 		}
+
+		if statusCode >= http.StatusInternalServerError {
+			attrs := []any{"method", c.Request.Method, "path", c.Request.URL.Path, "error", httpErr}
+			slog.Error("request failed", append(attrs, traceAttrs(c)...)...)
+		}
+
+		body, err := json.Marshal(httperr.NewProblem(httpErr, c.Request.URL.Path))
+		if err != nil {
+			slog.Error("can not render problem", "error", err)
+			c.Status(statusCode)
+			return
+		}
+		c.Data(statusCode, httperr.ContentType, body)
 	}
 }
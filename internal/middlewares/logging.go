@@ -6,19 +6,21 @@ import (
 	"time"
 )
 
+// LoggerMiddleware logs the handled requests with the trace id of the request if it's traced
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
 		duration := time.Since(start)
 
-		slog.Info("Request handled",
+		attrs := []any{
 			"method", c.Request.Method,
 			"path", c.Request.URL.Path,
 			"ip", c.ClientIP(),
 			"user-agent", c.Request.UserAgent(),
 			"status", c.Writer.Status(),
 			"duration", duration,
-		)
+		}
+		slog.Info("Request handled", append(attrs, traceAttrs(c)...)...)
 	}
 }
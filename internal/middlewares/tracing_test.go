@@ -0,0 +1,123 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/tracing/tracingtest"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func newTracingRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TracingMiddleware())
+	Setup(r)
+	r.GET("/posts/:id", func(c *gin.Context) {
+		if c.Param("id") == "0" {
+			c.Error(errors.New("storage is down"))
+			return
+		}
+		c.String(http.StatusOK, trace.SpanContextFromContext(c.Request.Context()).TraceID().String())
+	})
+	return r
+}
+
+// captureLogs returns the buffer the log lines are written to as JSON till the test ends
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestTracingMiddleware_Traceparent(t *testing.T) {
+	exporter := tracingtest.Record(t)
+	logs := captureLogs(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+	req.Header.Set("traceparent", "00-"+testTraceID+"-"+testSpanID+"-01")
+	resp := httptest.NewRecorder()
+	newTracingRouter().ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, testTraceID, resp.Body.String())
+
+	span := tracingtest.Span(t, exporter, "GET /posts/:id")
+	assert.Equal(t, testTraceID, span.SpanContext.TraceID().String())
+	assert.Equal(t, testSpanID, span.Parent.SpanID().String())
+	assert.True(t, span.Parent.IsRemote())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Contains(t, span.Attributes, semconv.HTTPRoute("/posts/:id"))
+	assert.Contains(t, span.Attributes, semconv.URLPath("/posts/1"))
+	assert.Contains(t, span.Attributes, semconv.HTTPResponseStatusCode(http.StatusOK))
+	assert.Equal(t, codes.Unset, span.Status.Code)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+	assert.Equal(t, "Request handled", line["msg"])
+	assert.Equal(t, testTraceID, line["trace_id"])
+	assert.Equal(t, span.SpanContext.SpanID().String(), line["span_id"])
+}
+
+func TestTracingMiddleware_NewTrace(t *testing.T) {
+	exporter := tracingtest.Record(t)
+
+	resp := httptest.NewRecorder()
+	newTracingRouter().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+
+	span := tracingtest.Span(t, exporter, "GET /posts/:id")
+	assert.True(t, span.SpanContext.IsValid())
+	assert.False(t, span.Parent.IsValid())
+	assert.Equal(t, span.SpanContext.TraceID().String(), resp.Body.String())
+}
+
+func TestTracingMiddleware_ServerError(t *testing.T) {
+	exporter := tracingtest.Record(t)
+	logs := captureLogs(t)
+
+	resp := httptest.NewRecorder()
+	newTracingRouter().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/posts/0", nil))
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+
+	span := tracingtest.Span(t, exporter, "GET /posts/:id")
+	assert.Equal(t, codes.Error, span.Status.Code)
+	require.Len(t, span.Events, 1)
+	assert.Equal(t, "exception", span.Events[0].Name)
+
+	// The failure and the request lines both have the trace id
+	traceID := span.SpanContext.TraceID().String()
+	dec := json.NewDecoder(logs)
+	for dec.More() {
+		var line map[string]any
+		require.NoError(t, dec.Decode(&line))
+		assert.Equal(t, traceID, line["trace_id"], line["msg"])
+	}
+}
+
+func TestTracingMiddleware_NotTraced(t *testing.T) {
+	logs := captureLogs(t)
+
+	resp := httptest.NewRecorder()
+	newTracingRouter().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+	assert.NotContains(t, line, "trace_id")
+}
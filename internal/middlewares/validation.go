@@ -0,0 +1,191 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/openapi"
+	"golang.org/x/exp/slog"
+)
+
+const jsonContentType = "application/json"
+
+// maxJSONBodySize is the size of the largest JSON body of a request, the largest post is well below it
+const maxJSONBodySize = 1 << 20
+
+// routeParam matches the parameters of a gin route, e.g. :id, they are {id} in the document
+var routeParam = regexp.MustCompile(`[:*](\w+)`)
+
+// RequestValidationMiddleware checks the parameters and the JSON body of the requests against the operations of the
+// document. An invalid request is rejected with 400 listing all the invalid fields, so the handlers get the requests
+// matching the document only. The routes the document does not describe are passed as is.
+func RequestValidationMiddleware(d *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := operation(d, c)
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		fields, err := validateRequest(d, op, c)
+		if err == nil && len(fields) > 0 {
+			err = httperr.Invalid(fields...)
+		}
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ResponseValidationMiddleware checks the responses against the operations of the document and logs the ones not
+// matching it, so a handler drifting from the document is noticed. The JSON responses are kept in memory
+// to be checked, so it's enabled explicitly.
+func ResponseValidationMiddleware(d *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := operation(d, c)
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		fields, err := validateResponse(d, op, w.Status(), w.Header().Get("Content-Type"), w.body.Bytes())
+		if err == nil && len(fields) > 0 {
+			err = errors.New("body does not match the schema")
+		}
+		if err != nil {
+			attrs := []any{"method", c.Request.Method, "route", c.FullPath(), "status", w.Status(), "error", err}
+			if len(fields) > 0 {
+				attrs = append(attrs, "fields", fields)
+			}
+			slog.Error("response does not match the OpenAPI document", attrs...)
+		}
+	}
+}
+
+// operation returns the operation of the route the request matched or nil if the document does not describe it
+func operation(d *openapi.Document, c *gin.Context) *openapi.Operation {
+	if c.FullPath() == "" {
+		return nil
+	}
+	return d.Operation(c.Request.Method, routeParam.ReplaceAllString(c.FullPath(), "{$1}"))
+}
+
+// validateRequest returns the invalid fields of the request.
+// The error is returned if the JSON body can not be decoded at all.
+func validateRequest(d *openapi.Document, op *openapi.Operation, c *gin.Context) ([]httperr.FieldError, error) {
+	var fields []httperr.FieldError
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "path":
+			if v := c.Param(p.Name); v != "" {
+				values = []string{v}
+			}
+		case "query":
+			values = c.QueryArray(p.Name)
+		case "header":
+			values = c.Request.Header.Values(p.Name)
+		}
+		fields = append(fields, d.ValidateParameter(p, values)...)
+	}
+
+	if op.RequestBody == nil || op.RequestBody.Content[jsonContentType] == nil {
+		return fields, nil
+	}
+
+	// The body is put back for the handler to bind it
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxJSONBodySize))
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		msg := fmt.Errorf("request body should be at most %d bytes", tooLarge.Limit)
+		return nil, httperr.WrapWithCode(msg, http.StatusBadRequest, httperr.CodeMalformedBody)
+	}
+
+	var body map[string]any
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&body)
+	}
+	if err != nil || body == nil {
+		msg := errors.New("request body should be a JSON object")
+		return nil, httperr.WrapWithCode(msg, http.StatusBadRequest, httperr.CodeMalformedBody)
+	}
+
+	return append(fields, d.Validate("", op.RequestBody.Content[jsonContentType].Schema, body)...), nil
+}
+
+// validateResponse returns the fields of the JSON body not matching the schema of the response.
+// The error is returned if the status or the content type of the response are not documented.
+func validateResponse(d *openapi.Document, op *openapi.Operation, status int, contentType string, body []byte) ([]httperr.FieldError, error) {
+	resp := d.ResolveResponse(op.Responses[strconv.Itoa(status)])
+	if resp == nil {
+		return nil, fmt.Errorf("status %d is not documented", status)
+	}
+	if len(resp.Content) == 0 {
+		return nil, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("content type '%s' is not documented", contentType)
+	}
+	if !isJSON(mediaType) {
+		return nil, nil
+	}
+
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("body is not JSON: %w", err)
+	}
+	return d.Validate("", media.Schema, v), nil
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == jsonContentType || strings.HasSuffix(mediaType, "+json")
+}
+
+// recordingWriter keeps the JSON body written to the response, the other bodies are only passed through
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) record(data []byte) {
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if isJSON(mediaType) {
+		w.body.Write(data)
+	}
+}
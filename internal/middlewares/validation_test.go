@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/openapi"
+)
+
+type item struct {
+	Name string `json:"name" binding:"required,max=5"`
+}
+
+func newItemsDocument() *openapi.Document {
+	d := &openapi.Document{}
+	d.Component(item{}).AdditionalProperties = new(bool)
+	d.Components.Responses = map[string]*openapi.Response{
+		"BadRequest": {Content: map[string]*openapi.MediaType{httperr.ContentType: {Schema: &openapi.Schema{Type: openapi.Types{"object"}}}}},
+	}
+
+	jsonContent := func(s *openapi.Schema) map[string]*openapi.MediaType {
+		return map[string]*openapi.MediaType{jsonContentType: {Schema: s}}
+	}
+	d.AddOperation(http.MethodPut, "/items/{id}", &openapi.Operation{
+		Parameters:  []*openapi.Parameter{{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: openapi.Types{"integer"}}}},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(openapi.Ref("Item"))},
+		Responses: map[string]*openapi.Response{
+			"200": {Content: jsonContent(openapi.Ref("Item"))},
+			"400": openapi.ResponseRef("BadRequest"),
+		},
+	})
+	return d
+}
+
+func newValidationRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	d := newItemsDocument()
+	r := gin.New()
+	r.Use(ResponseValidationMiddleware(d))
+	Setup(r)
+	r.Use(RequestValidationMiddleware(d))
+	r.PUT("/items/:id", handler)
+	r.GET("/items", func(c *gin.Context) { c.String(http.StatusOK, "not described") })
+	return r
+}
+
+func putItem(r http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", jsonContentType)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRequestValidationMiddleware(t *testing.T) {
+	handled := false
+	r := newValidationRouter(func(c *gin.Context) {
+		handled = true
+		c.JSON(http.StatusOK, gin.H{"name": "pen"})
+	})
+
+	resp := putItem(r, "/items/x", `{"name": "pencil", "color": "red"}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `[
+		{"field": "id", "message": "should be an integer, got 'x'"},
+		{"field": "color", "message": "is not a known field"},
+		{"field": "name", "message": "should be at most 5 characters long"}
+	]`, extractErrors(t, resp.Body.String()))
+
+	resp = putItem(r, "/items/1", `["pen"]`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"code":"malformed_body"`)
+	assert.False(t, handled, "the invalid requests should not be handled")
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/items?unknown=1", nil))
+	assert.Equal(t, http.StatusOK, resp.Code, "the routes the document does not describe should be passed")
+}
+
+func TestRequestValidationMiddleware_Body(t *testing.T) {
+	r := newValidationRouter(func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		c.Data(http.StatusOK, jsonContentType, body)
+	})
+
+	resp := putItem(r, "/items/1", `{"name": "pen"}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"name": "pen"}`, resp.Body.String(), "the handler should get the body")
+}
+
+func TestRequestValidationMiddleware_LargeBody(t *testing.T) {
+	handled := false
+	r := newValidationRouter(func(c *gin.Context) { handled = true })
+
+	resp := putItem(r, "/items/1", `{"name": "`+strings.Repeat("a", maxJSONBodySize)+`"}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"code":"malformed_body"`)
+	assert.Contains(t, resp.Body.String(), "at most 1048576 bytes")
+	assert.False(t, handled)
+}
+
+func TestResponseValidationMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  gin.HandlerFunc
+		expected string
+	}{
+		{"Valid", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"name": "pen"}) }, ""},
+		{"Problem", func(c *gin.Context) { c.Error(httperr.WrapWithHttpCode(assert.AnError, http.StatusBadRequest)) }, ""},
+		{"Invalid Body", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"name": 1}) }, "body does not match the schema"},
+		{"Undocumented Status", func(c *gin.Context) { c.Status(http.StatusTeapot) }, "status 418 is not documented"},
+		{"Undocumented Content Type", func(c *gin.Context) { c.String(http.StatusOK, "pen") }, "content type 'text/plain; charset=utf-8' is not documented"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			resp := putItem(newValidationRouter(tt.handler), "/items/1", `{"name": "pen"}`)
+			assert.NotEqual(t, http.StatusInternalServerError, resp.Code, "the response should be sent as is")
+
+			if tt.expected == "" {
+				assert.NotContains(t, logs.String(), "response does not match")
+				return
+			}
+			assert.Contains(t, logs.String(), `"msg":"response does not match the OpenAPI document"`)
+			assert.Contains(t, logs.String(), `"route":"/items/:id"`)
+			assert.Contains(t, logs.String(), tt.expected)
+		})
+	}
+}
+
+// extractErrors returns the invalid fields of the problem details
+func extractErrors(t *testing.T, problem string) string {
+	var p struct {
+		Errors json.RawMessage `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(problem), &p))
+	return string(p.Errors)
+}
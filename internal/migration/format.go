@@ -0,0 +1,121 @@
+package migration
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+// CurrentVersion is the version of the migration format with operations.
+// Version 1, the files without a version, lists the posts to create.
+const CurrentVersion = 2
+
+// Op is the kind of an operation of a migration
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+// file is the migration file
+type file struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	// Posts are the posts to create in version 1
+	Posts      []post      `json:"Posts"`
+	Operations []operation `json:"operations"`
+}
+
+// post is a post of version 1
+type post struct {
+	ID      int    `json:"ID,omitempty"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Author  string `json:"author"`
+}
+
+// operation changes a post. The update sets the fields of the post which are present only.
+type operation struct {
+	Op     Op            `json:"op"`
+	PostID domain.PostId `json:"post_id,omitempty"`
+	Post   *postFields   `json:"post,omitempty"`
+}
+
+type postFields struct {
+	Title     *string            `json:"title,omitempty"`
+	Content   *string            `json:"content,omitempty"`
+	Author    *string            `json:"author,omitempty"`
+	Tags      *[]string          `json:"tags,omitempty"`
+	Status    *domain.PostStatus `json:"status,omitempty"`
+	PublishAt *time.Time         `json:"publish_at,omitempty"`
+}
+
+// readFile reads the migration and returns it with the checksum of the file
+func readFile(filePath string) (*file, string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f file
+	if err := dec.Decode(&f); err != nil {
+		return nil, "", fmt.Errorf("can not parse migration file '%s'. error: %w", filePath, err)
+	}
+	if dec.More() {
+		return nil, "", fmt.Errorf("migration file '%s' should keep one migration", filePath)
+	}
+
+	if err := f.upgrade(); err != nil {
+		return nil, "", fmt.Errorf("can not read migration file '%s'. error: %w", filePath, err)
+	}
+
+	return &f, checksum, nil
+}
+
+// upgrade turns the posts of version 1 to the create operations.
+// The posts of version 1 are published at the time the migration is applied.
+func (f *file) upgrade() error {
+	switch f.Version {
+	case 0, 1:
+		if len(f.Operations) > 0 {
+			return fmt.Errorf("operations require version %d", CurrentVersion)
+		}
+	case CurrentVersion:
+		if len(f.Posts) > 0 {
+			return fmt.Errorf("posts are replaced with create operations in version %d", CurrentVersion)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migration version %d, the latest is %d", f.Version, CurrentVersion)
+	}
+
+	for _, p := range f.Posts {
+		p := p
+		published := domain.StatusPublished
+		f.Operations = append(f.Operations, operation{
+			Op:     OpCreate,
+			PostID: domain.PostId(p.ID),
+			Post: &postFields{
+				Title:   &p.Title,
+				Content: &p.Content,
+				Author:  &p.Author,
+				Status:  &published,
+			},
+		})
+	}
+	f.Posts = nil
+	return nil
+}
@@ -2,51 +2,594 @@ package migration
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/voltento/go-blog-project/internal/domain"
-	"os"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/exp/slog"
 )
 
 type Storage interface {
+	Post(ctx context.Context, id domain.PostId) (*domain.Post, error)
 	CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error)
+	CreatePostWithID(ctx context.Context, post *domain.Post) error
+	UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error
+	DeletePost(ctx context.Context, id domain.PostId, version int) error
+	Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error)
+	AddRevision(ctx context.Context, rev *domain.Revision) error
+	Comments(ctx context.Context, postId domain.PostId) ([]*domain.Comment, error)
+	CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error)
+	CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error)
+	UserByUsername(ctx context.Context, username string) (*domain.User, error)
+	MigrationRecord(ctx context.Context, id string) (*domain.MigrationRecord, error)
+	AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error
+	DeleteMigrationRecord(ctx context.Context, id string) error
 }
 
+// placeholderPrefix starts the usernames of the imported authors. It's not allowed on signup,
+// so the imported authors never clash with the registered users.
+const placeholderPrefix = "imported:"
+
+// checksumPrefix starts the ledger ids of the migrations which have no id
+const checksumPrefix = "sha256:"
+
+// Migration applies migration files to storage. A migration is applied once: the applied migrations are kept
+// in the ledger of the storage by the id of the file, or by its checksum if the file has no id, so a rerun is a no-op.
+// The migration is added to the ledger before the first change is made, so of the instances applying it at once
+// only one makes the changes. All the operations are checked before the first change is made, and the made changes
+// are undone and the migration is removed from the ledger if storage fails.
 type Migration struct {
+	// DryRun checks the migration and reports the changes it would make without making them
+	DryRun bool
+	// Report is called with every change the migration makes, or would make on a dry run
+	Report func(change string)
 }
 
 func (m *Migration) Apply(ctx context.Context, filePath string, s Storage) error {
-	data, err := os.ReadFile(filePath)
+	f, checksum, err := readFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	id := f.ID
+	if id == "" {
+		id = checksumPrefix + checksum
+	}
+
+	applied, err := m.applied(ctx, s, id, checksum, filePath)
+	if err != nil || applied {
+		return err
+	}
+
+	appliedAt := time.Now().UTC()
+	p := &planner{storage: s, authors: &authors{storage: s, users: map[string]*domain.User{}}, posts: map[domain.PostId]*domain.Post{}}
+	steps, err := p.plan(ctx, f.Operations, appliedAt)
 	if err != nil {
+		return fmt.Errorf("can not apply migration from file '%s'. error: %w", filePath, err)
+	}
+
+	for _, st := range steps {
+		m.report(st.String())
+	}
+	if m.DryRun {
+		return nil
+	}
+
+	// The record is added first, so a concurrent instance applying the migration gets a conflict
+	err = s.AddMigrationRecord(ctx, &domain.MigrationRecord{ID: id, Checksum: checksum, AppliedAt: appliedAt, Operations: len(steps)})
+	if httperr.HTTPStatusCode(err, 0) == http.StatusConflict {
+		_, err := m.applied(ctx, s, id, checksum, filePath)
 		return err
 	}
+	if err != nil {
+		return fmt.Errorf("can not add migration to the ledger. id: %v. error: %w", id, err)
+	}
+
+	a := &applier{storage: s, authors: p.authors}
+	if err := a.apply(ctx, steps); err != nil {
+		// The migration is not applied, so a rerun should apply it. It's not canceled with the context as the undo.
+		if err := s.DeleteMigrationRecord(context.Background(), id); err != nil {
+			slog.Error("can not remove failed migration from the ledger", "id", id, "error", err)
+		}
+		return fmt.Errorf("can not apply migration from file '%s'. error: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// applied reports whether the migration is in the ledger. The migration with the id but other content is an error.
+func (m *Migration) applied(ctx context.Context, s Storage, id, checksum, filePath string) (bool, error) {
+	rec, err := s.MigrationRecord(ctx, id)
+	switch {
+	case err == nil && rec.Checksum == checksum:
+		m.report(fmt.Sprintf("migration %s is applied already at %s", id, rec.AppliedAt.Format(time.RFC3339)))
+		return true, nil
+	case err == nil:
+		err := fmt.Errorf("migration is applied already with other content. id: %v, file: '%s'", id, filePath)
+		return false, httperr.WrapWithHttpCode(err, http.StatusConflict)
+	case httperr.HTTPStatusCode(err, 0) != http.StatusNotFound:
+		return false, err
+	default:
+		return false, nil
+	}
+}
+
+func (m *Migration) report(change string) {
+	if m.Report != nil {
+		m.Report(change)
+	}
+}
+
+// step is a checked operation
+type step struct {
+	// n is the number of the operation in the file starting from 1
+	n  int
+	op Op
+	id domain.PostId
+	// version is the version of the stored post the update or the delete is based on
+	version int
+	// before is the post the update or the delete changes, the updated one is restored on undo
+	before *domain.Post
+	// after is the post the create or the update stores. The author id of a new author is set on apply.
+	after *domain.Post
+	// fields are the names of the fields the update sets
+	fields []string
+	// deleted is the post the delete removed, it's restored on undo
+	deleted *deletedPost
+}
+
+// deletedPost is the stored post with its revisions and comments as they are before the delete
+type deletedPost struct {
+	post      *domain.Post
+	revisions []*domain.Revision
+	comments  []*domain.Comment
+}
+
+func (st *step) String() string {
+	switch st.op {
+	case OpCreate:
+		return fmt.Sprintf("create %s post '%s' by '%s'", st.after.Status, st.after.Title, st.after.Author)
+	case OpUpdate:
+		return fmt.Sprintf("update post %d '%s': %s", st.id, st.before.Title, strings.Join(st.fields, ", "))
+	default:
+		return fmt.Sprintf("delete post %d '%s'", st.id, st.before.Title)
+	}
+}
+
+// planner checks the operations without changing storage
+type planner struct {
+	storage Storage
+	authors *authors
+	// posts are the posts as the checked operations leave them, the deleted posts are nil
+	posts map[domain.PostId]*domain.Post
+}
+
+// plan checks all the operations and returns their steps. The invalid operations are listed in the error.
+func (p *planner) plan(ctx context.Context, ops []operation, appliedAt time.Time) ([]*step, error) {
+	var steps []*step
+	var invalid []error
+	for i, op := range ops {
+		st, err := p.step(ctx, op, appliedAt)
+		var opErr *operationError
+		switch {
+		case errors.As(err, &opErr):
+			invalid = append(invalid, fmt.Errorf("operation %d: %w", i+1, err))
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		st.n = i + 1
+		steps = append(steps, st)
+	}
+
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("migration is invalid. error: %w", errors.Join(invalid...))
+	}
+	return steps, nil
+}
+
+// operationError is an invalid operation, the rest of the errors are the failures of storage
+type operationError struct {
+	msg string
+}
+
+func (e *operationError) Error() string {
+	return e.msg
+}
+
+func invalidf(format string, args ...any) error {
+	return &operationError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *planner) step(ctx context.Context, op operation, appliedAt time.Time) (*step, error) {
+	switch op.Op {
+	case OpCreate:
+		return p.create(ctx, op, appliedAt)
+	case OpUpdate:
+		return p.update(ctx, op, appliedAt)
+	case OpDelete:
+		return p.delete(ctx, op)
+	default:
+		return nil, invalidf("op '%s' should be one of create, update or delete", op.Op)
+	}
+}
+
+// create checks the new post. The post is published unless another status is set.
+func (p *planner) create(ctx context.Context, op operation, appliedAt time.Time) (*step, error) {
+	if op.Post == nil {
+		return nil, invalidf("post is required")
+	}
+
+	after := &domain.Post{ID: op.PostID, Status: domain.StatusPublished}
+	if _, err := p.setFields(ctx, after, nil, op.Post, appliedAt); err != nil {
+		return nil, err
+	}
+
+	if op.PostID == 0 {
+		return &step{op: OpCreate, after: after}, nil
+	}
+
+	// The post with the explicit id can be changed by the next operations
+	if err := p.checkFree(ctx, op.PostID); err != nil {
+		return nil, err
+	}
+	created := *after
+	created.Version = 1
+	p.posts[op.PostID] = &created
+	return &step{op: OpCreate, id: op.PostID, after: after}, nil
+}
+
+// checkFree checks that the explicit id of a new post is not taken
+func (p *planner) checkFree(ctx context.Context, id domain.PostId) error {
+	if id < 0 {
+		return invalidf("post_id should be positive. id: %v", id)
+	}
+
+	if post, ok := p.posts[id]; ok {
+		if post != nil {
+			return invalidf("post already exists. id: %v", id)
+		}
+		return nil
+	}
+
+	_, err := p.storage.Post(ctx, id)
+	switch {
+	case err == nil:
+		return invalidf("post already exists. id: %v", id)
+	case httperr.HTTPStatusCode(err, 0) == http.StatusNotFound:
+		return nil
+	default:
+		return err
+	}
+}
+
+// update checks the change of the stored post or of the post changed by the previous operations
+func (p *planner) update(ctx context.Context, op operation, appliedAt time.Time) (*step, error) {
+	if op.Post == nil {
+		return nil, invalidf("post is required")
+	}
+
+	before, err := p.post(ctx, op.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	after := *before
+	fields, err := p.setFields(ctx, &after, before, op.Post, appliedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, invalidf("update should set a post field")
+	}
+
+	next := after
+	next.Version++
+	p.posts[op.PostID] = &next
+	return &step{op: OpUpdate, id: op.PostID, version: before.Version, before: before, after: &after, fields: fields}, nil
+}
+
+func (p *planner) delete(ctx context.Context, op operation) (*step, error) {
+	if op.Post != nil {
+		return nil, invalidf("delete should not set a post")
+	}
+
+	before, err := p.post(ctx, op.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.posts[op.PostID] = nil
+	return &step{op: OpDelete, id: op.PostID, version: before.Version, before: before}, nil
+}
+
+// post returns the post as the previous operations leave it
+func (p *planner) post(ctx context.Context, id domain.PostId) (*domain.Post, error) {
+	if id == 0 {
+		return nil, invalidf("post_id is required")
+	}
+
+	if post, ok := p.posts[id]; ok {
+		if post == nil {
+			return nil, invalidf("post is deleted by a previous operation. id: %v", id)
+		}
+		return post, nil
+	}
+
+	post, err := p.storage.Post(ctx, id)
+	if httperr.HTTPStatusCode(err, 0) == http.StatusNotFound {
+		return nil, invalidf("post is not found. id: %v", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.posts[id] = post
+	return post, nil
+}
+
+// setFields sets the present fields on the post and returns their names. stored is nil for a new post.
+// A post which goes live gets the time the migration is applied at unless the publish time is set.
+func (p *planner) setFields(ctx context.Context, post, stored *domain.Post, fields *postFields, appliedAt time.Time) ([]string, error) {
+	var names []string
+	if fields.Title != nil {
+		post.Title = *fields.Title
+		names = append(names, "title")
+	}
+	if fields.Content != nil {
+		post.Content = *fields.Content
+		names = append(names, "content")
+	}
+	if strings.TrimSpace(post.Title) == "" || strings.TrimSpace(post.Content) == "" {
+		return nil, invalidf("title and content are required")
+	}
+
+	if fields.Author != nil {
+		id, err := p.authors.lookup(ctx, *fields.Author)
+		if err != nil {
+			return nil, err
+		}
+		post.AuthorID, post.Author = id, *fields.Author
+		names = append(names, "author")
+	}
+
+	if fields.Tags != nil {
+		post.Tags = domain.NormalizeTags(*fields.Tags)
+		names = append(names, "tags")
+	}
+
+	if fields.Status != nil {
+		if !fields.Status.IsValid() {
+			return nil, invalidf("status '%s' should be one of draft, published, scheduled or archived", *fields.Status)
+		}
+		post.Status = *fields.Status
+		names = append(names, "status")
+	}
+
+	if fields.PublishAt != nil {
+		post.PublishAt = fields.PublishAt.UTC()
+		names = append(names, "publish_at")
+	}
+
+	wasLive := stored != nil && (stored.Status == domain.StatusPublished || stored.Status == domain.StatusArchived)
+	switch {
+	case post.Status == domain.StatusDraft:
+		post.PublishAt = time.Time{}
+	case post.Status == domain.StatusScheduled && post.PublishAt.IsZero():
+		return nil, invalidf("publish_at is required for a scheduled post")
+	case post.Status == domain.StatusPublished && !wasLive && fields.PublishAt == nil:
+		post.PublishAt = appliedAt
+	}
+
+	return names, nil
+}
+
+// applier makes the changes of the checked steps
+type applier struct {
+	storage Storage
+	authors *authors
+	// done are the applied steps, they are undone in reverse order
+	done []*step
+}
+
+// apply makes the changes in order, the made changes are undone if a change fails
+func (a *applier) apply(ctx context.Context, steps []*step) error {
+	for _, st := range steps {
+		if err := a.applyStep(ctx, st); err != nil {
+			a.undo()
+			return fmt.Errorf("operation %d failed, the changes are undone. error: %w", st.n, err)
+		}
+		a.done = append(a.done, st)
+	}
+
+	return nil
+}
+
+func (a *applier) applyStep(ctx context.Context, st *step) error {
+	switch st.op {
+	case OpCreate:
+		post := *st.after
+		if err := a.setAuthor(ctx, &post); err != nil {
+			return err
+		}
+
+		if post.ID != 0 {
+			return a.storage.CreatePostWithID(ctx, &post)
+		}
+
+		id, err := a.storage.CreatePost(ctx, &post)
+		if err != nil {
+			return err
+		}
+		st.id = id
+		return nil
+	case OpUpdate:
+		post := *st.after
+		if slices.Contains(st.fields, "author") {
+			if err := a.setAuthor(ctx, &post); err != nil {
+				return err
+			}
+		}
+
+		post.Version = st.version
+		return a.storage.UpdatePost(ctx, &post, st.id)
+	default:
+		deleted, err := a.load(ctx, st.id)
+		if err != nil {
+			return err
+		}
 
-	blogData := struct {
-		Posts []post `json:"Posts"`
-	}{}
-	if err := json.Unmarshal(data, &blogData); err != nil {
+		if err := a.storage.DeletePost(ctx, st.id, st.version); err != nil {
+			return err
+		}
+		st.deleted = deleted
+		return nil
+	}
+}
+
+// load reads the post with its revisions and comments, so the post can be restored
+func (a *applier) load(ctx context.Context, id domain.PostId) (*deletedPost, error) {
+	post, err := a.storage.Post(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := a.storage.Revisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := a.storage.Comments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deletedPost{post: post, revisions: revisions, comments: comments}, nil
+}
+
+// restore creates the deleted post again with its id, version, revisions and comments.
+// The comments get new ids, the replies are linked to the new ids of their parents.
+func (a *applier) restore(ctx context.Context, deleted *deletedPost) error {
+	post := *deleted.post
+	if err := a.storage.CreatePostWithID(ctx, &post); err != nil {
 		return err
 	}
 
-	for _, p := range blogData.Posts {
-		_, err := s.CreatePost(ctx, &domain.Post{
-			ID:      domain.PostId(p.ID),
-			Title:   p.Title,
-			Content: p.Content,
-			Author:  p.Author,
-		})
+	for _, rev := range deleted.revisions {
+		if err := a.storage.AddRevision(ctx, rev); err != nil {
+			return err
+		}
+	}
 
+	// The comments are ordered by id, so a parent is restored before its replies
+	ids := make(map[domain.CommentId]domain.CommentId, len(deleted.comments))
+	for _, c := range deleted.comments {
+		comment := *c
+		comment.ParentID = ids[c.ParentID]
+		id, err := a.storage.CreateComment(ctx, &comment)
 		if err != nil {
-			return fmt.Errorf("can not apply migration from file '%s'. error: %w", filePath, err)
+			return err
 		}
+		ids[c.ID] = id
+	}
+
+	return nil
+}
+
+// setAuthor sets the id of the author created by the migration
+func (a *applier) setAuthor(ctx context.Context, post *domain.Post) error {
+	if post.AuthorID != 0 || post.Author == "" {
+		return nil
+	}
+
+	id, err := a.authors.create(ctx, post.Author)
+	if err != nil {
+		return err
 	}
 
+	post.AuthorID = id
 	return nil
 }
 
-type post struct {
-	ID      int    `json:"ID,omitempty"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Author  string `json:"author"`
+// undo reverts the applied steps. It's not canceled with the context, so the migration is not left half done.
+// The deleted posts are restored with their revisions and comments. The created authors are kept, a rerun uses them.
+func (a *applier) undo() {
+	ctx := context.Background()
+	for i := len(a.done) - 1; i >= 0; i-- {
+		st := a.done[i]
+		var err error
+		switch st.op {
+		case OpCreate:
+			err = a.storage.DeletePost(ctx, st.id, domain.AnyVersion)
+		case OpUpdate:
+			post := *st.before
+			post.Version = domain.AnyVersion
+			err = a.storage.UpdatePost(ctx, &post, st.id)
+		default:
+			err = a.restore(ctx, st.deleted)
+		}
+
+		if err != nil {
+			slog.Error("can not undo migration operation", "operation", st.n, "op", st.op, "id", st.id, "error", err)
+		}
+	}
+
+	a.done = nil
+}
+
+// authors are the placeholder users of the imported authors by their names.
+// Placeholder users have no password, so nobody can log in as them.
+type authors struct {
+	storage Storage
+	// users are the looked up authors, the authors which are not stored yet are nil
+	users map[string]*domain.User
+}
+
+// lookup returns the id of the stored author, it's zero if the author is created on apply
+func (a *authors) lookup(ctx context.Context, name string) (domain.UserId, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	if user, ok := a.users[name]; ok {
+		if user == nil {
+			return 0, nil
+		}
+		return user.ID, nil
+	}
+
+	user, err := a.storage.UserByUsername(ctx, placeholderPrefix+name)
+	switch {
+	case err == nil:
+		a.users[name] = user
+		return user.ID, nil
+	case httperr.HTTPStatusCode(err, 0) == http.StatusNotFound:
+		a.users[name] = nil
+		return 0, nil
+	default:
+		return 0, err
+	}
+}
+
+// create stores the author which is not found on lookup
+func (a *authors) create(ctx context.Context, name string) (domain.UserId, error) {
+	if user := a.users[name]; user != nil {
+		return user.ID, nil
+	}
+
+	user := &domain.User{Username: placeholderPrefix + name, DisplayName: name, CreatedAt: time.Now().UTC()}
+	id, err := a.storage.CreateUser(ctx, user)
+	if err != nil {
+		return 0, fmt.Errorf("can not create author '%s'. error: %w", name, err)
+	}
+
+	user.ID = id
+	a.users[name] = user
+	return id, nil
 }
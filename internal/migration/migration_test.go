@@ -5,10 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/storage"
 	"github.com/voltento/go-blog-project/mocks"
+	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMigration_Apply(t *testing.T) {
@@ -23,24 +32,47 @@ func TestMigration_Apply(t *testing.T) {
 		}{Posts: []post{
 			{ID: 1, Title: "Title 1", Content: "Content 1", Author: "Author 1"},
 			{ID: 2, Title: "Title 2", Content: "Content 2", Author: "Author 2"},
+			{ID: 3, Title: "Title 3", Content: "Content 3", Author: "Author 1"},
 		}}
 
 		filename, clean := tempFileFromData(t, data)
 		defer clean()
 
-		mockStorage.On("CreatePost", ctx, &domain.Post{
-			ID:      domain.PostId(1),
-			Title:   "Title 1",
-			Content: "Content 1",
-			Author:  "Author 1",
-		}).Return(domain.PostId(1), nil).Once()
+		mockStorage.On("MigrationRecord", ctx, mock.Anything).Return(nil, notApplied).Once()
+		mockStorage.On("Post", ctx, mock.Anything).Return(nil, postNotFound)
+		mockStorage.On("UserByUsername", ctx, "imported:Author 1").Return(nil, notFound).Once()
+		mockStorage.On("CreateUser", ctx, placeholder("Author 1")).Return(domain.UserId(1), nil).Once()
+		mockStorage.On("UserByUsername", ctx, "imported:Author 2").Return(&domain.User{ID: 5}, nil).Once()
+		mockStorage.On("AddMigrationRecord", ctx, mock.MatchedBy(func(rec *domain.MigrationRecord) bool {
+			return rec.ID == checksumPrefix+rec.Checksum && rec.Operations == 3
+		})).Return(nil).Once()
 
-		mockStorage.On("CreatePost", ctx, &domain.Post{
-			ID:      domain.PostId(2),
-			Title:   "Title 2",
-			Content: "Content 2",
-			Author:  "Author 2",
-		}).Return(domain.PostId(2), nil).Once()
+		mockStorage.On("CreatePostWithID", ctx, published(&domain.Post{
+			ID:       domain.PostId(1),
+			Title:    "Title 1",
+			Content:  "Content 1",
+			AuthorID: 1,
+			Author:   "Author 1",
+			Status:   domain.StatusPublished,
+		})).Return(nil).Once()
+
+		mockStorage.On("CreatePostWithID", ctx, published(&domain.Post{
+			ID:       domain.PostId(2),
+			Title:    "Title 2",
+			Content:  "Content 2",
+			AuthorID: 5,
+			Author:   "Author 2",
+			Status:   domain.StatusPublished,
+		})).Return(nil).Once()
+
+		mockStorage.On("CreatePostWithID", ctx, published(&domain.Post{
+			ID:       domain.PostId(3),
+			Title:    "Title 3",
+			Content:  "Content 3",
+			AuthorID: 1,
+			Author:   "Author 1",
+			Status:   domain.StatusPublished,
+		})).Return(nil).Once()
 
 		err := migration.Apply(ctx, filename, mockStorage)
 		assert.NoError(t, err)
@@ -74,6 +106,30 @@ func TestMigration_Apply(t *testing.T) {
 		assert.Contains(t, err.Error(), "invalid character")
 	})
 
+	t.Run("create author error", func(t *testing.T) {
+		mockStorage := new(mocks.Storage)
+		migration := &Migration{}
+
+		data := struct {
+			Posts []post `json:"Posts"`
+		}{Posts: []post{{ID: 1, Title: "Title 1", Content: "Content 1", Author: "Author 1"}}}
+
+		filename, clean := tempFileFromData(t, data)
+		defer clean()
+
+		mockStorage.On("MigrationRecord", ctx, mock.Anything).Return(nil, notApplied).Once()
+		mockStorage.On("Post", ctx, mock.Anything).Return(nil, postNotFound)
+		mockStorage.On("UserByUsername", ctx, "imported:Author 1").Return(nil, notFound).Once()
+		mockStorage.On("CreateUser", ctx, placeholder("Author 1")).Return(domain.UserId(0), errors.New("failed to create user")).Once()
+		mockStorage.On("AddMigrationRecord", ctx, mock.Anything).Return(nil).Once()
+		mockStorage.On("DeleteMigrationRecord", context.Background(), mock.Anything).Return(nil).Once()
+
+		err := migration.Apply(ctx, filename, mockStorage)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "can not create author")
+		mockStorage.AssertExpectations(t)
+	})
+
 	t.Run("create post error", func(t *testing.T) {
 		mockStorage := new(mocks.Storage)
 		migration := &Migration{}
@@ -88,12 +144,20 @@ func TestMigration_Apply(t *testing.T) {
 		filename, clean := tempFileFromData(t, data)
 		defer clean()
 
-		mockStorage.On("CreatePost", ctx, &domain.Post{
-			ID:      domain.PostId(1),
-			Title:   "Title 1",
-			Content: "Content 1",
-			Author:  "Author 1",
-		}).Return(domain.PostId(0), errors.New("failed to create post")).Once()
+		mockStorage.On("MigrationRecord", ctx, mock.Anything).Return(nil, notApplied).Once()
+		mockStorage.On("Post", ctx, mock.Anything).Return(nil, postNotFound)
+		mockStorage.On("UserByUsername", ctx, "imported:Author 1").Return(&domain.User{ID: 1}, nil).Once()
+		mockStorage.On("UserByUsername", ctx, "imported:Author 2").Return(&domain.User{ID: 2}, nil).Once()
+		mockStorage.On("AddMigrationRecord", ctx, mock.Anything).Return(nil).Once()
+		mockStorage.On("DeleteMigrationRecord", context.Background(), mock.Anything).Return(nil).Once()
+		mockStorage.On("CreatePostWithID", ctx, published(&domain.Post{
+			ID:       domain.PostId(1),
+			Title:    "Title 1",
+			Content:  "Content 1",
+			AuthorID: 1,
+			Author:   "Author 1",
+			Status:   domain.StatusPublished,
+		})).Return(errors.New("failed to create post")).Once()
 
 		err := migration.Apply(ctx, filename, mockStorage)
 		assert.Error(t, err)
@@ -102,6 +166,28 @@ func TestMigration_Apply(t *testing.T) {
 	})
 }
 
+var notFound = httperr.WrapWithHttpCode(errors.New("user not found"), http.StatusNotFound)
+
+var postNotFound = httperr.WrapWithHttpCode(errors.New("blog not found"), http.StatusNotFound)
+
+var notApplied = httperr.WrapWithHttpCode(errors.New("migration is not applied"), http.StatusNotFound)
+
+// placeholder matches the placeholder user of the imported author
+func placeholder(author string) any {
+	return mock.MatchedBy(func(u *domain.User) bool {
+		return u.Username == "imported:"+author && u.DisplayName == author && u.IsPlaceholder()
+	})
+}
+
+// published matches the post of version 1, it's published at the time the migration is applied
+func published(post *domain.Post) any {
+	return mock.MatchedBy(func(p *domain.Post) bool {
+		unpublished := *p
+		unpublished.PublishAt = time.Time{}
+		return !p.PublishAt.IsZero() && reflect.DeepEqual(post, &unpublished)
+	})
+}
+
 type cleanF func()
 
 func tempFileFromData(t *testing.T, vs any) (string, cleanF) {
@@ -117,3 +203,315 @@ func tempFileFromData(t *testing.T, vs any) (string, cleanF) {
 
 	return tempFile.Name(), func() { _ = os.Remove(tempFile.Name()) }
 }
+
+func writeMigration(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "migration.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// seedStorage returns the storage with posts 1 and 2 written by the imported author Alice
+func seedStorage(t *testing.T) *storage.Storage {
+	ctx := context.Background()
+	s := storage.NewStorage()
+	authorId, err := s.CreateUser(ctx, &domain.User{Username: "imported:Alice", DisplayName: "Alice"})
+	require.NoError(t, err)
+
+	for _, title := range []string{"First", "Second"} {
+		_, err := s.CreatePost(ctx, &domain.Post{Title: title, Content: "Content", AuthorID: authorId, Author: "Alice", Status: domain.StatusPublished})
+		require.NoError(t, err)
+	}
+	return s
+}
+
+const operations = `{
+	"version": 2,
+	"id": "2024-05-cleanup",
+	"operations": [
+		{"op": "create", "post": {"title": "Third", "content": "Content", "author": "Bob", "tags": [" Go ", "go"], "status": "draft"}},
+		{"op": "update", "post_id": 1, "post": {"title": "First, edited", "status": "archived"}},
+		{"op": "delete", "post_id": 2}
+	]
+}`
+
+func TestMigration_Operations(t *testing.T) {
+	ctx := context.Background()
+	s := seedStorage(t)
+	path := writeMigration(t, operations)
+
+	var changes []string
+	m := &Migration{Report: func(change string) { changes = append(changes, change) }}
+	require.NoError(t, m.Apply(ctx, path, s))
+	assert.Equal(t, []string{
+		"create draft post 'Third' by 'Bob'",
+		"update post 1 'First': title, status",
+		"delete post 2 'Second'",
+	}, changes)
+
+	created, err := s.Post(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "Third", created.Title)
+	assert.Equal(t, []string{"go"}, created.Tags)
+	assert.Equal(t, domain.StatusDraft, created.Status)
+	bob, err := s.UserByUsername(ctx, "imported:Bob")
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, created.AuthorID)
+
+	updated, err := s.Post(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "First, edited", updated.Title)
+	assert.Equal(t, "Content", updated.Content, "fields which are not set should be kept")
+	assert.Equal(t, domain.StatusArchived, updated.Status)
+	assert.Equal(t, 2, updated.Version)
+
+	_, err = s.Post(ctx, 2)
+	assert.Equal(t, http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+
+	rec, err := s.MigrationRecord(ctx, "2024-05-cleanup")
+	require.NoError(t, err)
+	assert.Equal(t, 3, rec.Operations)
+
+	t.Run("rerun is a no-op", func(t *testing.T) {
+		changes = nil
+		require.NoError(t, m.Apply(ctx, path, s))
+		require.Len(t, changes, 1)
+		assert.Contains(t, changes[0], "is applied already")
+
+		posts, err := s.Posts(ctx)
+		require.NoError(t, err)
+		assert.Len(t, posts, 2)
+	})
+
+	t.Run("changed file with the applied id", func(t *testing.T) {
+		changed := writeMigration(t, strings.Replace(operations, "Third", "Fourth", 1))
+		err := m.Apply(ctx, changed, s)
+		assert.Equal(t, http.StatusConflict, httperr.HTTPStatusCode(err, -1))
+	})
+}
+
+func TestMigration_DryRun(t *testing.T) {
+	ctx := context.Background()
+	s := seedStorage(t)
+
+	var changes []string
+	m := &Migration{DryRun: true, Report: func(change string) { changes = append(changes, change) }}
+	require.NoError(t, m.Apply(ctx, writeMigration(t, operations), s))
+	assert.Len(t, changes, 3)
+
+	posts, err := s.Posts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+	post, err := s.Post(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "First", post.Title)
+
+	_, err = s.UserByUsername(ctx, "imported:Bob")
+	assert.Equal(t, http.StatusNotFound, httperr.HTTPStatusCode(err, -1), "dry run should not create authors")
+	_, err = s.MigrationRecord(ctx, "2024-05-cleanup")
+	assert.Equal(t, http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func TestMigration_InvalidOperations(t *testing.T) {
+	ctx := context.Background()
+	s := seedStorage(t)
+	path := writeMigration(t, `{"version": 2, "operations": [
+		{"op": "create", "post": {"title": "New", "content": "Content"}},
+		{"op": "create", "post": {"title": " ", "content": "Content"}},
+		{"op": "update", "post_id": 1, "post": {}},
+		{"op": "update", "post_id": 9, "post": {"title": "Missing"}},
+		{"op": "delete", "post_id": 2},
+		{"op": "delete", "post_id": 2},
+		{"op": "create", "post": {"title": "New", "content": "Content", "status": "scheduled"}},
+		{"op": "create", "post": {"title": "New", "content": "Content", "status": "hidden"}},
+		{"op": "rename", "post_id": 1}
+	]}`)
+
+	err := (&Migration{}).Apply(ctx, path, s)
+	require.Error(t, err)
+	for _, msg := range []string{
+		"operation 2: title and content are required",
+		"operation 3: update should set a post field",
+		"operation 4: post is not found. id: 9",
+		"operation 6: post is deleted by a previous operation. id: 2",
+		"operation 7: publish_at is required for a scheduled post",
+		"operation 8: status 'hidden' should be one of",
+		"operation 9: op 'rename' should be one of",
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+	assert.NotContains(t, err.Error(), "operation 1:")
+	assert.NotContains(t, err.Error(), "operation 5:")
+
+	posts, err := s.Posts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, posts, 2, "invalid migration should change nothing")
+}
+
+func TestMigration_Versions(t *testing.T) {
+	ctx := context.Background()
+
+	for name, content := range map[string]string{
+		"unknown version":         `{"version": 3, "operations": []}`,
+		"operations of version 1": `{"operations": [{"op": "delete", "post_id": 1}]}`,
+		"posts of version 2":      `{"version": 2, "posts": [{"title": "Title", "content": "Content"}]}`,
+		"unknown field":           `{"version": 2, "operations": [{"op": "delete", "post_id": 1, "force": true}]}`,
+		"several migrations":      `{"version": 2} {"version": 2}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := (&Migration{}).Apply(ctx, writeMigration(t, content), new(mocks.Storage))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// failingStorage fails to delete the posts
+type failingStorage struct {
+	*storage.Storage
+}
+
+func (s *failingStorage) DeletePost(ctx context.Context, id domain.PostId, version int) error {
+	if version != domain.AnyVersion {
+		return errors.New("storage is down")
+	}
+	return s.Storage.DeletePost(ctx, id, version)
+}
+
+func TestMigration_Undo(t *testing.T) {
+	ctx := context.Background()
+	s := seedStorage(t)
+
+	err := (&Migration{}).Apply(ctx, writeMigration(t, operations), &failingStorage{Storage: s})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "operation 3 failed, the changes are undone")
+
+	posts, err := s.Posts(ctx)
+	require.NoError(t, err)
+	require.Len(t, posts, 2, "created post should be deleted")
+
+	post, err := s.Post(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "First", post.Title)
+	assert.Equal(t, domain.StatusPublished, post.Status)
+
+	_, err = s.MigrationRecord(ctx, "2024-05-cleanup")
+	assert.Equal(t, http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+// failingCreateStorage fails to create the posts without ids
+type failingCreateStorage struct {
+	*storage.Storage
+}
+
+func (s *failingCreateStorage) CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error) {
+	return 0, errors.New("storage is down")
+}
+
+func TestMigration_UndoDelete(t *testing.T) {
+	ctx := context.Background()
+	s := seedStorage(t)
+	require.NoError(t, s.UpdatePost(ctx, &domain.Post{Title: "Second, edited", Content: "Content", Status: domain.StatusPublished}, 2))
+	require.NoError(t, s.AddRevision(ctx, &domain.Revision{PostID: 2, Number: 2, Title: "Second, edited", Content: "Content"}))
+	parentId, err := s.CreateComment(ctx, &domain.Comment{PostID: 2, Author: "Bob", Content: "Comment"})
+	require.NoError(t, err)
+	_, err = s.CreateComment(ctx, &domain.Comment{PostID: 2, ParentID: parentId, Author: "Alice", Content: "Reply"})
+	require.NoError(t, err)
+	deleted, err := s.Post(ctx, 2)
+	require.NoError(t, err)
+
+	path := writeMigration(t, `{"version": 2, "operations": [
+		{"op": "delete", "post_id": 2},
+		{"op": "create", "post": {"title": "Third", "content": "Content"}}
+	]}`)
+	err = (&Migration{}).Apply(ctx, path, &failingCreateStorage{Storage: s})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "operation 2 failed, the changes are undone")
+
+	restored, err := s.Post(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, deleted, restored, "post should be restored with its version and times")
+
+	revisions, err := s.Revisions(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "Second, edited", revisions[0].Title)
+
+	comments, err := s.Comments(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, "Comment", comments[0].Content)
+	assert.Equal(t, "Reply", comments[1].Content)
+	assert.Equal(t, comments[0].ID, comments[1].ParentID, "reply should be linked to the restored comment")
+
+	require.NoError(t, s.UpdatePost(ctx, &domain.Post{Title: "Second", Content: "Content", Version: 2}, 2))
+	require.NoError(t, s.AddRevision(ctx, &domain.Revision{PostID: 2, Number: 3}), "revisions should continue after the restored ones")
+}
+
+// racingStorage lets another instance apply the migration between the ledger check and the ledger update
+type racingStorage struct {
+	*storage.Storage
+}
+
+func (s *racingStorage) AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error {
+	other := *rec
+	if err := s.Storage.AddMigrationRecord(ctx, &other); err != nil {
+		return err
+	}
+	return s.Storage.AddMigrationRecord(ctx, rec)
+}
+
+func TestMigration_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	s := seedStorage(t)
+
+	var changes []string
+	m := &Migration{Report: func(change string) { changes = append(changes, change) }}
+	require.NoError(t, m.Apply(ctx, writeMigration(t, operations), &racingStorage{Storage: s}))
+	assert.Contains(t, changes[len(changes)-1], "is applied already")
+
+	posts, err := s.Posts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, posts, 2, "migration applied by another instance should not be applied again")
+	post, err := s.Post(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "First", post.Title)
+}
+
+func TestMigration_ExplicitIDs(t *testing.T) {
+	ctx := context.Background()
+	s := seedStorage(t)
+	path := writeMigration(t, `{"version": 2, "operations": [
+		{"op": "create", "post_id": 40, "post": {"title": "Linked", "content": "See post 41"}},
+		{"op": "create", "post_id": 41, "post": {"title": "Target", "content": "Content"}},
+		{"op": "update", "post_id": 40, "post": {"tags": ["links"]}},
+		{"op": "delete", "post_id": 2},
+		{"op": "create", "post_id": 2, "post": {"title": "Second, recreated", "content": "Content"}}
+	]}`)
+
+	require.NoError(t, (&Migration{}).Apply(ctx, path, s))
+
+	for id, title := range map[domain.PostId]string{40: "Linked", 41: "Target", 2: "Second, recreated"} {
+		post, err := s.Post(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, title, post.Title)
+	}
+	linked, err := s.Post(ctx, 40)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"links"}, linked.Tags)
+
+	id, err := s.CreatePost(ctx, &domain.Post{Title: "Next", Content: "Content"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostId(42), id, "new posts should get ids after the imported ones")
+
+	t.Run("taken id", func(t *testing.T) {
+		path := writeMigration(t, `{"version": 2, "operations": [
+			{"op": "create", "post_id": 1, "post": {"title": "Clash", "content": "Content"}},
+			{"op": "create", "post_id": 50, "post": {"title": "New", "content": "Content"}},
+			{"op": "create", "post_id": 50, "post": {"title": "Repeated", "content": "Content"}}
+		]}`)
+
+		err := (&Migration{}).Apply(ctx, path, s)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "operation 1: post already exists. id: 1")
+		assert.Contains(t, err.Error(), "operation 3: post already exists. id: 50")
+	})
+}
@@ -0,0 +1,34 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+//go:embed docs.html
+var docsPage string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// DocsHandler serves Swagger UI showing the document at specURL. The page is served at the root of the handler
+// and the UI files next to it, so the handler is mounted with http.StripPrefix.
+func DocsHandler(title, specURL string) http.Handler {
+	var page bytes.Buffer
+	if err := docsTemplate.Execute(&page, struct{ Title, SpecURL string }{title, specURL}); err != nil {
+		panic(err)
+	}
+
+	files := http.FileServer(http.FS(swaggerFiles.FS))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || r.URL.Path == "" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(page.Bytes())
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// SchemaOf returns the schema of the value the way encoding/json renders it.
+// The named struct types are added to the component schemas and referenced, so the schema of a type is changed
+// in the components. The fields with `binding:"required"` are required, the min and max rules of the string
+// fields are their lengths and the slices are nullable as a nil slice is rendered as null.
+// It panics on the types JSON can not render.
+func (d *Document) SchemaOf(v any) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+// Define sets the schema of the type of the value, it's used for the type wherever it's reflected.
+// The types rendering themselves to JSON should be defined before they are reflected.
+func (d *Document) Define(v any, s *Schema) {
+	if d.defined == nil {
+		d.defined = map[reflect.Type]*Schema{}
+	}
+	d.defined[reflect.TypeOf(v)] = s
+}
+
+// Component returns the component schema reflected from the value, so its fields can be described
+func (d *Document) Component(v any) *Schema {
+	return d.Resolve(d.SchemaOf(v))
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if s, ok := d.defined[t]; ok {
+		// The reflected schemas are changed by the callers, so every field has its own copy
+		cp := *s
+		return &cp
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		panic(fmt.Sprintf("openapi: type %v renders itself to JSON, its schema should be set with Define", t))
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}, Minimum: ptr(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string", "null"}, Format: "byte"}
+		}
+		return &Schema{Type: Types{"array", "null"}, Items: d.schemaOf(t.Elem())}
+	case reflect.Array:
+		return &Schema{Type: Types{"array"}, Items: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return d.component(t)
+	default:
+		panic(fmt.Sprintf("openapi: type %v can not be rendered to JSON", t))
+	}
+}
+
+// component adds the schema of the named struct to the components and returns the reference to it
+func (d *Document) component(t reflect.Type) *Schema {
+	name := componentName(t)
+	if known, ok := d.types[name]; ok {
+		if known != t {
+			panic(fmt.Sprintf("openapi: types %v and %v have the same schema name %s", known, t, name))
+		}
+		return Ref(name)
+	}
+
+	if d.types == nil {
+		d.types = map[string]reflect.Type{}
+	}
+	if d.Components.Schemas == nil {
+		d.Components.Schemas = map[string]*Schema{}
+	}
+	// The type is known before its fields are reflected, so the recursive types reference themselves
+	d.types[name] = t
+	d.Components.Schemas[name] = d.structSchema(t)
+	return Ref(name)
+}
+
+// componentName is the name of the type starting with an upper-case letter
+func componentName(t reflect.Type) string {
+	r, size := utf8.DecodeRuneInString(t.Name())
+	return string(unicode.ToUpper(r)) + t.Name()[size:]
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+	d.addFields(s, t)
+	return s
+}
+
+// addFields adds the fields of the struct to the object schema.
+// The fields of the embedded structs are added as encoding/json does.
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				d.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		prop := d.schemaOf(f.Type)
+		s.Properties[name] = prop
+		for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+			if rule == "required" {
+				s.Required = append(s.Required, name)
+			}
+			if f.Type.Kind() == reflect.String {
+				prop.MinLength = ruleParam(rule, "min", prop.MinLength)
+				prop.MaxLength = ruleParam(rule, "max", prop.MaxLength)
+			}
+		}
+	}
+}
+
+// ruleParam returns the number the binding rule of the name is parametrized with, e.g. 200 of max=200,
+// or the value if the rule has another name
+func ruleParam(rule, name string, value *int) *int {
+	param, ok := strings.CutPrefix(rule, name+"=")
+	if !ok {
+		return value
+	}
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("openapi: binding rule %s should have a number", rule))
+	}
+	return &n
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,95 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type base struct {
+	ID        int
+	CreatedAt time.Time
+}
+
+type node struct {
+	*base
+	Name     string   `json:"name" binding:"required,min=2,max=10"`
+	Note     string   `json:"note,omitempty"`
+	Hidden   string   `json:"-"`
+	Tags     []string `json:"tags"`
+	Children []*node  `json:"children"`
+	Weight   float64
+	Visible  bool
+	Count    uint
+	secret   string
+}
+
+func TestSchemaOf(t *testing.T) {
+	var d Document
+	ref := d.SchemaOf(&node{})
+	assert.Equal(t, "#/components/schemas/Node", ref.Ref)
+
+	data, err := json.Marshal(d.Components.Schemas)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Node": {
+		"type": "object",
+		"properties": {
+			"ID": {"type": "integer"},
+			"CreatedAt": {"type": "string", "format": "date-time"},
+			"name": {"type": "string", "minLength": 2, "maxLength": 10},
+			"note": {"type": "string"},
+			"tags": {"type": ["array", "null"], "items": {"type": "string"}},
+			"children": {"type": ["array", "null"], "items": {"$ref": "#/components/schemas/Node"}},
+			"Weight": {"type": "number"},
+			"Visible": {"type": "boolean"},
+			"Count": {"type": "integer", "minimum": 0}
+		},
+		"required": ["name"]
+	}}`, string(data))
+
+	assert.Same(t, d.Components.Schemas["Node"], d.Component(node{}), "the type should be reflected once")
+}
+
+type other struct{}
+
+func TestSchemaOf_NameTaken(t *testing.T) {
+	var d Document
+	d.SchemaOf(other{})
+
+	type Other struct{}
+	assert.Panics(t, func() { d.SchemaOf(Other{}) })
+}
+
+func TestSchemaOf_Unsupported(t *testing.T) {
+	var d Document
+	assert.Panics(t, func() { d.SchemaOf(struct{ C chan int }{}) })
+	assert.Panics(t, func() { d.SchemaOf(json.RawMessage{}) }, "the types rendering themselves should be described by hand")
+}
+
+func TestSchemaOf_Defined(t *testing.T) {
+	var d Document
+	d.Define(json.RawMessage{}, &Schema{Type: Types{"object"}})
+
+	s := d.SchemaOf(struct {
+		A json.RawMessage
+		B json.RawMessage
+	}{})
+	assert.Equal(t, Types{"object"}, s.Properties["A"].Type)
+
+	s.Properties["A"].Description = "changed"
+	assert.Empty(t, s.Properties["B"].Description, "every field should have its own schema")
+}
+
+func TestTypes_JSON(t *testing.T) {
+	data, err := json.Marshal(&Schema{Type: Types{"string"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "string"}`, string(data))
+
+	var s Schema
+	require.NoError(t, json.Unmarshal([]byte(`{"type": ["array", "null"]}`), &s))
+	assert.Equal(t, Types{"array", "null"}, s.Type)
+	assert.True(t, s.Type.Has("null"))
+}
@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+// Validate checks the value decoded from JSON with json.Decoder.UseNumber against the schema.
+// It returns the fields not matching the schema named by their path, e.g. tags[1], the value itself is named by field.
+func (d *Document) Validate(field string, s *Schema, v any) []httperr.FieldError {
+	var fields []httperr.FieldError
+	d.validate(&fields, field, s, v)
+	return fields
+}
+
+// ValidateParameter checks the values of the parameter sent as strings, an integer parameter should be a number.
+// The parameter with no values is only checked to be present if it's required.
+func (d *Document) ValidateParameter(p *Parameter, values []string) []httperr.FieldError {
+	if len(values) == 0 {
+		if p.Required {
+			return []httperr.FieldError{{Field: p.Name, Message: "is required"}}
+		}
+		return nil
+	}
+
+	s := d.Resolve(p.Schema)
+	if s == nil {
+		return nil
+	}
+	if s.Type.Has("array") {
+		items := make([]any, 0, len(values))
+		for _, v := range values {
+			items = append(items, parameterValue(d.Resolve(s.Items), v))
+		}
+		return d.Validate(p.Name, s, items)
+	}
+	return d.Validate(p.Name, s, parameterValue(s, values[0]))
+}
+
+// parameterValue is the value of the parameter as it's decoded from JSON
+func parameterValue(s *Schema, v string) any {
+	if s != nil && (s.Type.Has("integer") || s.Type.Has("number")) {
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	}
+	return v
+}
+
+func (d *Document) validate(fields *[]httperr.FieldError, field string, s *Schema, v any) {
+	s = d.Resolve(s)
+	if s == nil {
+		return
+	}
+	fail := func(format string, args ...any) {
+		*fields = append(*fields, httperr.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, sub := range s.AllOf {
+		d.validate(fields, field, sub, v)
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(v, t) }) {
+		if str, ok := v.(string); ok {
+			fail("should be %s, got '%s'", typeNames(s.Type), str)
+		} else {
+			fail("should be %s", typeNames(s.Type))
+		}
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		fail("should be %s, got '%v'", oneOf(s.Enum), v)
+		return
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		switch {
+		case s.MinLength != nil && n < *s.MinLength:
+			fail("should be at least %d characters long", *s.MinLength)
+		case s.MaxLength != nil && n > *s.MaxLength:
+			fail("should be at most %d characters long", *s.MaxLength)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				fail("should be a time in RFC 3339 format")
+			}
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		switch {
+		case s.Minimum != nil && n < float64(*s.Minimum):
+			fail("should be at least %d, got '%s'", *s.Minimum, v)
+		case s.Maximum != nil && n > float64(*s.Maximum):
+			fail("should be at most %d, got '%s'", *s.Maximum, v)
+		}
+	case []any:
+		for i, item := range v {
+			d.validate(fields, fmt.Sprintf("%s[%d]", field, i), s.Items, item)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*fields = append(*fields, httperr.FieldError{Field: join(field, name), Message: "is required"})
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			prop, known := s.Properties[name]
+			switch {
+			case known:
+				d.validate(fields, join(field, name), prop, v[name])
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				*fields = append(*fields, httperr.FieldError{Field: join(field, name), Message: "is not a known field"})
+			}
+		}
+	}
+}
+
+// hasType reports whether the decoded JSON value is of the JSON Schema type
+func hasType(v any, typ string) bool {
+	switch v := v.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case json.Number:
+		if typ == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return typ == "number"
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	default:
+		return false
+	}
+}
+
+var typeArticles = map[string]string{
+	"boolean": "a boolean",
+	"string":  "a string",
+	"integer": "an integer",
+	"number":  "a number",
+	"array":   "an array",
+	"object":  "an object",
+	"null":    "null",
+}
+
+// typeNames names the types for a message, null is not named if the value may have another type
+func typeNames(types Types) string {
+	var names []string
+	for _, t := range types {
+		if t != "null" || len(types) == 1 {
+			names = append(names, typeArticles[t])
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// oneOf lists the values for a message, e.g. "one of draft, published or archived"
+func oneOf(values []any) string {
+	names := make([]string, 0, len(values))
+	for _, v := range values {
+		names = append(names, fmt.Sprint(v))
+	}
+	if len(names) == 1 {
+		return "'" + names[0] + "'"
+	}
+	return "one of " + strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
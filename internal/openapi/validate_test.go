@@ -0,0 +1,99 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+type article struct {
+	Title     string   `json:"title" binding:"required,max=5"`
+	Tags      []string `json:"tags"`
+	Status    string   `json:"status"`
+	Rating    int      `json:"rating"`
+	PublishAt string   `json:"publish_at"`
+}
+
+func newArticleDocument() (*Document, *Schema) {
+	var d Document
+	ref := d.SchemaOf(article{})
+	s := d.Resolve(ref)
+	s.AdditionalProperties = ptr(false)
+	s.Properties["status"].Enum = []any{"draft", "published"}
+	s.Properties["rating"].Minimum, s.Properties["rating"].Maximum = ptr(1), ptr(5)
+	s.Properties["publish_at"].Format = "date-time"
+	return &d, ref
+}
+
+func decode(t *testing.T, data string) any {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	require.NoError(t, dec.Decode(&v))
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []httperr.FieldError
+	}{
+		{"Valid", `{"title": "Go", "tags": ["go", "web"], "status": "draft", "rating": 5, "publish_at": "2024-05-01T10:00:00Z"}`, nil},
+		{"Null Tags", `{"title": "Go", "tags": null}`, nil},
+		{"Missing Title", `{"tags": []}`, []httperr.FieldError{{Field: "title", Message: "is required"}}},
+		{"Long Title", `{"title": "Golang"}`, []httperr.FieldError{{Field: "title", Message: "should be at most 5 characters long"}}},
+		{"Title Counted In Characters", `{"title": "Привет"}`, []httperr.FieldError{{Field: "title", Message: "should be at most 5 characters long"}}},
+		{"Unknown Field", `{"title": "Go", "titel": "Go"}`, []httperr.FieldError{{Field: "titel", Message: "is not a known field"}}},
+		{"Wrong Type", `{"title": 1}`, []httperr.FieldError{{Field: "title", Message: "should be a string"}}},
+		{"Wrong Item", `{"title": "Go", "tags": ["go", 1]}`, []httperr.FieldError{{Field: "tags[1]", Message: "should be a string"}}},
+		{"Not In Enum", `{"title": "Go", "status": "hidden"}`, []httperr.FieldError{{Field: "status", Message: "should be one of draft or published, got 'hidden'"}}},
+		{"Not Integer", `{"title": "Go", "rating": 1.5}`, []httperr.FieldError{{Field: "rating", Message: "should be an integer"}}},
+		{"Out Of Range", `{"title": "Go", "rating": 6}`, []httperr.FieldError{{Field: "rating", Message: "should be at most 5, got '6'"}}},
+		{"Wrong Format", `{"title": "Go", "publish_at": "tomorrow"}`, []httperr.FieldError{{Field: "publish_at", Message: "should be a time in RFC 3339 format"}}},
+		{
+			"Several Fields",
+			`{"status": "hidden", "extra": true}`,
+			[]httperr.FieldError{
+				{Field: "title", Message: "is required"},
+				{Field: "extra", Message: "is not a known field"},
+				{Field: "status", Message: "should be one of draft or published, got 'hidden'"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, s := newArticleDocument()
+			assert.Equal(t, tt.expected, d.Validate("", s, decode(t, tt.body)))
+		})
+	}
+}
+
+func TestValidate_AllOf(t *testing.T) {
+	d, s := newArticleDocument()
+	extended := &Schema{AllOf: []*Schema{s, {Type: Types{"object"}, Required: []string{"rating"}}}}
+
+	fields := d.Validate("", extended, decode(t, `{"title": "Go"}`))
+	assert.Equal(t, []httperr.FieldError{{Field: "rating", Message: "is required"}}, fields)
+}
+
+func TestValidateParameter(t *testing.T) {
+	var d Document
+	limit := &Parameter{Name: "limit", In: "query", Schema: &Schema{Type: Types{"integer"}, Minimum: ptr(1), Maximum: ptr(100)}}
+	tags := &Parameter{Name: "tag", In: "query", Schema: &Schema{Type: Types{"array"}, Items: &Schema{Type: Types{"string"}}}}
+	id := &Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: Types{"integer"}}}
+
+	assert.Empty(t, d.ValidateParameter(limit, nil))
+	assert.Empty(t, d.ValidateParameter(limit, []string{"20"}))
+	assert.Empty(t, d.ValidateParameter(tags, []string{"go", "web"}))
+	assert.Empty(t, d.ValidateParameter(id, []string{"1"}))
+
+	assert.Equal(t, []httperr.FieldError{{Field: "limit", Message: "should be an integer, got 'many'"}}, d.ValidateParameter(limit, []string{"many"}))
+	assert.Equal(t, []httperr.FieldError{{Field: "limit", Message: "should be at least 1, got '0'"}}, d.ValidateParameter(limit, []string{"0"}))
+	assert.Equal(t, []httperr.FieldError{{Field: "id", Message: "is required"}}, d.ValidateParameter(id, nil))
+}
@@ -0,0 +1,146 @@
+// Package search implements full-text search over posts.
+// Posts are kept in an inverted index of stemmed terms and matches are ranked with Okapi BM25.
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+const (
+	// BM25 parameters, the common defaults are used
+	k1 = 1.2
+	b  = 0.75
+
+	// titleWeight is how many times a title term counts more than a content term
+	titleWeight = 2
+)
+
+// Hit is a post matching a query
+type Hit struct {
+	ID    domain.PostId
+	Score float64
+}
+
+// Index is an inverted index of posts title and content. It's safe for concurrent use.
+type Index struct {
+	mtx sync.RWMutex
+	// postings maps a term to frequencies of the term in the posts
+	postings    map[string]map[domain.PostId]int
+	docs        map[domain.PostId]document
+	totalLength int
+}
+
+type document struct {
+	length int
+	terms  map[string]int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: map[string]map[domain.PostId]int{},
+		docs:     map[domain.PostId]document{},
+	}
+}
+
+// Add indexes the post replacing the previously indexed version of it
+func (idx *Index) Add(post *domain.Post) {
+	doc := document{terms: map[string]int{}}
+	for _, t := range tokenize(post.Title) {
+		doc.terms[t.term] += titleWeight
+		doc.length += titleWeight
+	}
+	for _, t := range tokenize(post.Content) {
+		doc.terms[t.term]++
+		doc.length++
+	}
+
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	idx.remove(post.ID)
+	for term, freq := range doc.terms {
+		posting, ok := idx.postings[term]
+		if !ok {
+			posting = map[domain.PostId]int{}
+			idx.postings[term] = posting
+		}
+		posting[post.ID] = freq
+	}
+	idx.docs[post.ID] = doc
+	idx.totalLength += doc.length
+}
+
+// Remove drops the post from the index
+func (idx *Index) Remove(id domain.PostId) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id domain.PostId) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		posting := idx.postings[term]
+		delete(posting, id)
+		if len(posting) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+	idx.totalLength -= doc.length
+}
+
+// Search returns up to limit posts matching any term of the query, the most relevant go first
+func (idx *Index) Search(query string, limit int) []Hit {
+	idx.mtx.RLock()
+	defer idx.mtx.RUnlock()
+
+	if len(idx.docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.docs))
+	avgLength := float64(idx.totalLength) / n
+
+	scores := map[domain.PostId]float64{}
+	for _, term := range terms(query) {
+		posting := idx.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, freq := range posting {
+			tf := float64(freq)
+			docLength := float64(idx.docs[id].length)
+			scores[id] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*docLength/avgLength))
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
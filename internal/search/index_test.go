@@ -0,0 +1,80 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+func hitIds(hits []Hit) []domain.PostId {
+	ids := make([]domain.PostId, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+func newTestIndex() *Index {
+	idx := NewIndex()
+	idx.Add(&domain.Post{ID: 1, Title: "Cooking pasta", Content: "Boil the water, add salt and cook the pasta."})
+	idx.Add(&domain.Post{ID: 2, Title: "Go concurrency", Content: "Goroutines and channels make concurrent programs simple."})
+	idx.Add(&domain.Post{ID: 3, Title: "Channels", Content: "A post about TV channels, not about Go."})
+	return idx
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := newTestIndex()
+
+	t.Run("Ranked by relevance", func(t *testing.T) {
+		assert.Equal(t, []domain.PostId{2, 3}, hitIds(idx.Search("concurrent channels", 10)))
+	})
+
+	t.Run("Title match outranks content match", func(t *testing.T) {
+		assert.Equal(t, []domain.PostId{3, 2}, hitIds(idx.Search("channel", 10)))
+	})
+
+	t.Run("Stemmed terms match", func(t *testing.T) {
+		assert.Equal(t, []domain.PostId{1}, hitIds(idx.Search("Cooked", 10)))
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		assert.Len(t, idx.Search("channels", 1), 1)
+	})
+
+	t.Run("No matches", func(t *testing.T) {
+		assert.Empty(t, idx.Search("rust", 10))
+		assert.Empty(t, idx.Search("", 10))
+	})
+
+	t.Run("Scores are positive and descending", func(t *testing.T) {
+		hits := idx.Search("go channels", 10)
+		assert.NotEmpty(t, hits)
+		for i, h := range hits {
+			assert.Positive(t, h.Score)
+			if i > 0 {
+				assert.GreaterOrEqual(t, hits[i-1].Score, h.Score)
+			}
+		}
+	})
+}
+
+func TestIndex_Update(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Add(&domain.Post{ID: 1, Title: "Baking bread", Content: "Flour, water and yeast."})
+
+	assert.Empty(t, idx.Search("pasta", 10), "old content should not be found")
+	assert.Equal(t, []domain.PostId{1}, hitIds(idx.Search("bread", 10)))
+}
+
+func TestIndex_Remove(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Remove(3)
+	idx.Remove(100)
+
+	assert.Equal(t, []domain.PostId{2}, hitIds(idx.Search("channels", 10)))
+	assert.NotContains(t, idx.postings, "tv", "terms of removed posts should be dropped")
+	assert.Len(t, idx.docs, 2)
+}
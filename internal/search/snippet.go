@@ -0,0 +1,64 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	// snippetWords is the max number of words in a snippet
+	snippetWords = 30
+	// snippetLeadingWords is the number of words shown before the first match
+	snippetLeadingWords = 8
+
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	ellipsis       = "…"
+)
+
+// Snippet returns a fragment of the text around the first word matching the query.
+// The text is HTML-escaped and the matching words are wrapped into <mark> tags.
+func Snippet(text, query string) string {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	queryTerms := map[string]bool{}
+	for _, term := range terms(query) {
+		queryTerms[term] = true
+	}
+
+	first := 0
+	for i, t := range tokens {
+		if queryTerms[t.term] {
+			first = max(0, i-snippetLeadingWords)
+			break
+		}
+	}
+	last := min(len(tokens), first+snippetWords) - 1
+
+	var sb strings.Builder
+	pos := 0
+	if first > 0 {
+		sb.WriteString(ellipsis)
+		pos = tokens[first].start
+	}
+
+	for _, t := range tokens[first : last+1] {
+		sb.WriteString(html.EscapeString(text[pos:t.start]))
+		word := html.EscapeString(text[t.start:t.end])
+		if queryTerms[t.term] {
+			word = highlightStart + word + highlightEnd
+		}
+		sb.WriteString(word)
+		pos = t.end
+	}
+
+	if last < len(tokens)-1 {
+		sb.WriteString(ellipsis)
+	} else {
+		sb.WriteString(html.EscapeString(text[pos:]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,36 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 20)
+
+	tests := []struct {
+		name     string
+		text     string
+		query    string
+		expected string
+	}{
+		{"Highlights matches", "Go channels are typed.", "channel", "Go <mark>channels</mark> are typed."},
+		{"Escapes html", "<b>Channels</b> & more", "channels", "&lt;b&gt;<mark>Channels</mark>&lt;/b&gt; &amp; more"},
+		{"No matches", "Go channels are typed.", "rust", "Go channels are typed."},
+		{"Empty text", "", "go", ""},
+		{
+			"Cuts text around the match",
+			long + "the channel " + long,
+			"channel",
+			"…ipsum lorem ipsum lorem ipsum lorem ipsum the <mark>channel</mark> lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Snippet(tt.text, tt.query))
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package search
+
+import "strings"
+
+// stem reduces an English word to its stem with the Porter stemming algorithm,
+// so different forms of a word like "connected" and "connection" match each other.
+// Words with non-ASCII letters are returned unchanged.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	w := word
+	w = step1a(w)
+	w = step1b(w)
+	w = step1c(w)
+	w = replaceSuffix(w, 0, step2Suffixes)
+	w = replaceSuffix(w, 0, step3Suffixes)
+	w = step4(w)
+	w = step5(w)
+	return w
+}
+
+func isConsonant(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	default:
+		return true
+	}
+}
+
+// measure returns the number of vowel-consonant sequences in w
+func measure(w string) int {
+	m := 0
+	prevVowel := false
+	for i := range w {
+		consonant := isConsonant(w, i)
+		if consonant && prevVowel {
+			m++
+		}
+		prevVowel = !consonant
+	}
+	return m
+}
+
+func hasVowel(w string) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsWithDoubleConsonant(w string) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsWithCVC reports whether w ends with consonant-vowel-consonant and the last consonant is not w, x or y
+func endsWithCVC(w string) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	last := w[n-1]
+	return last != 'w' && last != 'x' && last != 'y'
+}
+
+type suffixRule struct {
+	suffix      string
+	replacement string
+}
+
+// replaceSuffix applies the first rule matching the end of w if the rest of the word has measure above minMeasure
+func replaceSuffix(w string, minMeasure int, rules []suffixRule) string {
+	for _, r := range rules {
+		if !strings.HasSuffix(w, r.suffix) {
+			continue
+		}
+
+		base := w[:len(w)-len(r.suffix)]
+		if measure(base) > minMeasure {
+			return base + r.replacement
+		}
+		return w
+	}
+	return w
+}
+
+func step1a(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func step1b(w string) string {
+	if strings.HasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+
+	var base string
+	switch {
+	case strings.HasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		base = w[:len(w)-2]
+	case strings.HasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		base = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(base, "at"), strings.HasSuffix(base, "bl"), strings.HasSuffix(base, "iz"):
+		return base + "e"
+	case endsWithDoubleConsonant(base) && !strings.HasSuffix(base, "l") && !strings.HasSuffix(base, "s") && !strings.HasSuffix(base, "z"):
+		return base[:len(base)-1]
+	case measure(base) == 1 && endsWithCVC(base):
+		return base + "e"
+	}
+	return base
+}
+
+func step1c(w string) string {
+	if strings.HasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		return w[:len(w)-1] + "i"
+	}
+	return w
+}
+
+var step2Suffixes = []suffixRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"}, {"izer", "ize"},
+	{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"},
+	{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"},
+	{"fulness", "ful"}, {"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var step3Suffixes = []suffixRule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"}, {"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent",
+	"ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func step4(w string) string {
+	// The longest matching suffix is the only one considered
+	longest := ""
+	for _, suffix := range step4Suffixes {
+		if strings.HasSuffix(w, suffix) && len(suffix) > len(longest) {
+			longest = suffix
+		}
+	}
+
+	if longest == "" {
+		return w
+	}
+
+	base := w[:len(w)-len(longest)]
+	if measure(base) <= 1 {
+		return w
+	}
+
+	if longest == "ion" && !strings.HasSuffix(base, "s") && !strings.HasSuffix(base, "t") {
+		return w
+	}
+	return base
+}
+
+func step5(w string) string {
+	if strings.HasSuffix(w, "e") {
+		base := w[:len(w)-1]
+		if m := measure(base); m > 1 || (m == 1 && !endsWithCVC(base)) {
+			w = base
+		}
+	}
+
+	if measure(w) > 1 && endsWithDoubleConsonant(w) && strings.HasSuffix(w, "l") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"cats":        "cat",
+		"feed":        "feed",
+		"agreed":      "agre",
+		"plastered":   "plaster",
+		"motoring":    "motor",
+		"sing":        "sing",
+		"hopping":     "hop",
+		"falling":     "fall",
+		"filing":      "file",
+		"happy":       "happi",
+		"relational":  "relat",
+		"conditional": "condit",
+		"hopefulness": "hope",
+		"electrical":  "electr",
+		"adjustment":  "adjust",
+		"adoption":    "adopt",
+		"controlling": "control",
+		"generalize":  "gener",
+		"connected":   "connect",
+		"connection":  "connect",
+		"connections": "connect",
+		"go":          "go",
+		"привет":      "привет",
+	}
+
+	for word, expected := range tests {
+		t.Run(word, func(t *testing.T) {
+			assert.Equal(t, expected, stem(word))
+		})
+	}
+}
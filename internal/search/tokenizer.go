@@ -0,0 +1,54 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// token is a normalized word with its byte offsets in the source text
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// tokenize splits the text into words of letters and digits.
+// The words are lower-cased and stemmed, so they can be matched against the index terms.
+func tokenize(text string) []token {
+	var tokens []token
+
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWordRune && start < 0:
+			start = i
+		case !isWordRune && start >= 0:
+			tokens = append(tokens, newToken(text, start, i))
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, newToken(text, start, len(text)))
+	}
+
+	return tokens
+}
+
+func newToken(text string, start, end int) token {
+	return token{term: stem(strings.ToLower(text[start:end])), start: start, end: end}
+}
+
+// terms returns the distinct terms of the text in order of appearance
+func terms(text string) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, t := range tokenize(text) {
+		if !seen[t.term] {
+			seen[t.term] = true
+			res = append(res, t.term)
+		}
+	}
+	return res
+}
@@ -0,0 +1,84 @@
+// Package backend opens the storage of the posts picked by the command line flags
+package backend
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/ids"
+	"github.com/voltento/go-blog-project/internal/storage"
+	"github.com/voltento/go-blog-project/internal/storage/filestore"
+	"github.com/voltento/go-blog-project/internal/storage/postgres"
+)
+
+const (
+	Memory   = "memory"
+	Postgres = "postgres"
+	File     = "file"
+)
+
+// Config picks the storage and keeps its settings
+type Config struct {
+	Kind    string
+	DSN     string
+	DataDir string
+	// IDAllocator picks the allocator of post ids, see ids.New
+	IDAllocator string
+	// SnowflakeNode is the node number of the snowflake allocator
+	SnowflakeNode int64
+}
+
+// BindFlags binds the config to the -storage, -dsn, -data-dir, -id-allocator and -snowflake-node flags
+func (c *Config) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Kind, "storage", Memory, "Storage of the posts: memory, postgres or file")
+	fs.StringVar(&c.DSN, "dsn", "", "Data source name of the postgres storage")
+	fs.StringVar(&c.DataDir, "data-dir", "./data", "Directory of the file storage")
+	fs.StringVar(&c.IDAllocator, "id-allocator", ids.Sequential, "Allocator of post ids: sequential, time-ordered or snowflake")
+	fs.Int64Var(&c.SnowflakeNode, "snowflake-node", 0, "Node number of the snowflake id allocator, unique per instance")
+}
+
+// Open creates the storage of the kind. The returned function releases the storage resources.
+func Open(ctx context.Context, cfg Config) (blog.Storage, func(), error) {
+	allocator, err := ids.New(cfg.IDAllocator, cfg.SnowflakeNode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s, closeStorage, err := open(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if allocator != nil {
+		st, ok := s.(interface{ SetIDAllocator(a ids.Allocator) })
+		if !ok {
+			closeStorage()
+			return nil, nil, fmt.Errorf("storage '%s' does not support id allocator '%s'", cfg.Kind, cfg.IDAllocator)
+		}
+		st.SetIDAllocator(allocator)
+	}
+	return s, closeStorage, nil
+}
+
+func open(ctx context.Context, cfg Config) (blog.Storage, func(), error) {
+	switch cfg.Kind {
+	case Memory:
+		return storage.NewStorage(), func() {}, nil
+	case Postgres:
+		s, err := postgres.Open(ctx, cfg.DSN)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not open postgres storage. error: %w", err)
+		}
+		return s, func() { _ = s.Close() }, nil
+	case File:
+		s, err := filestore.Open(cfg.DataDir, filestore.DefaultSnapshotEvery)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not open file storage. error: %w", err)
+		}
+		return s, func() { _ = s.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage '%s'", cfg.Kind)
+	}
+}
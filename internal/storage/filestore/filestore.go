@@ -0,0 +1,499 @@
+// Package filestore implements blog.Storage persisted to a local directory.
+// Every change is appended to a write-ahead log before it's acknowledged, the log is compacted into
+// a snapshot periodically. On start the snapshot is loaded and the log records made after it are replayed.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/storage"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+
+	// DefaultSnapshotEvery is the number of log records which triggers a snapshot
+	DefaultSnapshotEvery = 1000
+)
+
+// Storage serves reads from the in-memory storage and persists the changes to the disk.
+// The in-memory storage keeps the id sequencing rules, so they are the same for both storages.
+type Storage struct {
+	*storage.Storage
+
+	// mtx serializes the changes, so the log order matches the order they are applied in memory
+	mtx           sync.Mutex
+	dir           string
+	wal           *wal
+	lsn           uint64
+	snapshotLSN   uint64
+	snapshotEvery int
+}
+
+// Open loads the storage kept in dir creating the directory if needed.
+// A snapshot is taken after every snapshotEvery changes.
+func Open(dir string, snapshotEvery int) (*Storage, error) {
+	if snapshotEvery < 1 {
+		return nil, fmt.Errorf("snapshot interval should be positive, got %d", snapshotEvery)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		return nil, fmt.Errorf("can not read snapshot. error: %w", err)
+	}
+
+	w, records, err := openWal(filepath.Join(dir, walFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	state, lsn := replay(snap, records)
+	return &Storage{
+		Storage:       storage.NewStorageFromState(state),
+		dir:           dir,
+		wal:           w,
+		lsn:           lsn,
+		snapshotLSN:   snap.LSN,
+		snapshotEvery: snapshotEvery,
+	}, nil
+}
+
+// replay applies the records made after the snapshot. It returns the resulting state and the last applied LSN.
+func replay(snap snapshot, records []record) (storage.State, uint64) {
+	posts := make(map[domain.PostId]*domain.Post, len(snap.State.Posts))
+	for _, p := range snap.State.Posts {
+		posts[p.ID] = p
+	}
+
+	revisions := map[domain.PostId][]*domain.Revision{}
+	for _, r := range snap.State.Revisions {
+		revisions[r.PostID] = append(revisions[r.PostID], r)
+	}
+
+	comments := map[domain.PostId][]*domain.Comment{}
+	for _, c := range snap.State.Comments {
+		comments[c.PostID] = append(comments[c.PostID], c)
+	}
+
+	users := make(map[domain.UserId]*domain.User, len(snap.State.Users))
+	for _, u := range snap.State.Users {
+		users[u.ID] = u
+	}
+
+	migrations := make(map[string]*domain.MigrationRecord, len(snap.State.Migrations))
+	for _, m := range snap.State.Migrations {
+		migrations[m.ID] = m
+	}
+
+	seqId, commentSeqId, userSeqId, lsn := snap.State.SeqId, snap.State.CommentSeqId, snap.State.UserSeqId, snap.LSN
+	for _, rec := range records {
+		if rec.LSN <= lsn {
+			continue
+		}
+
+		switch rec.Op {
+		case opCreate:
+			posts[rec.ID] = rec.Post
+			if rec.SeqId > 0 {
+				seqId = max(seqId, rec.SeqId)
+			} else {
+				seqId = max(seqId, int64(rec.ID)+1)
+			}
+		case opUpdate:
+			posts[rec.ID] = rec.Post
+		case opDelete:
+			delete(posts, rec.ID)
+			delete(revisions, rec.ID)
+			delete(comments, rec.ID)
+		case opRevision:
+			revisions[rec.ID] = append(revisions[rec.ID], rec.Revision)
+		case opCreateComment:
+			comments[rec.ID] = append(comments[rec.ID], rec.Comment)
+			commentSeqId = max(commentSeqId, int64(rec.Comment.ID)+1)
+		case opUpdateComment:
+			for i, c := range comments[rec.ID] {
+				if c.ID == rec.Comment.ID {
+					comments[rec.ID][i] = rec.Comment
+				}
+			}
+		case opDeleteComment:
+			comments[rec.ID] = storage.WithoutThread(comments[rec.ID], rec.CommentID)
+		case opCreateUser:
+			users[rec.User.ID] = rec.User
+			userSeqId = max(userSeqId, int64(rec.User.ID)+1)
+		case opUpdateUser:
+			users[rec.User.ID] = rec.User
+		case opAddMigration:
+			migrations[rec.Migration.ID] = rec.Migration
+		case opDeleteMigration:
+			delete(migrations, rec.MigrationID)
+		}
+		lsn = rec.LSN
+	}
+
+	state := storage.State{
+		SeqId:        seqId,
+		CommentSeqId: commentSeqId,
+		UserSeqId:    userSeqId,
+		Posts:        make([]*domain.Post, 0, len(posts)),
+		Users:        make([]*domain.User, 0, len(users)),
+	}
+	for _, p := range posts {
+		state.Posts = append(state.Posts, p)
+		state.Revisions = append(state.Revisions, revisions[p.ID]...)
+		state.Comments = append(state.Comments, comments[p.ID]...)
+	}
+	for _, u := range users {
+		state.Users = append(state.Users, u)
+	}
+	for _, m := range migrations {
+		state.Migrations = append(state.Migrations, m)
+	}
+
+	return state, lsn
+}
+
+func (s *Storage) CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	id, err := s.Storage.CreatePost(ctx, post)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.log(record{Op: opCreate, ID: id, Post: post, SeqId: s.Storage.SeqId()}); err != nil {
+		// The post was not persisted, so it should not be visible
+		_ = s.Storage.DeletePost(ctx, id, domain.AnyVersion)
+		return 0, err
+	}
+
+	s.snapshotIfNeeded()
+	return id, nil
+}
+
+func (s *Storage) CreatePostWithID(ctx context.Context, post *domain.Post) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.Storage.CreatePostWithID(ctx, post); err != nil {
+		return err
+	}
+
+	// The sequence is moved past the id of the created post
+	if err := s.log(record{Op: opCreate, ID: post.ID, Post: post, SeqId: s.Storage.SeqId()}); err != nil {
+		_ = s.Storage.DeletePost(ctx, post.ID, domain.AnyVersion)
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+func (s *Storage) UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stored, err := s.Storage.Post(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if post.Version != domain.AnyVersion && post.Version != stored.Version {
+		return storage.VersionMismatchError(id, post.Version)
+	}
+
+	// The in-memory storage checks the version against the stored one and increments it
+	post.ID = id
+	post.Version = stored.Version
+	at := s.Now()
+	updated := *post
+	updated.Version++
+	updated.CreatedAt = stored.CreatedAt
+	updated.UpdatedAt = at
+	if err := s.log(record{Op: opUpdate, ID: id, Post: &updated}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.UpdatePostAt(ctx, post, id, at); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+// DeletePost deletes the post if it has the version. The version is not checked if it's domain.AnyVersion.
+func (s *Storage) DeletePost(ctx context.Context, id domain.PostId, version int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stored, err := s.Storage.Post(ctx, id)
+	if version != domain.AnyVersion && (err != nil || stored.Version != version) {
+		return storage.VersionMismatchError(id, version)
+	}
+
+	if err != nil {
+		// Deletion of a not existing post is not an error, and there is nothing to log
+		return nil
+	}
+
+	if err := s.log(record{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.DeletePost(ctx, id, version); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+// AddRevision keeps the revision of the post. Revision numbers of a post should increase.
+func (s *Storage) AddRevision(ctx context.Context, rev *domain.Revision) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.Storage.Post(ctx, rev.PostID); err != nil {
+		return err
+	}
+
+	revisions, err := s.Storage.Revisions(ctx, rev.PostID)
+	if err != nil {
+		return err
+	}
+
+	if len(revisions) > 0 && revisions[len(revisions)-1].Number >= rev.Number {
+		err := fmt.Errorf("revision already exists. id: %v, revision: %v", rev.PostID, rev.Number)
+		return httperr.WrapWithHttpCode(err, http.StatusConflict)
+	}
+
+	if err := s.log(record{Op: opRevision, ID: rev.PostID, Revision: rev}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.AddRevision(ctx, rev); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+func (s *Storage) CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	id, err := s.Storage.CreateComment(ctx, comment)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.log(record{Op: opCreateComment, ID: comment.PostID, Comment: comment}); err != nil {
+		// The comment was not persisted, so it should not be visible. It has no replies yet.
+		_ = s.Storage.DeleteComment(ctx, comment.PostID, id)
+		return 0, err
+	}
+
+	s.snapshotIfNeeded()
+	return id, nil
+}
+
+// UpdateComment replaces the content and the update time of the comment, the rest of its fields are kept
+func (s *Storage) UpdateComment(ctx context.Context, comment *domain.Comment) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stored, err := s.Storage.Comment(ctx, comment.PostID, comment.ID)
+	if err != nil {
+		return err
+	}
+
+	updated := *stored
+	updated.Content = comment.Content
+	updated.UpdatedAt = comment.UpdatedAt
+	if err := s.log(record{Op: opUpdateComment, ID: comment.PostID, Comment: &updated}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.UpdateComment(ctx, comment); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+// DeleteComment deletes the comment with all the replies to it
+func (s *Storage) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.Storage.Comment(ctx, postId, id); err != nil {
+		// Deletion of a not existing comment is not an error, and there is nothing to log
+		return nil
+	}
+
+	if err := s.log(record{Op: opDeleteComment, ID: postId, CommentID: id}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.DeleteComment(ctx, postId, id); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+func (s *Storage) CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	id, err := s.Storage.CreateUser(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.log(record{Op: opCreateUser, User: user}); err != nil {
+		// The user was not persisted, so it should not be visible
+		s.Storage.DeleteUser(ctx, id)
+		return 0, err
+	}
+
+	s.snapshotIfNeeded()
+	return id, nil
+}
+
+// UpdateUser replaces the profile and the password hash of the user, the username and the creation time are kept
+func (s *Storage) UpdateUser(ctx context.Context, user *domain.User) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stored, err := s.Storage.User(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	updated := *stored
+	updated.DisplayName = user.DisplayName
+	updated.Bio = user.Bio
+	updated.PasswordHash = user.PasswordHash
+	if err := s.log(record{Op: opUpdateUser, User: &updated}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+// AddMigrationRecord adds the migration to the ledger, a migration is added once
+func (s *Storage) AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.Storage.MigrationRecord(ctx, rec.ID); err == nil {
+		return storage.MigrationAppliedError(rec.ID)
+	}
+
+	if err := s.log(record{Op: opAddMigration, Migration: rec}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.AddMigrationRecord(ctx, rec); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+// DeleteMigrationRecord removes the migration from the ledger, e.g. the migration which failed to apply
+func (s *Storage) DeleteMigrationRecord(ctx context.Context, id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.Storage.MigrationRecord(ctx, id); err != nil {
+		// Deletion of a not existing migration is not an error, and there is nothing to log
+		return nil
+	}
+
+	if err := s.log(record{Op: opDeleteMigration, MigrationID: id}); err != nil {
+		return err
+	}
+
+	if err := s.Storage.DeleteMigrationRecord(ctx, id); err != nil {
+		return err
+	}
+
+	s.snapshotIfNeeded()
+	return nil
+}
+
+// log appends the record to the log assigning the next LSN to it
+func (s *Storage) log(rec record) error {
+	rec.LSN = s.lsn + 1
+	if err := s.wal.append(rec); err != nil {
+		return fmt.Errorf("can not write to the log. error: %w", err)
+	}
+
+	s.lsn = rec.LSN
+	return nil
+}
+
+// snapshotIfNeeded takes a snapshot when enough changes are logged since the last one.
+// It's called after the logged change is applied in memory, so the snapshot includes it.
+func (s *Storage) snapshotIfNeeded() {
+	if s.lsn-s.snapshotLSN < uint64(s.snapshotEvery) {
+		return
+	}
+
+	// The changes are persisted in the log already, a failed snapshot is retried with the next change
+	if err := s.snapshot(); err != nil {
+		slog.Warn("can not take snapshot", "dir", s.dir, "error", err)
+	}
+}
+
+// snapshot writes the current state to the disk and drops the log records it covers.
+// The log is dropped after the snapshot is persisted, a crash in between leaves records which are skipped by LSN.
+func (s *Storage) snapshot() error {
+	snap := snapshot{LSN: s.lsn, State: s.Storage.State()}
+	if err := writeSnapshot(filepath.Join(s.dir, snapshotFileName), snap); err != nil {
+		return err
+	}
+	s.snapshotLSN = snap.LSN
+
+	return s.wal.reset()
+}
+
+// Close takes a snapshot and releases the log file
+func (s *Storage) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var err error
+	if s.lsn != s.snapshotLSN {
+		err = s.snapshot()
+	}
+
+	return errors.Join(err, s.wal.close())
+}
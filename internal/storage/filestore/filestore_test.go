@@ -0,0 +1,419 @@
+package filestore
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/storage/storagetest"
+)
+
+// testNow is the time of the test storages clock
+var testNow = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+func openTestStorage(t *testing.T, dir string, snapshotEvery int) *Storage {
+	s, err := Open(dir, snapshotEvery)
+	require.NoError(t, err)
+	s.SetClock(func() time.Time { return testNow })
+	return s
+}
+
+func TestStorageSuite(t *testing.T) {
+	suite.Run(t, &storagetest.Suite{
+		NewStorage: func(t *testing.T, now func() time.Time) blog.Storage {
+			s := openTestStorage(t, t.TempDir(), DefaultSnapshotEvery)
+			s.SetClock(now)
+			t.Cleanup(func() { _ = s.Close() })
+			return s
+		},
+	})
+}
+
+var testRevision = &domain.Revision{
+	PostID:    1,
+	Number:    2,
+	Title:     "updated",
+	Content:   "content",
+	Author:    "author",
+	CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+}
+
+// fillStorage creates posts 1, 2 and 3, updates the first one and deletes the last one
+func fillStorage(t *testing.T, s *Storage) {
+	ctx := context.Background()
+	for _, title := range []string{"1", "2", "3"} {
+		_, err := s.CreatePost(ctx, &domain.Post{Title: title, Content: "content", Author: "author", Status: domain.StatusPublished})
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.UpdatePost(ctx, &domain.Post{Title: "updated", Content: "content", Author: "author", Tags: []string{"go"}, Status: domain.StatusDraft}, 1))
+	require.NoError(t, s.DeletePost(ctx, 3, domain.AnyVersion))
+	require.NoError(t, s.AddRevision(ctx, testRevision))
+}
+
+func assertFilledState(t *testing.T, s *Storage) {
+	ctx := context.Background()
+
+	posts, err := s.Posts(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*domain.Post{
+		{
+			ID: 1, Title: "updated", Content: "content", Author: "author", Tags: []string{"go"}, Status: domain.StatusDraft,
+			CreatedAt: testNow, UpdatedAt: testNow, Version: 2,
+		},
+		{
+			ID: 2, Title: "2", Content: "content", Author: "author", Status: domain.StatusPublished,
+			CreatedAt: testNow, UpdatedAt: testNow, Version: 1,
+		},
+	}, posts)
+
+	_, err = s.Post(ctx, 3)
+	assert.Equal(t, http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+
+	revisions, err := s.Revisions(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.Revision{testRevision}, revisions)
+
+	page, err := s.PostsPage(ctx, domain.PostsQuery{Limit: 10, Tags: []string{"go"}})
+	require.NoError(t, err)
+	assert.Len(t, page.Posts, 1, "the tag index should be restored")
+
+	id, err := s.CreatePost(ctx, &domain.Post{Title: "4"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostId(4), id, "id of the deleted post should not be reused")
+}
+
+func TestStorage_ReplayLog(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	fillStorage(t, s)
+	// Simulate a crash, the log is not compacted on close
+	require.NoError(t, s.wal.close())
+
+	_, err := os.Stat(filepath.Join(dir, snapshotFileName))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	assertFilledState(t, s)
+}
+
+func TestStorage_ReplaySnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	fillStorage(t, s)
+	require.NoError(t, s.Close())
+
+	info, err := os.Stat(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "log should be compacted on close")
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	assertFilledState(t, s)
+}
+
+func TestStorage_PeriodicSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openTestStorage(t, dir, 5)
+	fillStorage(t, s)
+
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFileName))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), snap.LSN)
+
+	_, records, err := openWal(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+	assert.Len(t, records, 1, "only the change made after the snapshot should be in the log")
+
+	require.NoError(t, s.wal.close())
+	s = openTestStorage(t, dir, 5)
+	defer s.Close()
+	assertFilledState(t, s)
+}
+
+func TestStorage_SnapshotCoveredRecordsAreSkipped(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	fillStorage(t, s)
+
+	// Simulate a crash after the snapshot is written but before the log is dropped
+	require.NoError(t, writeSnapshot(filepath.Join(dir, snapshotFileName), snapshot{LSN: s.lsn, State: s.Storage.State()}))
+	require.NoError(t, s.wal.close())
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	assert.Equal(t, uint64(6), s.lsn)
+	assertFilledState(t, s)
+}
+
+func TestStorage_ReplayComments(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	newComment := func(parentId domain.CommentId, content string) *domain.Comment {
+		return &domain.Comment{PostID: 1, ParentID: parentId, Author: "author", Content: content, CreatedAt: createdAt, UpdatedAt: createdAt}
+	}
+
+	assertComments := func(t *testing.T, s *Storage) {
+		comments, err := s.Comments(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+		assert.Equal(t, "updated", comments[0].Content)
+
+		id, err := s.CreateComment(ctx, newComment(0, "next"))
+		require.NoError(t, err)
+		assert.Equal(t, domain.CommentId(4), id, "id of the deleted comment should not be reused")
+	}
+
+	for name, reopen := range map[string]func(t *testing.T, s *Storage){
+		"log":      func(t *testing.T, s *Storage) { require.NoError(t, s.wal.close()) },
+		"snapshot": func(t *testing.T, s *Storage) { require.NoError(t, s.Close()) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := openTestStorage(t, dir, DefaultSnapshotEvery)
+
+			_, err := s.CreatePost(ctx, &domain.Post{Title: "post"})
+			require.NoError(t, err)
+			first, err := s.CreateComment(ctx, newComment(0, "first"))
+			require.NoError(t, err)
+			_, err = s.CreateComment(ctx, newComment(first, "reply"))
+			require.NoError(t, err)
+			second, err := s.CreateComment(ctx, newComment(0, "second"))
+			require.NoError(t, err)
+			require.NoError(t, s.UpdateComment(ctx, &domain.Comment{ID: second, PostID: 1, Content: "updated", UpdatedAt: createdAt}))
+			require.NoError(t, s.DeleteComment(ctx, 1, first))
+			reopen(t, s)
+
+			s = openTestStorage(t, dir, DefaultSnapshotEvery)
+			defer s.Close()
+			assertComments(t, s)
+		})
+	}
+}
+
+func TestStorage_ReplayUsers(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	for name, reopen := range map[string]func(t *testing.T, s *Storage){
+		"log":      func(t *testing.T, s *Storage) { require.NoError(t, s.wal.close()) },
+		"snapshot": func(t *testing.T, s *Storage) { require.NoError(t, s.Close()) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := openTestStorage(t, dir, DefaultSnapshotEvery)
+
+			id, err := s.CreateUser(ctx, &domain.User{Username: "alice", DisplayName: "alice", PasswordHash: "hash", CreatedAt: createdAt})
+			require.NoError(t, err)
+			_, err = s.CreateUser(ctx, &domain.User{Username: "alice"})
+			require.Error(t, err)
+			require.NoError(t, s.UpdateUser(ctx, &domain.User{ID: id, DisplayName: "Alice", Bio: "bio", PasswordHash: "hash"}))
+			reopen(t, s)
+
+			s = openTestStorage(t, dir, DefaultSnapshotEvery)
+			defer s.Close()
+			user, err := s.UserByUsername(ctx, "alice")
+			require.NoError(t, err)
+			assert.Equal(t, &domain.User{ID: id, Username: "alice", DisplayName: "Alice", Bio: "bio", PasswordHash: "hash", CreatedAt: createdAt}, user)
+
+			next, err := s.CreateUser(ctx, &domain.User{Username: "bob"})
+			require.NoError(t, err)
+			assert.Equal(t, domain.UserId(2), next)
+		})
+	}
+}
+
+func TestStorage_ReplayMigrationLedger(t *testing.T) {
+	ctx := context.Background()
+	rec := &domain.MigrationRecord{ID: "posts-2024", Checksum: "checksum", AppliedAt: testNow, Operations: 2}
+
+	for name, reopen := range map[string]func(t *testing.T, s *Storage){
+		"log":      func(t *testing.T, s *Storage) { require.NoError(t, s.wal.close()) },
+		"snapshot": func(t *testing.T, s *Storage) { require.NoError(t, s.Close()) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := openTestStorage(t, dir, DefaultSnapshotEvery)
+			require.NoError(t, s.AddMigrationRecord(ctx, rec))
+			reopen(t, s)
+
+			s = openTestStorage(t, dir, DefaultSnapshotEvery)
+			defer s.Close()
+			stored, err := s.MigrationRecord(ctx, rec.ID)
+			require.NoError(t, err)
+			assert.Equal(t, rec, stored)
+
+			err = s.AddMigrationRecord(ctx, rec)
+			assert.Equal(t, http.StatusConflict, httperr.HTTPStatusCode(err, -1))
+
+			require.NoError(t, s.DeleteMigrationRecord(ctx, rec.ID))
+			reopen(t, s)
+
+			s = openTestStorage(t, dir, DefaultSnapshotEvery)
+			defer s.Close()
+			_, err = s.MigrationRecord(ctx, rec.ID)
+			assert.Equal(t, http.StatusNotFound, httperr.HTTPStatusCode(err, -1), "deleted record should stay deleted")
+		})
+	}
+}
+
+func TestStorage_ReplayExplicitID(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	require.NoError(t, s.CreatePostWithID(ctx, &domain.Post{ID: 10, Title: "Title", Content: "Content"}))
+	require.NoError(t, s.wal.close())
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	post, err := s.Post(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "Title", post.Title)
+
+	id, err := s.CreatePost(ctx, &domain.Post{Title: "Next", Content: "Content"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostId(11), id)
+}
+
+// bigAllocator hands out the ids starting at 2^40
+type bigAllocator struct {
+	next domain.PostId
+}
+
+func (a *bigAllocator) Next() domain.PostId {
+	a.next++
+	return 1<<40 + a.next
+}
+
+func TestStorage_ReplayAllocatedID(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	_, err := s.CreatePost(ctx, &domain.Post{Title: "Sequential", Content: "Content"})
+	require.NoError(t, err)
+	s.SetIDAllocator(&bigAllocator{})
+	id, err := s.CreatePost(ctx, &domain.Post{Title: "Allocated", Content: "Content"})
+	require.NoError(t, err)
+	require.Equal(t, domain.PostId(1<<40+1), id)
+	require.NoError(t, s.wal.close())
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	_, err = s.Post(ctx, id)
+	require.NoError(t, err)
+
+	id, err = s.CreatePost(ctx, &domain.Post{Title: "Next", Content: "Content"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostId(2), id, "allocated id should not move the sequence")
+}
+
+func TestStorage_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, walFileName)
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	fillStorage(t, s)
+	_, err := s.CreatePost(context.Background(), &domain.Post{Title: "torn"})
+	require.NoError(t, err)
+	require.NoError(t, s.wal.close())
+
+	// Cut the last record in the middle as an interrupted write does
+	info, err := os.Stat(walPath)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(walPath, info.Size()-5))
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	assertFilledState(t, s)
+	require.NoError(t, s.wal.close())
+
+	// The torn tail is cut off, so the post created after the recovery is not lost
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	post, err := s.Post(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Equal(t, "4", post.Title)
+}
+
+func TestStorage_CorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, walFileName)
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	fillStorage(t, s)
+	_, err := s.CreatePost(context.Background(), &domain.Post{Title: "corrupted"})
+	require.NoError(t, err)
+	require.NoError(t, s.wal.close())
+
+	// Damage the payload of the last record, so its checksum does not match
+	data, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+	data[len(data)-2] ^= 0xff
+	require.NoError(t, os.WriteFile(walPath, data, 0o644))
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	assertFilledState(t, s)
+}
+
+func TestStorage_CorruptedMiddleRecord(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, walFileName)
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	fillStorage(t, s)
+	require.NoError(t, s.wal.close())
+
+	// Damage the payload of the first record, the records after it are intact
+	data, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+	data[recordHeaderSize+1] ^= 0xff
+	require.NoError(t, os.WriteFile(walPath, data, 0o644))
+
+	_, err = Open(dir, DefaultSnapshotEvery)
+	assert.Error(t, err)
+
+	// The records after the damaged one are not cut off
+	after, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+	assert.Equal(t, data, after)
+}
+
+func TestStorage_ZeroedTail(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, walFileName)
+
+	s := openTestStorage(t, dir, DefaultSnapshotEvery)
+	fillStorage(t, s)
+	require.NoError(t, s.wal.close())
+
+	// An interrupted write may leave the appended space zeroed
+	f, err := os.OpenFile(walPath, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write(make([]byte, 100))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s = openTestStorage(t, dir, DefaultSnapshotEvery)
+	defer s.Close()
+	assertFilledState(t, s)
+}
+
+func TestOpen_WrongSnapshotInterval(t *testing.T) {
+	_, err := Open(t.TempDir(), 0)
+	assert.Error(t, err)
+}
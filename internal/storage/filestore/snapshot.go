@@ -0,0 +1,81 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/voltento/go-blog-project/internal/storage"
+)
+
+// snapshot is the storage state with all the log records up to LSN applied
+type snapshot struct {
+	LSN   uint64        `json:"lsn"`
+	State storage.State `json:"state"`
+}
+
+// readSnapshot returns the snapshot kept in path or an empty one if the file does not exist
+func readSnapshot(path string) (snapshot, error) {
+	snap := snapshot{State: storage.State{SeqId: 1}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	}
+
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snapshot{}, err
+	}
+
+	return snap, nil
+}
+
+// writeSnapshot replaces the snapshot kept in path atomically.
+// The snapshot is written to a temporary file which is renamed after it's flushed to the disk.
+func writeSnapshot(path string, snap snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	return d.Sync()
+}
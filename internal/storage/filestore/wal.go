@@ -0,0 +1,205 @@
+package filestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+	// opRevision adds a revision of a post
+	opRevision = "revision"
+
+	opCreateComment = "create_comment"
+	opUpdateComment = "update_comment"
+	// opDeleteComment deletes a comment with the replies to it
+	opDeleteComment = "delete_comment"
+
+	opCreateUser = "create_user"
+	opUpdateUser = "update_user"
+
+	// opAddMigration adds a data migration to the ledger
+	opAddMigration = "add_migration"
+	// opDeleteMigration removes a data migration from the ledger
+	opDeleteMigration = "delete_migration"
+
+	// recordHeaderSize is the size of the payload length and the payload checksum preceding every record
+	recordHeaderSize = 8
+	maxRecordSize    = 64 << 20
+)
+
+// record is a single change of the storage written to the log
+type record struct {
+	LSN      uint64           `json:"lsn"`
+	Op       string           `json:"op"`
+	ID       domain.PostId    `json:"id"`
+	Post     *domain.Post     `json:"post,omitempty"`
+	Revision *domain.Revision `json:"revision,omitempty"`
+	Comment  *domain.Comment  `json:"comment,omitempty"`
+	User     *domain.User     `json:"user,omitempty"`
+	// Migration is the ledger record of the applied data migration
+	Migration *domain.MigrationRecord `json:"migration,omitempty"`
+	// MigrationID is the id of the migration removed from the ledger
+	MigrationID string `json:"migration_id,omitempty"`
+	// CommentID is the id of the deleted comment
+	CommentID domain.CommentId `json:"comment_id,omitempty"`
+	// SeqId is the next id of the post sequence after the post is created. The ids of the allocators
+	// do not move the sequence, so it's kept apart from the id. The records logged before it was kept have none.
+	SeqId int64 `json:"seq_id,omitempty"`
+}
+
+// wal is an append-only log of records.
+// Each record is framed as a 4-byte payload length, a 4-byte CRC32 of the payload and the JSON payload.
+type wal struct {
+	f *os.File
+	// size is the size of the complete records in the file
+	size int64
+}
+
+// openWal opens the log file, reads all the complete records and cuts off a torn tail
+// left by an interrupted write, so new records are appended right after the last valid one.
+// A damaged record in the middle of the log is an error, the log is left as it is.
+func openWal(path string) (*wal, []record, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, validSize, err := readRecords(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("can not read log '%s'. error: %w", path, err)
+	}
+
+	w := &wal{f: f}
+	if err := w.truncate(validSize); err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+
+	return w, records, nil
+}
+
+// readRecords returns the records and the size of the log prefix holding them.
+// A short or damaged record is accepted as a torn tail only when nothing but zeros follows it,
+// a damaged record followed by more data is a corruption of the log.
+func readRecords(r io.Reader) ([]record, int64, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, recordHeaderSize)
+
+	var records []record
+	var size int64
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, size, nil
+			}
+			return nil, 0, err
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		checksum := binary.BigEndian.Uint32(header[4:])
+		if length > maxRecordSize {
+			return tornTail(br, records, size)
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, size, nil
+			}
+			return nil, 0, err
+		}
+
+		var rec record
+		if crc32.ChecksumIEEE(payload) != checksum || json.Unmarshal(payload, &rec) != nil {
+			return tornTail(br, records, size)
+		}
+
+		records = append(records, rec)
+		size += recordHeaderSize + int64(length)
+	}
+}
+
+// tornTail returns the records read before a damaged record if the rest of the log holds no data.
+// A file system may leave the space of an interrupted write zeroed.
+func tornTail(r io.Reader, records []record, size int64) ([]record, int64, error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return nil, 0, fmt.Errorf("damaged record at offset %d is followed by more records", size)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return records, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// append writes the record and flushes it to the disk.
+// A partially written record is cut off, so it does not hide the records appended later.
+func (w *wal) append(rec record) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(payload))
+	buf = append(buf, payload...)
+
+	_, err = w.f.Write(buf)
+	if err == nil {
+		err = w.f.Sync()
+	}
+
+	if err != nil {
+		return errors.Join(err, w.truncate(w.size))
+	}
+
+	w.size += int64(len(buf))
+	return nil
+}
+
+func (w *wal) truncate(size int64) error {
+	if err := w.f.Truncate(size); err != nil {
+		return err
+	}
+
+	if _, err := w.f.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+
+	w.size = size
+	return nil
+}
+
+// reset drops all the records of the log
+func (w *wal) reset() error {
+	if err := w.truncate(0); err != nil {
+		return err
+	}
+
+	return w.f.Sync()
+}
+
+func (w *wal) close() error {
+	return w.f.Close()
+}
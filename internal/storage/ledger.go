@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+// MigrationRecord returns the ledger record of the applied migration
+func (s *Storage) MigrationRecord(ctx context.Context, id string) (*domain.MigrationRecord, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	if rec, ok := s.migrations[id]; ok {
+		return rec, nil
+	}
+
+	return nil, MigrationNotFoundError(id)
+}
+
+// AddMigrationRecord adds the migration to the ledger, a migration is added once
+func (s *Storage) AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	if _, ok := s.migrations[rec.ID]; ok {
+		return MigrationAppliedError(rec.ID)
+	}
+
+	s.migrations[rec.ID] = rec
+	return nil
+}
+
+// DeleteMigrationRecord removes the migration from the ledger, e.g. the migration which failed to apply
+func (s *Storage) DeleteMigrationRecord(ctx context.Context, id string) error {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	delete(s.migrations, id)
+	return nil
+}
+
+func MigrationNotFoundError(id string) error {
+	err := fmt.Errorf("migration is not applied. id: %v", id)
+	return httperr.WrapWithHttpCode(err, http.StatusNotFound)
+}
+
+func MigrationAppliedError(id string) error {
+	err := fmt.Errorf("migration is applied already. id: %v", id)
+	return httperr.WrapWithHttpCode(err, http.StatusConflict)
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/storage"
+)
+
+// MigrationRecord returns the ledger record of the applied migration
+func (s *Storage) MigrationRecord(ctx context.Context, id string) (*domain.MigrationRecord, error) {
+	var rec domain.MigrationRecord
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, checksum, applied_at, operations FROM migration_ledger WHERE id = $1`,
+		id,
+	).Scan(&rec.ID, &rec.Checksum, &rec.AppliedAt, &rec.Operations)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.MigrationNotFoundError(id)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can not select migration. id: %v. error: %w", id, err)
+	}
+
+	rec.AppliedAt = rec.AppliedAt.UTC()
+	return &rec, nil
+}
+
+// AddMigrationRecord adds the migration to the ledger, a migration is added once
+func (s *Storage) AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO migration_ledger (id, checksum, applied_at, operations) VALUES ($1, $2, $3, $4)`,
+		rec.ID, rec.Checksum, rec.AppliedAt.UTC().Truncate(timePrecision), rec.Operations,
+	)
+	if err != nil {
+		// The primary key is checked after the failure, so the check does not depend on the driver errors
+		if _, lookupErr := s.MigrationRecord(ctx, rec.ID); lookupErr == nil {
+			return storage.MigrationAppliedError(rec.ID)
+		}
+		return fmt.Errorf("can not insert migration. id: %v. error: %w", rec.ID, err)
+	}
+
+	return nil
+}
+
+// DeleteMigrationRecord removes the migration from the ledger, e.g. the migration which failed to apply
+func (s *Storage) DeleteMigrationRecord(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM migration_ledger WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("can not delete migration. id: %v. error: %w", id, err)
+	}
+	return nil
+}
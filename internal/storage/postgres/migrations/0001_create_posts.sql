@@ -0,0 +1,13 @@
+CREATE TABLE posts (
+    id      BIGINT PRIMARY KEY,
+    title   TEXT   NOT NULL,
+    content TEXT   NOT NULL,
+    author  TEXT   NOT NULL
+);
+
+-- post_seq keeps the next candidate post id the same way storage.Storage does it in memory
+CREATE TABLE post_seq (
+    value BIGINT NOT NULL
+);
+
+INSERT INTO post_seq (value) VALUES (1);
@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,10 @@
+CREATE TABLE revisions (
+    post_id    BIGINT    NOT NULL,
+    number     BIGINT    NOT NULL,
+    title      TEXT      NOT NULL,
+    content    TEXT      NOT NULL,
+    author     TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    diff       TEXT      NOT NULL,
+    PRIMARY KEY (post_id, number)
+);
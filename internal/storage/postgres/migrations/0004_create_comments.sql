@@ -0,0 +1,18 @@
+CREATE TABLE comments (
+    id         BIGINT    PRIMARY KEY,
+    post_id    BIGINT    NOT NULL,
+    -- parent_id is zero for a top-level comment
+    parent_id  BIGINT    NOT NULL,
+    author     TEXT      NOT NULL,
+    content    TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX comments_post_id_idx ON comments (post_id, id);
+
+CREATE TABLE comment_seq (
+    value BIGINT NOT NULL
+);
+
+INSERT INTO comment_seq (value) VALUES (1);
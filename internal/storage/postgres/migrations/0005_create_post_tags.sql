@@ -0,0 +1,7 @@
+CREATE TABLE post_tags (
+    post_id BIGINT NOT NULL,
+    tag     TEXT   NOT NULL,
+    PRIMARY KEY (post_id, tag)
+);
+
+CREATE INDEX post_tags_tag_idx ON post_tags (tag, post_id);
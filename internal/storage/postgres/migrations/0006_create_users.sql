@@ -0,0 +1,19 @@
+CREATE TABLE users (
+    id            BIGINT    PRIMARY KEY,
+    username      TEXT      NOT NULL UNIQUE,
+    display_name  TEXT      NOT NULL,
+    bio           TEXT      NOT NULL,
+    -- password_hash is empty for placeholder users
+    password_hash TEXT      NOT NULL,
+    created_at    TIMESTAMP NOT NULL
+);
+
+CREATE TABLE user_seq (
+    value BIGINT NOT NULL
+);
+
+INSERT INTO user_seq (value) VALUES (1);
+
+-- author_id is zero for posts and comments made before users were introduced
+ALTER TABLE posts ADD COLUMN author_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN author_id BIGINT NOT NULL DEFAULT 0;
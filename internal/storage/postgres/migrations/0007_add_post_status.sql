@@ -0,0 +1,6 @@
+-- The posts made before statuses were introduced are published
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+-- publish_at is NULL for drafts
+ALTER TABLE posts ADD COLUMN publish_at TIMESTAMP NULL;
+
+CREATE INDEX posts_scheduled_idx ON posts (status, publish_at);
@@ -0,0 +1,7 @@
+-- The posts made before timestamps were introduced get the zero time, the way it's written by the driver
+ALTER TABLE posts ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00+00:00';
+ALTER TABLE posts ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00+00:00';
+
+CREATE INDEX posts_created_at_idx ON posts (created_at, id);
+CREATE INDEX posts_updated_at_idx ON posts (updated_at, id);
+CREATE INDEX posts_title_idx ON posts (title, id);
@@ -0,0 +1,7 @@
+-- migration_ledger keeps the data migrations applied to the storage, so a migration is applied once
+CREATE TABLE migration_ledger (
+    id         TEXT      PRIMARY KEY,
+    checksum   TEXT      NOT NULL,
+    applied_at TIMESTAMP NOT NULL,
+    operations BIGINT    NOT NULL
+);
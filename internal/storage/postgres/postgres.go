@@ -0,0 +1,884 @@
+// Package postgres implements blog.Storage on top of database/sql and PostgreSQL
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/ids"
+	"github.com/voltento/go-blog-project/internal/storage"
+)
+
+// postColumns are the columns of posts read by scanPost
+const postColumns = `id, title, content, author_id, author, status, publish_at, created_at, updated_at, version`
+
+// sortColumns are the columns posts are ordered by for every sort
+var sortColumns = map[domain.PostSort]string{
+	domain.SortByID:        "id",
+	domain.SortByCreatedAt: "created_at",
+	domain.SortByUpdatedAt: "updated_at",
+	domain.SortByTitle:     "title",
+}
+
+// Storage keeps posts in PostgreSQL.
+// The queries stick to the portable subset of SQL, so the storage can be tested without a running database.
+type Storage struct {
+	db *sql.DB
+	// now is the clock the posts are timestamped with
+	now func() time.Time
+	// allocator hands out the ids of new posts, post_seq is used if it's nil
+	allocator ids.Allocator
+}
+
+// Open connects to the database with the dsn and prepares the schema
+func Open(ctx context.Context, dsn string) (*Storage, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := NewStorage(ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NewStorage applies the schema migrations and returns the storage using db
+func NewStorage(ctx context.Context, db *sql.DB) (*Storage, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &Storage{db: db, now: time.Now}, nil
+}
+
+// SetClock replaces the clock the posts are timestamped with. It should be called before the storage is used.
+func (s *Storage) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// timePrecision is the precision of the times the database keeps
+const timePrecision = time.Microsecond
+
+// timestamp returns the time of the clock with the precision the database keeps
+func (s *Storage) timestamp() time.Time {
+	return s.now().UTC().Truncate(timePrecision)
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// ScheduledPosts returns the scheduled posts which publish time is not after the time, the earliest go first
+func (s *Storage) ScheduledPosts(ctx context.Context, until time.Time) ([]*domain.Post, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+postColumns+` FROM posts WHERE status = $1 AND publish_at <= $2 ORDER BY publish_at, id`,
+		domain.StatusScheduled, until.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can not select scheduled posts. error: %w", err)
+	}
+
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.loadTags(ctx, posts, `SELECT id FROM posts WHERE status = $1 AND publish_at <= $2`, domain.StatusScheduled, until.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (s *Storage) Post(ctx context.Context, id domain.PostId) (*domain.Post, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+postColumns+` FROM posts WHERE id = $1`, id)
+
+	post, err := scanPost(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		err := fmt.Errorf("blog not found. id: %v", id)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can not select post. id: %v. error: %w", id, err)
+	}
+
+	if err := s.loadTags(ctx, []*domain.Post{post}, `$1`, id); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+func (s *Storage) CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := s.nextAvailableId(ctx, tx)
+	if err != nil {
+		return 0, fmt.Errorf("can not acquire post id. error: %w", err)
+	}
+
+	now := s.timestamp()
+	created := *post
+	created.Version, created.CreatedAt, created.UpdatedAt = 1, now, now
+	if err := insertPost(ctx, tx, id, &created); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	*post = created
+	post.ID = id
+	return id, nil
+}
+
+// CreatePostWithID keeps the post with its id instead of allocating one, e.g. the post of a data migration.
+// The id should be positive and not taken, the sequence of ids is moved past it.
+// A post with the creation time is restored: it keeps its version and times, e.g. the post of an undone deletion.
+func (s *Storage) CreatePostWithID(ctx context.Context, post *domain.Post) error {
+	if post.ID <= 0 {
+		err := fmt.Errorf("post id should be positive. id: %v", post.ID)
+		return httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Updating post_seq first locks its row, so the check of the id is not raced by the other creations
+	_, err = tx.ExecContext(ctx, `UPDATE post_seq SET value = CASE WHEN value > $1 THEN value ELSE $1 + 1 END`, post.ID)
+	if err != nil {
+		return fmt.Errorf("can not advance post sequence. error: %w", err)
+	}
+
+	busy, err := postExists(ctx, tx, post.ID)
+	if err != nil {
+		return err
+	}
+	if busy {
+		return storage.PostExistsError(post.ID)
+	}
+
+	created := *post
+	if created.CreatedAt.IsZero() {
+		now := s.timestamp()
+		created.Version, created.CreatedAt, created.UpdatedAt = 1, now, now
+	}
+	if err := insertPost(ctx, tx, post.ID, &created); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	*post = created
+	return nil
+}
+
+func insertPost(ctx context.Context, tx *sql.Tx, id domain.PostId, post *domain.Post) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO posts (id, title, content, author_id, author, status, publish_at, created_at, updated_at, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		id, post.Title, post.Content, post.AuthorID, post.Author, post.Status, nullTime(post.PublishAt),
+		post.CreatedAt.UTC().Truncate(timePrecision), post.UpdatedAt.UTC().Truncate(timePrecision), post.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("can not insert post. error: %w", err)
+	}
+
+	return insertTags(ctx, tx, id, post.Tags)
+}
+
+// SetIDAllocator replaces post_seq allocating the ids of new posts. It should be called before the storage is used.
+func (s *Storage) SetIDAllocator(a ids.Allocator) {
+	s.allocator = a
+}
+
+// nextAvailableId returns next post id which is guarantied to be not used yet.
+// Updating post_seq locks its row till the end of tx, so concurrent creations are serialized.
+func (s *Storage) nextAvailableId(ctx context.Context, tx *sql.Tx) (domain.PostId, error) {
+	for {
+		var id domain.PostId
+		if s.allocator != nil {
+			id = s.allocator.Next()
+		} else {
+			err := tx.QueryRowContext(ctx, `UPDATE post_seq SET value = value + 1 RETURNING value - 1`).Scan(&id)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		busy, err := postExists(ctx, tx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		if !busy {
+			return id, nil
+		}
+	}
+}
+
+func postExists(ctx context.Context, tx *sql.Tx, id domain.PostId) (bool, error) {
+	var busy bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1)`, id).Scan(&busy)
+	return busy, err
+}
+
+func (s *Storage) UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error {
+	post.ID = id
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var version int
+	var createdAt time.Time
+	now := s.timestamp()
+	err = tx.QueryRowContext(ctx,
+		`UPDATE posts SET title = $1, content = $2, author_id = $3, author = $4, status = $5, publish_at = $6, updated_at = $7,
+		version = version + 1
+		WHERE id = $8 AND ($9 = 0 OR version = $9)
+		RETURNING version, created_at`,
+		post.Title, post.Content, post.AuthorID, post.Author, post.Status, nullTime(post.PublishAt), now, id, post.Version,
+	).Scan(&version, &createdAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		// Either the post does not exist or it has another version.
+		// The tx is finished first as it may hold the only connection.
+		_ = tx.Rollback()
+		if _, err := s.Post(ctx, id); err != nil {
+			return err
+		}
+		return storage.VersionMismatchError(id, post.Version)
+	}
+
+	if err != nil {
+		return fmt.Errorf("can not update post. id: %v. error: %w", id, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_tags WHERE post_id = $1`, id); err != nil {
+		return fmt.Errorf("can not delete tags. id: %v. error: %w", id, err)
+	}
+
+	if err := insertTags(ctx, tx, id, post.Tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	post.Version = version
+	post.CreatedAt = createdAt.UTC()
+	post.UpdatedAt = now
+	return nil
+}
+
+// DeletePost deletes the post with its revisions if it has the version.
+// The version is not checked if it's domain.AnyVersion.
+func (s *Storage) DeletePost(ctx context.Context, id domain.PostId, version int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM posts WHERE id = $1 AND ($2 = 0 OR version = $2)`, id, version)
+	if err != nil {
+		return fmt.Errorf("can not delete post. id: %v. error: %w", id, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		if version == domain.AnyVersion {
+			return nil
+		}
+		return storage.VersionMismatchError(id, version)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM revisions WHERE post_id = $1`, id); err != nil {
+		return fmt.Errorf("can not delete revisions. id: %v. error: %w", id, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE post_id = $1`, id); err != nil {
+		return fmt.Errorf("can not delete comments. id: %v. error: %w", id, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_tags WHERE post_id = $1`, id); err != nil {
+		return fmt.Errorf("can not delete tags. id: %v. error: %w", id, err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) Posts(ctx context.Context) ([]*domain.Post, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("can not select posts. error: %w", err)
+	}
+
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadTags(ctx, posts, `SELECT id FROM posts`); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// PostsPage returns posts in the order of q.Sort starting right after the q.After post
+func (s *Storage) PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error) {
+	column, ok := sortColumns[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %v", q.Sort)
+	}
+
+	direction, cmp := "ASC", ">"
+	if q.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	var conds []string
+	var args []any
+	if q.After != 0 {
+		after, err := q.Sort.Position(q.After, q.AfterKey)
+		if err != nil {
+			return nil, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+		}
+
+		if q.Sort == domain.SortByID {
+			conds = append(conds, fmt.Sprintf(`id %s $1`, cmp))
+			args = append(args, q.After)
+		} else {
+			conds = append(conds, fmt.Sprintf(`(%[1]s %[2]s $1 OR (%[1]s = $1 AND id %[2]s $2))`, column, cmp))
+			args = append(args, sortValue(q.Sort, after), q.After)
+		}
+	}
+
+	if q.PublishedOnly {
+		conds = append(conds, fmt.Sprintf(`(status = $%d OR author_id = $%d)`, len(args)+1, len(args)+2))
+		// The posts made before users were introduced have zero author id, so zero viewer should not match them
+		viewer := q.Viewer
+		if viewer == 0 {
+			viewer = -1
+		}
+		args = append(args, domain.StatusPublished, viewer)
+	}
+
+	if q.Author != 0 {
+		conds = append(conds, fmt.Sprintf(`author_id = $%d`, len(args)+1))
+		args = append(args, q.Author)
+	}
+
+	if len(q.Tags) > 0 {
+		tags := slices.Clone(q.Tags)
+		slices.Sort(tags)
+		tags = slices.Compact(tags)
+		tagsQuery := `SELECT post_id FROM post_tags WHERE tag IN (` + placeholders(len(args)+1, len(tags)) + `)`
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+
+		if q.TagMatch == domain.MatchAllTags {
+			tagsQuery += fmt.Sprintf(` GROUP BY post_id HAVING COUNT(*) = %d`, len(tags))
+		}
+		conds = append(conds, `id IN (`+tagsQuery+`)`)
+	}
+
+	query := `SELECT ` + postColumns + ` FROM posts`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, ` AND `)
+	}
+
+	// One extra post is requested to find out whether there is a next page
+	query += fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT $%d`, column, direction, direction, len(args)+1)
+	args = append(args, q.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("can not select posts. error: %w", err)
+	}
+
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page{Posts: posts}
+	if len(posts) > q.Limit {
+		page.Posts = posts[:q.Limit]
+		page.HasMore = true
+	}
+
+	if err := s.loadPageTags(ctx, page.Posts); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// sortValue returns the field of the post the posts are ordered by
+func sortValue(sort domain.PostSort, p *domain.Post) any {
+	switch sort {
+	case domain.SortByCreatedAt:
+		return p.CreatedAt
+	case domain.SortByUpdatedAt:
+		return p.UpdatedAt
+	case domain.SortByTitle:
+		return p.Title
+	}
+	return p.ID
+}
+
+// Tags returns the tags of published posts with the number of posts having them, the most used tags go first
+func (s *Storage) Tags(ctx context.Context) ([]*domain.TagCount, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT tag, COUNT(*) FROM post_tags WHERE post_id IN (SELECT id FROM posts WHERE status = $1) GROUP BY tag`,
+		domain.StatusPublished,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can not select tags. error: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make([]*domain.TagCount, 0)
+	for rows.Next() {
+		var c domain.TagCount
+		if err := rows.Scan(&c.Tag, &c.Posts); err != nil {
+			return nil, fmt.Errorf("can not scan tag. error: %w", err)
+		}
+		counts = append(counts, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	storage.SortTagCounts(counts)
+	return counts, nil
+}
+
+// StatusCounts returns the number of the posts by status, the statuses without posts are not listed
+func (s *Storage) StatusCounts(ctx context.Context) (map[domain.PostStatus]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM posts GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("can not count posts. error: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := map[domain.PostStatus]int{}
+	for rows.Next() {
+		var status domain.PostStatus
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("can not scan post count. error: %w", err)
+		}
+		counts[status] = n
+	}
+
+	return counts, rows.Err()
+}
+
+// loadPageTags sets tags of a page of posts. A page is small, so its ids are passed as parameters.
+func (s *Storage) loadPageTags(ctx context.Context, posts []*domain.Post) error {
+	args := make([]any, 0, len(posts))
+	for _, p := range posts {
+		args = append(args, p.ID)
+	}
+
+	return s.loadTags(ctx, posts, placeholders(1, len(args)), args...)
+}
+
+// loadTags sets tags of the posts in one query. The ids are the contents of an IN list: either
+// placeholders or a subquery selecting the same posts, so that the number of the parameters
+// does not grow with the number of the posts.
+func (s *Storage) loadTags(ctx context.Context, posts []*domain.Post, ids string, args ...any) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byId := make(map[domain.PostId]*domain.Post, len(posts))
+	for _, p := range posts {
+		byId[p.ID] = p
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT post_id, tag FROM post_tags WHERE post_id IN (`+ids+`) ORDER BY post_id, tag`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("can not select tags. error: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var id domain.PostId
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("can not scan tag. error: %w", err)
+		}
+		// The subquery may see a post created after the posts were selected
+		if p, ok := byId[id]; ok {
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, id domain.PostId, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO post_tags (post_id, tag) VALUES ($1, $2)`, id, tag); err != nil {
+			return fmt.Errorf("can not insert tag. id: %v, tag: %v. error: %w", id, tag, err)
+		}
+	}
+
+	return nil
+}
+
+// placeholders returns n comma-separated placeholders starting from $start
+func placeholders(start, n int) string {
+	ps := make([]string, 0, n)
+	for i := start; i < start+n; i++ {
+		ps = append(ps, "$"+strconv.Itoa(i))
+	}
+	return strings.Join(ps, ", ")
+}
+
+// AddRevision keeps the revision of the post. Revision numbers of a post should increase.
+func (s *Storage) AddRevision(ctx context.Context, rev *domain.Revision) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Reading the post in tx guards against adding revision of a post being deleted
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1)`, rev.PostID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("can not select post. id: %v. error: %w", rev.PostID, err)
+	}
+
+	if !exists {
+		err := fmt.Errorf("blog not found. id: %v", rev.PostID)
+		return httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	var lastNumber int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(number), 0) FROM revisions WHERE post_id = $1`, rev.PostID).Scan(&lastNumber)
+	if err != nil {
+		return fmt.Errorf("can not select revisions. id: %v. error: %w", rev.PostID, err)
+	}
+
+	if lastNumber >= rev.Number {
+		err := fmt.Errorf("revision already exists. id: %v, revision: %v", rev.PostID, rev.Number)
+		return httperr.WrapWithHttpCode(err, http.StatusConflict)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO revisions (post_id, number, title, content, author, created_at, diff) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		rev.PostID, rev.Number, rev.Title, rev.Content, rev.Author, rev.CreatedAt.UTC(), rev.Diff,
+	)
+	if err != nil {
+		return fmt.Errorf("can not insert revision. id: %v. error: %w", rev.PostID, err)
+	}
+
+	return tx.Commit()
+}
+
+// Revisions returns revisions of the post ordered by number
+func (s *Storage) Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT post_id, number, title, content, author, created_at, diff FROM revisions WHERE post_id = $1 ORDER BY number`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can not select revisions. id: %v. error: %w", id, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	revisions := make([]*domain.Revision, 0)
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("can not scan revision. error: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (s *Storage) Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT post_id, number, title, content, author, created_at, diff FROM revisions WHERE post_id = $1 AND number = $2`,
+		id, number,
+	)
+
+	rev, err := scanRevision(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		err := fmt.Errorf("revision not found. id: %v, revision: %v", id, number)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can not select revision. id: %v, revision: %v. error: %w", id, number, err)
+	}
+
+	return rev, nil
+}
+
+// CreateComment keeps the comment assigning the next comment id to it.
+// The parent comment, if any, should belong to the same post.
+func (s *Storage) CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1)`, comment.PostID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("can not select post. id: %v. error: %w", comment.PostID, err)
+	}
+
+	if !exists {
+		err := fmt.Errorf("blog not found. id: %v", comment.PostID)
+		return 0, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	if comment.ParentID != 0 {
+		err = tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1 AND post_id = $2)`,
+			comment.ParentID, comment.PostID,
+		).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("can not select comment. id: %v. error: %w", comment.ParentID, err)
+		}
+
+		if !exists {
+			return 0, storage.ParentCommentNotFoundError(comment.PostID, comment.ParentID)
+		}
+	}
+
+	var id domain.CommentId
+	if err := tx.QueryRowContext(ctx, `UPDATE comment_seq SET value = value + 1 RETURNING value - 1`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("can not acquire comment id. error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO comments (id, post_id, parent_id, author_id, author, content, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		id, comment.PostID, comment.ParentID, comment.AuthorID, comment.Author, comment.Content, comment.CreatedAt.UTC(), comment.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("can not insert comment. error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	comment.ID = id
+	return id, nil
+}
+
+func (s *Storage) Comment(ctx context.Context, postId domain.PostId, id domain.CommentId) (*domain.Comment, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, post_id, parent_id, author_id, author, content, created_at, updated_at FROM comments WHERE id = $1 AND post_id = $2`,
+		id, postId,
+	)
+
+	comment, err := scanComment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.CommentNotFoundError(postId, id)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can not select comment. id: %v. error: %w", id, err)
+	}
+
+	return comment, nil
+}
+
+// Comments returns comments of the post ordered by id
+func (s *Storage) Comments(ctx context.Context, postId domain.PostId) ([]*domain.Comment, error) {
+	return s.comments(ctx, s.db, postId)
+}
+
+func (s *Storage) comments(ctx context.Context, q queryer, postId domain.PostId) ([]*domain.Comment, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, post_id, parent_id, author_id, author, content, created_at, updated_at FROM comments WHERE post_id = $1 ORDER BY id`,
+		postId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can not select comments. id: %v. error: %w", postId, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	comments := make([]*domain.Comment, 0)
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("can not scan comment. error: %w", err)
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
+
+// UpdateComment replaces the content and the update time of the comment, the rest of its fields are kept
+func (s *Storage) UpdateComment(ctx context.Context, comment *domain.Comment) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE comments SET content = $1, updated_at = $2 WHERE id = $3 AND post_id = $4`,
+		comment.Content, comment.UpdatedAt.UTC(), comment.ID, comment.PostID,
+	)
+	if err != nil {
+		return fmt.Errorf("can not update comment. id: %v. error: %w", comment.ID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.CommentNotFoundError(comment.PostID, comment.ID)
+	}
+
+	return nil
+}
+
+// DeleteComment deletes the comment with all the replies to it
+func (s *Storage) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	comments, err := s.comments(ctx, tx, postId)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[domain.CommentId]bool, len(comments))
+	for _, c := range storage.WithoutThread(comments, id) {
+		kept[c.ID] = true
+	}
+
+	for _, c := range comments {
+		if kept[c.ID] {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, c.ID); err != nil {
+			return fmt.Errorf("can not delete comment. id: %v. error: %w", c.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryer is implemented by both sql.DB and sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPost(row scanner) (*domain.Post, error) {
+	var p domain.Post
+	var publishAt sql.NullTime
+	err := row.Scan(&p.ID, &p.Title, &p.Content, &p.AuthorID, &p.Author, &p.Status, &publishAt, &p.CreatedAt, &p.UpdatedAt, &p.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	p.CreatedAt = p.CreatedAt.UTC()
+	p.UpdatedAt = p.UpdatedAt.UTC()
+
+	if publishAt.Valid {
+		p.PublishAt = publishAt.Time.UTC()
+	}
+	return &p, nil
+}
+
+// nullTime stores zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+func scanRevision(row scanner) (*domain.Revision, error) {
+	var r domain.Revision
+	if err := row.Scan(&r.PostID, &r.Number, &r.Title, &r.Content, &r.Author, &r.CreatedAt, &r.Diff); err != nil {
+		return nil, err
+	}
+
+	r.CreatedAt = r.CreatedAt.UTC()
+	return &r, nil
+}
+
+func scanComment(row scanner) (*domain.Comment, error) {
+	var c domain.Comment
+	if err := row.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Author, &c.Content, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	c.CreatedAt = c.CreatedAt.UTC()
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	return &c, nil
+}
+
+func scanPosts(rows *sql.Rows) ([]*domain.Post, error) {
+	defer func() { _ = rows.Close() }()
+
+	posts := make([]*domain.Post, 0)
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("can not scan post. error: %w", err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/storage/storagetest"
+	_ "modernc.org/sqlite"
+)
+
+// postgresDsnEnv points to a database for running the tests against real PostgreSQL.
+// Without it the tests use in-memory SQLite as a stand-in.
+const postgresDsnEnv = "BLOG_TEST_POSTGRES_DSN"
+
+// openTestDB returns a database with the schema migrated and no data
+func openTestDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv(postgresDsnEnv)
+	if dsn == "" {
+		db, err := sql.Open("sqlite", ":memory:")
+		require.NoError(t, err)
+
+		// Every connection to :memory: opens a separate database
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	require.NoError(t, migrate(context.Background(), db))
+	_, err = db.Exec(`DELETE FROM comments; DELETE FROM revisions; DELETE FROM post_tags; DELETE FROM posts; UPDATE post_seq SET value = 1; UPDATE comment_seq SET value = 1; DELETE FROM users; UPDATE user_seq SET value = 1; DELETE FROM migration_ledger`)
+	require.NoError(t, err)
+	return db
+}
+
+func newTestStorage(t *testing.T) *Storage {
+	s, err := NewStorage(context.Background(), openTestDB(t))
+	require.NoError(t, err)
+	return s
+}
+
+func TestStorageSuite(t *testing.T) {
+	suite.Run(t, &storagetest.Suite{
+		NewStorage: func(t *testing.T, now func() time.Time) blog.Storage {
+			s := newTestStorage(t)
+			s.SetClock(now)
+			return s
+		},
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	t.Run("Applies all migrations", func(t *testing.T) {
+		require.NoError(t, migrate(ctx, db))
+
+		migrations, err := loadMigrations()
+		require.NoError(t, err)
+
+		var applied int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+		assert.Equal(t, len(migrations), applied)
+	})
+
+	t.Run("Rerun is no-op", func(t *testing.T) {
+		_, err := db.Exec(`INSERT INTO posts (id, title, content, author) VALUES (1, 'title', 'content', 'author')`)
+		require.NoError(t, err)
+
+		require.NoError(t, migrate(ctx, db))
+
+		var posts int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM posts`).Scan(&posts))
+		assert.Equal(t, 1, posts)
+	})
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, "migration versions should go one by one")
+		assert.NotEmpty(t, m.query)
+	}
+}
+
+func TestPosts_MorePostsThanParameters(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	// PostgreSQL allows at most 65535 parameters in a query
+	const count = 70000
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	for id := 1; id <= count; id++ {
+		_, err := tx.ExecContext(ctx, `INSERT INTO posts (id, title, content, author) VALUES ($1, 'title', 'content', 'author')`, id)
+		require.NoError(t, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO post_tags (post_id, tag) VALUES ($1, 'go')`, count)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	posts, err := s.Posts(ctx)
+	require.NoError(t, err)
+	require.Len(t, posts, count)
+	assert.Equal(t, []string{"go"}, posts[count-1].Tags)
+	assert.Empty(t, posts[0].Tags)
+}
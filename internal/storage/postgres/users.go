@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/storage"
+)
+
+// CreateUser keeps the user assigning the next user id to it. Usernames are unique.
+func (s *Storage) CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id domain.UserId
+	if err := tx.QueryRowContext(ctx, `UPDATE user_seq SET value = value + 1 RETURNING value - 1`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("can not acquire user id. error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO users (id, username, display_name, bio, password_hash, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		id, user.Username, user.DisplayName, user.Bio, user.PasswordHash, user.CreatedAt.UTC(),
+	)
+	if err == nil {
+		err = tx.Commit()
+	}
+
+	if err != nil {
+		// The unique constraint is checked after the failure, so the check does not depend on the driver errors
+		_ = tx.Rollback()
+		if _, lookupErr := s.UserByUsername(ctx, user.Username); lookupErr == nil {
+			return 0, storage.UsernameTakenError(user.Username)
+		}
+		return 0, fmt.Errorf("can not insert user. error: %w", err)
+	}
+
+	user.ID = id
+	return id, nil
+}
+
+func (s *Storage) User(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, username, display_name, bio, password_hash, created_at FROM users WHERE id = $1`,
+		id,
+	)
+
+	user, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.UserNotFoundError(id)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can not select user. id: %v. error: %w", id, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) UserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, username, display_name, bio, password_hash, created_at FROM users WHERE username = $1`,
+		username,
+	)
+
+	user, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		err := fmt.Errorf("user not found. username: %v", username)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can not select user. username: %v. error: %w", username, err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser replaces the profile and the password hash of the user, the username and the creation time are kept
+func (s *Storage) UpdateUser(ctx context.Context, user *domain.User) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE users SET display_name = $1, bio = $2, password_hash = $3 WHERE id = $4`,
+		user.DisplayName, user.Bio, user.PasswordHash, user.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("can not update user. id: %v. error: %w", user.ID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.UserNotFoundError(user.ID)
+	}
+
+	return nil
+}
+
+func scanUser(row scanner) (*domain.User, error) {
+	var u domain.User
+	if err := row.Scan(&u.ID, &u.Username, &u.DisplayName, &u.Bio, &u.PasswordHash, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	u.CreatedAt = u.CreatedAt.UTC()
+	return &u, nil
+}
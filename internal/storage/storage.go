@@ -5,51 +5,229 @@ import (
 	"fmt"
 	"github.com/voltento/go-blog-project/internal/domain"
 	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/ids"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Storage uses simple map as data storage as per task description
 type Storage struct {
 	postsMtx sync.RWMutex
 	posts    map[domain.PostId]*domain.Post
+	// ids keeps ids of posts in ascending order to provide stable pagination
+	ids []domain.PostId
+	// tags keeps ids of posts having the tag in ascending order, so filtered pages are built without scanning every post
+	tags map[string][]domain.PostId
+	// sorted keeps ids of posts in the order of every sort but SortByID, so sorted pages are built without sorting the posts
+	sorted map[domain.PostSort][]domain.PostId
+	// revisions keeps revisions of every post ordered by number
+	revisions map[domain.PostId][]*domain.Revision
+	// comments keeps comments of every post ordered by id, so a reply always goes after its parent
+	comments map[domain.PostId][]*domain.Comment
+	users    map[domain.UserId]*domain.User
+	// usernames maps the unique usernames to user ids
+	usernames map[string]domain.UserId
+	// migrations is the ledger of the applied data migrations by their ids
+	migrations map[string]*domain.MigrationRecord
 
 	seqId *int64
+	// commentSeqId and userSeqId are the next comment and user ids, they are changed under the write lock
+	commentSeqId int64
+	userSeqId    int64
+
+	// now is the clock the posts are timestamped with
+	now func() time.Time
+	// allocator hands out the ids of new posts, seqId is used if it's nil
+	allocator ids.Allocator
 }
 
+// indexedSorts are the sorts having an index in Storage.sorted
+var indexedSorts = []domain.PostSort{domain.SortByCreatedAt, domain.SortByUpdatedAt, domain.SortByTitle}
+
 func NewStorage() *Storage {
-	var startId int64 = 1
-	return &Storage{
-		seqId: &startId,
-		posts: map[domain.PostId]*domain.Post{},
+	return NewStorageFromState(State{SeqId: 1, CommentSeqId: 1, UserSeqId: 1})
+}
+
+// State is a point-in-time copy of the storage content. It's used by persistent storages built on top of Storage.
+type State struct {
+	Posts     []*domain.Post
+	Revisions []*domain.Revision
+	Comments  []*domain.Comment
+	Users     []*domain.User
+	// Migrations is the ledger of the applied data migrations
+	Migrations []*domain.MigrationRecord
+	// SeqId is the next id candidate for a new post
+	SeqId int64
+	// CommentSeqId and UserSeqId are the ids of the next comment and user
+	CommentSeqId int64
+	UserSeqId    int64
+}
+
+// NewStorageFromState returns the storage filled from the state
+func NewStorageFromState(state State) *Storage {
+	seqId := state.SeqId
+	s := &Storage{
+		seqId:        &seqId,
+		commentSeqId: max(state.CommentSeqId, 1),
+		userSeqId:    max(state.UserSeqId, 1),
+		posts:        make(map[domain.PostId]*domain.Post, len(state.Posts)),
+		ids:          make([]domain.PostId, 0, len(state.Posts)),
+		tags:         map[string][]domain.PostId{},
+		sorted:       make(map[domain.PostSort][]domain.PostId, len(indexedSorts)),
+		revisions:    map[domain.PostId][]*domain.Revision{},
+		comments:     map[domain.PostId][]*domain.Comment{},
+		users:        make(map[domain.UserId]*domain.User, len(state.Users)),
+		usernames:    make(map[string]domain.UserId, len(state.Users)),
+		migrations:   make(map[string]*domain.MigrationRecord, len(state.Migrations)),
+		now:          time.Now,
+	}
+
+	for _, p := range state.Posts {
+		// The posts stored before statuses were introduced are published
+		if p.Status == "" {
+			p.Status = domain.StatusPublished
+		}
+		s.posts[p.ID] = p
+		s.ids = append(s.ids, p.ID)
+		s.indexTags(p)
+	}
+	slices.Sort(s.ids)
+
+	for _, sort := range indexedSorts {
+		ids := slices.Clone(s.ids)
+		slices.SortFunc(ids, func(a, b domain.PostId) int { return sort.Compare(s.posts[a], s.posts[b]) })
+		s.sorted[sort] = ids
+	}
+
+	for _, r := range state.Revisions {
+		s.revisions[r.PostID] = append(s.revisions[r.PostID], r)
+	}
+	for _, revisions := range s.revisions {
+		slices.SortFunc(revisions, func(a, b *domain.Revision) int { return a.Number - b.Number })
+	}
+
+	for _, c := range state.Comments {
+		s.comments[c.PostID] = append(s.comments[c.PostID], c)
+		s.commentSeqId = max(s.commentSeqId, int64(c.ID)+1)
+	}
+	for _, comments := range s.comments {
+		slices.SortFunc(comments, func(a, b *domain.Comment) int { return int(a.ID - b.ID) })
+	}
+
+	for _, u := range state.Users {
+		s.users[u.ID] = u
+		s.usernames[u.Username] = u.ID
+		s.userSeqId = max(s.userSeqId, int64(u.ID)+1)
+	}
+
+	for _, m := range state.Migrations {
+		s.migrations[m.ID] = m
+	}
+
+	return s
+}
+
+// State returns copy of the storage content
+func (s *Storage) State() State {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	state := State{
+		Posts:        make([]*domain.Post, 0, len(s.ids)),
+		SeqId:        atomic.LoadInt64(s.seqId),
+		CommentSeqId: s.commentSeqId,
+		UserSeqId:    s.userSeqId,
+		Users:        make([]*domain.User, 0, len(s.users)),
 	}
+	for _, id := range s.ids {
+		p := *s.posts[id]
+		state.Posts = append(state.Posts, &p)
+		state.Revisions = append(state.Revisions, s.revisions[id]...)
+		state.Comments = append(state.Comments, s.comments[id]...)
+	}
+	for _, u := range s.users {
+		state.Users = append(state.Users, u)
+	}
+	slices.SortFunc(state.Users, func(a, b *domain.User) int { return int(a.ID - b.ID) })
+
+	for _, m := range s.migrations {
+		state.Migrations = append(state.Migrations, m)
+	}
+	slices.SortFunc(state.Migrations, func(a, b *domain.MigrationRecord) int { return strings.Compare(a.ID, b.ID) })
+
+	return state
+}
+
+// Now returns the time of the storage clock
+func (s *Storage) Now() time.Time {
+	return s.now().UTC()
+}
+
+// SetClock replaces the clock the posts are timestamped with. It should be called before the storage is used.
+func (s *Storage) SetClock(now func() time.Time) {
+	s.now = now
 }
 
 func (s *Storage) UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error {
+	return s.UpdatePostAt(ctx, post, id, s.Now())
+}
+
+// UpdatePostAt is UpdatePost made at the time.
+// It's used by persistent storages which take the update time before the change is applied.
+func (s *Storage) UpdatePostAt(ctx context.Context, post *domain.Post, id domain.PostId, at time.Time) error {
 	post.ID = id
 
 	s.postsMtx.Lock()
 	defer s.postsMtx.Unlock()
 
-	if _, exists := s.posts[id]; !exists {
+	stored, exists := s.posts[id]
+	if !exists {
 		err := fmt.Errorf("blog not found. id: %v", id)
 		return httperr.WrapWithHttpCode(err, http.StatusNotFound)
 	}
 
+	if post.Version != domain.AnyVersion && post.Version != stored.Version {
+		return VersionMismatchError(id, post.Version)
+	}
+
+	post.Version = stored.Version + 1
+	post.CreatedAt = stored.CreatedAt
+	post.UpdatedAt = at
+	s.unindexPost(stored)
 	s.posts[id] = post
+	s.indexPost(post)
 	return nil
 }
 
-func (s *Storage) DeletePost(ctx context.Context, id domain.PostId) error {
+// DeletePost deletes the post if it has the version. The version is not checked if it's domain.AnyVersion.
+func (s *Storage) DeletePost(ctx context.Context, id domain.PostId, version int) error {
 	s.postsMtx.Lock()
 	defer s.postsMtx.Unlock()
 
+	stored, exists := s.posts[id]
+	if version != domain.AnyVersion && (!exists || stored.Version != version) {
+		return VersionMismatchError(id, version)
+	}
+
+	if !exists {
+		return nil
+	}
+
+	s.unindexPost(stored)
 	delete(s.posts, id)
+	delete(s.revisions, id)
+	delete(s.comments, id)
+	if i, found := slices.BinarySearch(s.ids, id); found {
+		s.ids = slices.Delete(s.ids, i, i+1)
+	}
 	return nil
 }
 
-func (s *Storage) Posts(ctx context.Context) []*domain.Post {
+func (s *Storage) Posts(ctx context.Context) ([]*domain.Post, error) {
 	s.postsMtx.RLock()
 	defer s.postsMtx.RUnlock()
 
@@ -58,7 +236,214 @@ func (s *Storage) Posts(ctx context.Context) []*domain.Post {
 		posts = append(posts, p)
 	}
 
-	return posts
+	return posts, nil
+}
+
+// PostsPage returns posts in the order of q.Sort starting right after the q.After post
+func (s *Storage) PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error) {
+	var after *domain.Post
+	if q.After != 0 {
+		var err error
+		if after, err = q.Sort.Position(q.After, q.AfterKey); err != nil {
+			return nil, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+		}
+	}
+
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	ids := s.ids
+	switch {
+	case len(q.Tags) > 0:
+		// The tagged ids are ordered by id, in the other orders only the tagged posts are sorted
+		ids = s.taggedIds(q.Tags, q.TagMatch)
+		if q.Sort != domain.SortByID {
+			slices.SortFunc(ids, func(a, b domain.PostId) int { return q.Sort.Compare(s.posts[a], s.posts[b]) })
+		}
+	case q.Sort != domain.SortByID:
+		ids = s.sorted[q.Sort]
+	}
+
+	start, step := 0, 1
+	if q.Desc {
+		start, step = len(ids)-1, -1
+	}
+
+	if after != nil {
+		i, found := slices.BinarySearchFunc(ids, after, func(id domain.PostId, after *domain.Post) int {
+			return q.Sort.Compare(s.posts[id], after)
+		})
+		switch {
+		case q.Desc:
+			start = i - 1
+		case found:
+			start = i + 1
+		default:
+			start = i
+		}
+	}
+
+	page := &domain.Page{Posts: make([]*domain.Post, 0, min(q.Limit, len(ids)))}
+	for i := start; i >= 0 && i < len(ids); i += step {
+		p := s.posts[ids[i]]
+		if !q.Shows(p) || (q.Author != 0 && p.AuthorID != q.Author) {
+			continue
+		}
+
+		if len(page.Posts) == q.Limit {
+			page.HasMore = true
+			break
+		}
+		page.Posts = append(page.Posts, p)
+	}
+
+	return page, nil
+}
+
+// taggedIds returns a new slice of ids of posts matching the tags in ascending order
+func (s *Storage) taggedIds(tags []string, match domain.TagMatch) []domain.PostId {
+	if match == domain.MatchAnyTag {
+		var ids []domain.PostId
+		for _, tag := range tags {
+			ids = append(ids, s.tags[tag]...)
+		}
+		slices.Sort(ids)
+		return slices.Compact(ids)
+	}
+
+	// Every matching post is in the shortest list, so only its ids are checked
+	lists := make([][]domain.PostId, 0, len(tags))
+	for _, tag := range tags {
+		lists = append(lists, s.tags[tag])
+	}
+	slices.SortFunc(lists, func(a, b []domain.PostId) int { return len(a) - len(b) })
+
+	ids := make([]domain.PostId, 0, len(lists[0]))
+	for _, id := range lists[0] {
+		matches := true
+		for _, other := range lists[1:] {
+			if _, found := slices.BinarySearch(other, id); !found {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Tags returns the tags of published posts with the number of posts having them, the most used tags go first
+func (s *Storage) Tags(ctx context.Context) ([]*domain.TagCount, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	counts := make([]*domain.TagCount, 0, len(s.tags))
+	for tag, ids := range s.tags {
+		published := 0
+		for _, id := range ids {
+			if s.posts[id].Status == domain.StatusPublished {
+				published++
+			}
+		}
+
+		if published > 0 {
+			counts = append(counts, &domain.TagCount{Tag: tag, Posts: published})
+		}
+	}
+	SortTagCounts(counts)
+
+	return counts, nil
+}
+
+// StatusCounts returns the number of the posts by status, the statuses without posts are not listed
+func (s *Storage) StatusCounts(ctx context.Context) (map[domain.PostStatus]int, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	counts := map[domain.PostStatus]int{}
+	for _, p := range s.posts {
+		counts[p.Status]++
+	}
+
+	return counts, nil
+}
+
+// SortTagCounts orders the tags by the number of posts descending and then by name
+func SortTagCounts(counts []*domain.TagCount) {
+	slices.SortFunc(counts, func(a, b *domain.TagCount) int {
+		if a.Posts != b.Posts {
+			return b.Posts - a.Posts
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+}
+
+func (s *Storage) indexPost(p *domain.Post) {
+	s.indexTags(p)
+	for _, sort := range indexedSorts {
+		ids := s.sorted[sort]
+		i, _ := slices.BinarySearchFunc(ids, p, func(id domain.PostId, p *domain.Post) int { return sort.Compare(s.posts[id], p) })
+		s.sorted[sort] = slices.Insert(ids, i, p.ID)
+	}
+}
+
+// unindexPost removes the post from the indexes, it should be called before the stored post is replaced
+func (s *Storage) unindexPost(p *domain.Post) {
+	s.unindexTags(p)
+	for _, sort := range indexedSorts {
+		ids := s.sorted[sort]
+		i, found := slices.BinarySearchFunc(ids, p, func(id domain.PostId, p *domain.Post) int { return sort.Compare(s.posts[id], p) })
+		if found {
+			s.sorted[sort] = slices.Delete(ids, i, i+1)
+		}
+	}
+}
+
+func (s *Storage) indexTags(p *domain.Post) {
+	for _, tag := range p.Tags {
+		ids := s.tags[tag]
+		if i, found := slices.BinarySearch(ids, p.ID); !found {
+			s.tags[tag] = slices.Insert(ids, i, p.ID)
+		}
+	}
+}
+
+func (s *Storage) unindexTags(p *domain.Post) {
+	for _, tag := range p.Tags {
+		ids := s.tags[tag]
+		if i, found := slices.BinarySearch(ids, p.ID); found {
+			ids = slices.Delete(ids, i, i+1)
+		}
+
+		if len(ids) == 0 {
+			delete(s.tags, tag)
+		} else {
+			s.tags[tag] = ids
+		}
+	}
+}
+
+// ScheduledPosts returns the scheduled posts which publish time is not after the time, the earliest go first
+func (s *Storage) ScheduledPosts(ctx context.Context, until time.Time) ([]*domain.Post, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	posts := make([]*domain.Post, 0)
+	for _, p := range s.posts {
+		if p.Status == domain.StatusScheduled && !p.PublishAt.After(until) {
+			posts = append(posts, p)
+		}
+	}
+	slices.SortFunc(posts, func(a, b *domain.Post) int {
+		if c := a.PublishAt.Compare(b.PublishAt); c != 0 {
+			return c
+		}
+		return int(a.ID - b.ID)
+	})
+
+	return posts, nil
 }
 
 func (s *Storage) Post(ctx context.Context, id domain.PostId) (*domain.Post, error) {
@@ -76,26 +461,244 @@ func (s *Storage) Post(ctx context.Context, id domain.PostId) (*domain.Post, err
 func (s *Storage) CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error) {
 	nextPostId := s.nextAvailableId()
 	post.ID = nextPostId
+	post.Version = 1
+	post.CreatedAt = s.Now()
+	post.UpdatedAt = post.CreatedAt
 
 	s.postsMtx.Lock()
 	defer s.postsMtx.Unlock()
 
 	s.posts[nextPostId] = post
+	i, _ := slices.BinarySearch(s.ids, nextPostId)
+	s.ids = slices.Insert(s.ids, i, nextPostId)
+	s.indexPost(post)
 	return nextPostId, nil
 }
 
+// CreatePostWithID keeps the post with its id instead of allocating one, e.g. the post of a data migration.
+// The id should be positive and not taken, the sequence of ids is moved past it.
+// A post with the creation time is restored: it keeps its version and times, e.g. the post of an undone deletion.
+func (s *Storage) CreatePostWithID(ctx context.Context, post *domain.Post) error {
+	if post.ID <= 0 {
+		err := fmt.Errorf("post id should be positive. id: %v", post.ID)
+		return httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+	}
+
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	if _, exists := s.posts[post.ID]; exists {
+		return PostExistsError(post.ID)
+	}
+
+	if post.CreatedAt.IsZero() {
+		post.Version = 1
+		post.CreatedAt = s.Now()
+		post.UpdatedAt = post.CreatedAt
+	}
+	s.posts[post.ID] = post
+	i, _ := slices.BinarySearch(s.ids, post.ID)
+	s.ids = slices.Insert(s.ids, i, post.ID)
+	s.indexPost(post)
+	s.advanceSeqId(post.ID)
+	return nil
+}
+
+// AddRevision keeps the revision of the post. Revision numbers of a post should increase.
+func (s *Storage) AddRevision(ctx context.Context, rev *domain.Revision) error {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	if _, exists := s.posts[rev.PostID]; !exists {
+		err := fmt.Errorf("blog not found. id: %v", rev.PostID)
+		return httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	revisions := s.revisions[rev.PostID]
+	if len(revisions) > 0 && revisions[len(revisions)-1].Number >= rev.Number {
+		err := fmt.Errorf("revision already exists. id: %v, revision: %v", rev.PostID, rev.Number)
+		return httperr.WrapWithHttpCode(err, http.StatusConflict)
+	}
+
+	s.revisions[rev.PostID] = append(revisions, rev)
+	return nil
+}
+
+// Revisions returns revisions of the post ordered by number
+func (s *Storage) Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	return append([]*domain.Revision{}, s.revisions[id]...), nil
+}
+
+func (s *Storage) Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	revisions := s.revisions[id]
+	i, found := slices.BinarySearchFunc(revisions, number, func(r *domain.Revision, number int) int { return r.Number - number })
+	if !found {
+		err := fmt.Errorf("revision not found. id: %v, revision: %v", id, number)
+		return nil, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	return revisions[i], nil
+}
+
+// CreateComment keeps the comment assigning the next comment id to it.
+// The parent comment, if any, should belong to the same post.
+func (s *Storage) CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error) {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	if _, exists := s.posts[comment.PostID]; !exists {
+		err := fmt.Errorf("blog not found. id: %v", comment.PostID)
+		return 0, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	comments := s.comments[comment.PostID]
+	if comment.ParentID != 0 {
+		if _, found := findComment(comments, comment.ParentID); !found {
+			return 0, ParentCommentNotFoundError(comment.PostID, comment.ParentID)
+		}
+	}
+
+	comment.ID = domain.CommentId(s.commentSeqId)
+	s.commentSeqId++
+	s.comments[comment.PostID] = append(comments, comment)
+	return comment.ID, nil
+}
+
+func (s *Storage) Comment(ctx context.Context, postId domain.PostId, id domain.CommentId) (*domain.Comment, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	i, found := findComment(s.comments[postId], id)
+	if !found {
+		return nil, CommentNotFoundError(postId, id)
+	}
+
+	return s.comments[postId][i], nil
+}
+
+// Comments returns comments of the post ordered by id
+func (s *Storage) Comments(ctx context.Context, postId domain.PostId) ([]*domain.Comment, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	return append([]*domain.Comment{}, s.comments[postId]...), nil
+}
+
+// UpdateComment replaces the content and the update time of the comment, the rest of its fields are kept
+func (s *Storage) UpdateComment(ctx context.Context, comment *domain.Comment) error {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	comments := s.comments[comment.PostID]
+	i, found := findComment(comments, comment.ID)
+	if !found {
+		return CommentNotFoundError(comment.PostID, comment.ID)
+	}
+
+	updated := *comments[i]
+	updated.Content = comment.Content
+	updated.UpdatedAt = comment.UpdatedAt
+	comments[i] = &updated
+	return nil
+}
+
+// DeleteComment deletes the comment with all the replies to it
+func (s *Storage) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	if comments, ok := s.comments[postId]; ok {
+		s.comments[postId] = WithoutThread(comments, id)
+	}
+	return nil
+}
+
+// WithoutThread returns the comments ordered by id except the comment with the id and the replies to it
+func WithoutThread(comments []*domain.Comment, id domain.CommentId) []*domain.Comment {
+	deleted := map[domain.CommentId]bool{id: true}
+	kept := make([]*domain.Comment, 0, len(comments))
+	for _, c := range comments {
+		// A reply is created after its parent, so the parent is visited first
+		if deleted[c.ID] || deleted[c.ParentID] {
+			deleted[c.ID] = true
+			continue
+		}
+		kept = append(kept, c)
+	}
+
+	return kept
+}
+
+func findComment(comments []*domain.Comment, id domain.CommentId) (int, bool) {
+	return slices.BinarySearchFunc(comments, id, func(c *domain.Comment, id domain.CommentId) int { return int(c.ID - id) })
+}
+
 // nextAvailableId returns next post id which is guarantied to be not used yet
 func (s *Storage) nextAvailableId() domain.PostId {
 	s.postsMtx.RLock()
 	defer s.postsMtx.RUnlock()
 
 	for {
-		// Acquire next available seq Id
-		seqId := atomic.AddInt64(s.seqId, 1)
-		postId := domain.PostId(seqId - 1)
+		var postId domain.PostId
+		if s.allocator != nil {
+			postId = s.allocator.Next()
+		} else {
+			// Acquire next available seq Id
+			postId = domain.PostId(atomic.AddInt64(s.seqId, 1) - 1)
+		}
 
 		if _, busy := s.posts[postId]; !busy {
 			return postId
 		}
 	}
 }
+
+// SetIDAllocator replaces the sequence allocating the ids of new posts. It should be called before the storage is used.
+func (s *Storage) SetIDAllocator(a ids.Allocator) {
+	s.allocator = a
+}
+
+// SeqId returns the next id candidate of the sequence
+func (s *Storage) SeqId() int64 {
+	return atomic.LoadInt64(s.seqId)
+}
+
+// advanceSeqId moves the sequence past the id, so the sequence never hands out an id taken explicitly
+func (s *Storage) advanceSeqId(id domain.PostId) {
+	for {
+		seqId := atomic.LoadInt64(s.seqId)
+		if int64(id) < seqId || atomic.CompareAndSwapInt64(s.seqId, seqId, int64(id)+1) {
+			return
+		}
+	}
+}
+
+// PostExistsError is returned when a post is created with the id of an existing post
+func PostExistsError(id domain.PostId) error {
+	err := fmt.Errorf("blog already exists. id: %v", id)
+	return httperr.WrapWithCode(err, http.StatusConflict, httperr.CodePostExists)
+}
+
+// VersionMismatchError is returned when a conditional change is based on a version the post does not have
+func VersionMismatchError(id domain.PostId, version int) error {
+	err := fmt.Errorf("blog version mismatch. id: %v, version: %v", id, version)
+	return httperr.WrapWithCode(err, http.StatusPreconditionFailed, httperr.CodeVersionMismatch)
+}
+
+// CommentNotFoundError is returned when the post has no comment with the id
+func CommentNotFoundError(postId domain.PostId, id domain.CommentId) error {
+	err := fmt.Errorf("comment not found. post id: %v, id: %v", postId, id)
+	return httperr.WrapWithHttpCode(err, http.StatusNotFound)
+}
+
+// ParentCommentNotFoundError is returned when a reply is created to a comment the post does not have
+func ParentCommentNotFoundError(postId domain.PostId, parentId domain.CommentId) error {
+	err := fmt.Errorf("parent comment not found. post id: %v, parent id: %v", postId, parentId)
+	return httperr.WrapWithHttpCode(err, http.StatusUnprocessableEntity)
+}
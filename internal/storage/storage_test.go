@@ -3,10 +3,14 @@ package storage
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/blog"
 	"github.com/voltento/go-blog-project/internal/domain"
 	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/storage/storagetest"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestPost(t *testing.T) {
@@ -95,12 +99,12 @@ func TestStorageDeletePost(t *testing.T) {
 		id, err := s.CreatePost(context.Background(), post)
 		assert.NoError(t, err)
 
-		assert.NoError(t, s.DeletePost(context.Background(), id))
+		assert.NoError(t, s.DeletePost(context.Background(), id, domain.AnyVersion))
 	})
 
 	t.Run("Delete not existing post", func(t *testing.T) {
 		id := s.nextAvailableId()
-		assert.NoError(t, s.DeletePost(context.Background(), id))
+		assert.NoError(t, s.DeletePost(context.Background(), id, domain.AnyVersion))
 	})
 }
 
@@ -141,13 +145,124 @@ func TestStoragePosts(t *testing.T) {
 	ctx := context.Background()
 	s := NewStorage()
 
-	assert.Empty(t, s.Posts(ctx))
+	posts, err := s.Posts(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, posts)
 
-	_, err := s.CreatePost(ctx, &domain.Post{})
+	_, err = s.CreatePost(ctx, &domain.Post{})
+	assert.NoError(t, err)
+	posts, err = s.Posts(ctx)
 	assert.NoError(t, err)
-	assert.Len(t, s.Posts(ctx), 1)
+	assert.Len(t, posts, 1)
 
 	_, err = s.CreatePost(ctx, &domain.Post{})
 	assert.NoError(t, err)
-	assert.Len(t, s.Posts(ctx), 2)
+	posts, err = s.Posts(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+}
+
+func TestStoragePostsPage(t *testing.T) {
+	ctx := context.Background()
+	s := NewStorage()
+
+	for i := 0; i < 5; i++ {
+		_, err := s.CreatePost(ctx, &domain.Post{})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.DeletePost(ctx, 3, domain.AnyVersion))
+
+	ids := func(page *domain.Page) []domain.PostId {
+		var ids []domain.PostId
+		for _, p := range page.Posts {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	t.Run("First page", func(t *testing.T) {
+		page, err := s.PostsPage(ctx, domain.PostsQuery{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.PostId{1, 2}, ids(page))
+		assert.True(t, page.HasMore)
+	})
+
+	t.Run("Page after deleted post", func(t *testing.T) {
+		page, err := s.PostsPage(ctx, domain.PostsQuery{After: 3, Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.PostId{4, 5}, ids(page))
+		assert.False(t, page.HasMore)
+	})
+
+	t.Run("Page after the last post", func(t *testing.T) {
+		page, err := s.PostsPage(ctx, domain.PostsQuery{After: 5, Limit: 2})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Posts)
+		assert.False(t, page.HasMore)
+	})
+}
+
+func TestStorageSuite(t *testing.T) {
+	suite.Run(t, &storagetest.Suite{
+		NewStorage: func(t *testing.T, now func() time.Time) blog.Storage {
+			s := NewStorage()
+			s.SetClock(now)
+			return s
+		},
+	})
+}
+
+func TestStorageState(t *testing.T) {
+	ctx := context.Background()
+	s := NewStorage()
+
+	for i := 0; i < 3; i++ {
+		_, err := s.CreatePost(ctx, &domain.Post{Title: "title"})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.DeletePost(ctx, 3, domain.AnyVersion))
+
+	state := s.State()
+	assert.Equal(t, int64(4), state.SeqId)
+	assert.Len(t, state.Posts, 2)
+
+	restored := NewStorageFromState(state)
+	assert.Equal(t, state, restored.State())
+
+	id, err := restored.CreatePost(ctx, &domain.Post{})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PostId(4), id, "ids of deleted posts should not be reused")
+}
+
+func TestNewStorageFromState_PostsWithoutStatus(t *testing.T) {
+	s := NewStorageFromState(State{SeqId: 2, Posts: []*domain.Post{{ID: 1, Title: "title", Version: 1}}})
+
+	post, err := s.Post(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusPublished, post.Status, "posts stored before statuses were introduced should be published")
+}
+
+func TestNewStorageFromState_SortIndexes(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s := NewStorageFromState(State{SeqId: 4, Posts: []*domain.Post{
+		{ID: 1, Title: "b", CreatedAt: at.Add(time.Hour), UpdatedAt: at.Add(time.Hour), Version: 1},
+		{ID: 2, Title: "c", CreatedAt: at, UpdatedAt: at.Add(2 * time.Hour), Version: 2},
+		// Posts stored before timestamps were introduced go first
+		{ID: 3, Title: "a", Version: 1},
+	}})
+
+	for sort, expected := range map[domain.PostSort][]domain.PostId{
+		domain.SortByCreatedAt: {3, 2, 1},
+		domain.SortByUpdatedAt: {3, 1, 2},
+		domain.SortByTitle:     {3, 1, 2},
+	} {
+		page, err := s.PostsPage(context.Background(), domain.PostsQuery{Sort: sort, Limit: 10})
+		assert.NoError(t, err)
+
+		ids := make([]domain.PostId, 0, len(page.Posts))
+		for _, p := range page.Posts {
+			ids = append(ids, p.ID)
+		}
+		assert.Equal(t, expected, ids, "sort %v", sort)
+	}
 }
@@ -0,0 +1,830 @@
+// Package storagetest keeps behavioral tests which every blog.Storage implementation should pass
+package storagetest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/ids"
+)
+
+// Suite runs the storage tests against the storage returned by NewStorage.
+// NewStorage is called for every test and should return an empty storage timestamping the posts with now.
+type Suite struct {
+	suite.Suite
+	NewStorage func(t *testing.T, now func() time.Time) blog.Storage
+
+	storage blog.Storage
+	ctx     context.Context
+	// now is the time of the storage clock, the tests move it forward
+	now time.Time
+}
+
+func (s *Suite) SetupTest() {
+	s.now = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.storage = s.NewStorage(s.T(), func() time.Time { return s.now })
+	s.ctx = context.Background()
+}
+
+func (s *Suite) createPost(title string) domain.PostId {
+	id, err := s.storage.CreatePost(s.ctx, &domain.Post{Title: title, Content: title + " content", Author: title + " author"})
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *Suite) TestCreatePost() {
+	id, err := s.storage.CreatePost(s.ctx, &domain.Post{Title: "Title", Content: "Content", Author: "Author"})
+	s.Require().NoError(err)
+
+	post, err := s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal(domain.Post{ID: id, Title: "Title", Content: "Content", Author: "Author", CreatedAt: s.now, UpdatedAt: s.now, Version: 1}, *post)
+}
+
+func (s *Suite) TestCreatePost_SequentialIds() {
+	s.Equal(domain.PostId(1), s.createPost("first"))
+	s.Equal(domain.PostId(2), s.createPost("second"))
+	s.Equal(domain.PostId(3), s.createPost("third"))
+}
+
+func (s *Suite) TestCreatePost_IgnoresPassedId() {
+	id, err := s.storage.CreatePost(s.ctx, &domain.Post{ID: 10, Title: "Title", Content: "Content", Author: "Author"})
+	s.Require().NoError(err)
+	s.Equal(domain.PostId(1), id)
+}
+
+func (s *Suite) TestCreatePostWithID() {
+	post := &domain.Post{ID: 10, Title: "Title", Content: "Content", Author: "Author", Tags: []string{"go"}}
+	s.Require().NoError(s.storage.CreatePostWithID(s.ctx, post))
+	s.Equal(1, post.Version)
+
+	stored, err := s.storage.Post(s.ctx, 10)
+	s.Require().NoError(err)
+	s.Equal(domain.Post{ID: 10, Title: "Title", Content: "Content", Author: "Author", Tags: []string{"go"}, CreatedAt: s.now, UpdatedAt: s.now, Version: 1}, *stored)
+
+	s.Equal(domain.PostId(11), s.createPost("next"), "sequence should move past the explicit id")
+
+	s.Require().NoError(s.storage.CreatePostWithID(s.ctx, &domain.Post{ID: 5, Title: "Lower", Content: "Content"}))
+	s.Equal(domain.PostId(12), s.createPost("after lower"), "lower explicit id should not move the sequence back")
+}
+
+func (s *Suite) TestCreatePostWithID_Restored() {
+	createdAt := s.now.Add(-2 * time.Hour)
+	post := &domain.Post{ID: 10, Title: "Title", Content: "Content", CreatedAt: createdAt, UpdatedAt: createdAt.Add(time.Hour), Version: 4}
+	s.Require().NoError(s.storage.CreatePostWithID(s.ctx, post))
+
+	stored, err := s.storage.Post(s.ctx, 10)
+	s.Require().NoError(err)
+	s.Equal(4, stored.Version)
+	s.Equal(createdAt, stored.CreatedAt)
+	s.Equal(createdAt.Add(time.Hour), stored.UpdatedAt)
+
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "Updated", Content: "Content", Version: 4}, 10))
+}
+
+func (s *Suite) TestCreatePostWithID_Taken() {
+	id := s.createPost("first")
+
+	err := s.storage.CreatePostWithID(s.ctx, &domain.Post{ID: id, Title: "Other", Content: "Content"})
+	s.Equal(http.StatusConflict, httperr.HTTPStatusCode(err, -1))
+
+	post, err := s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal("first", post.Title)
+}
+
+func (s *Suite) TestCreatePostWithID_NotPositive() {
+	err := s.storage.CreatePostWithID(s.ctx, &domain.Post{Title: "Title", Content: "Content"})
+	s.Equal(http.StatusBadRequest, httperr.HTTPStatusCode(err, -1))
+}
+
+// fixedAllocator hands out the ids in order
+type fixedAllocator struct {
+	ids []domain.PostId
+}
+
+func (a *fixedAllocator) Next() domain.PostId {
+	id := a.ids[0]
+	a.ids = a.ids[1:]
+	return id
+}
+
+func (s *Suite) TestCreatePost_IDAllocator() {
+	st, ok := s.storage.(interface{ SetIDAllocator(a ids.Allocator) })
+	s.Require().True(ok, "storage should support id allocators")
+	st.SetIDAllocator(&fixedAllocator{ids: []domain.PostId{1 << 40, 1 << 40, 1<<40 + 7}})
+
+	s.Equal(domain.PostId(1<<40), s.createPost("first"))
+	s.Equal(domain.PostId(1<<40+7), s.createPost("second"), "taken id should be skipped")
+}
+
+func (s *Suite) TestCreatePost_DeletedIdIsNotReused() {
+	s.createPost("first")
+	id := s.createPost("second")
+	s.Require().NoError(s.storage.DeletePost(s.ctx, id, domain.AnyVersion))
+
+	s.Equal(id+1, s.createPost("third"))
+}
+
+func (s *Suite) TestPost_NotFound() {
+	post, err := s.storage.Post(s.ctx, 1)
+	s.Nil(post)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestUpdatePost() {
+	id := s.createPost("title")
+	createdAt := s.now
+	s.now = s.now.Add(time.Hour)
+
+	err := s.storage.UpdatePost(s.ctx, &domain.Post{Title: "Updated", Content: "Updated content", Author: "Updated author"}, id)
+	s.Require().NoError(err)
+
+	post, err := s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal(domain.Post{
+		ID: id, Title: "Updated", Content: "Updated content", Author: "Updated author", CreatedAt: createdAt, UpdatedAt: s.now, Version: 2,
+	}, *post)
+}
+
+func (s *Suite) TestUpdatePost_Version() {
+	id := s.createPost("title")
+
+	post := &domain.Post{Title: "Updated", Content: "Updated content", Author: "Updated author", Version: 1}
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, post, id))
+	s.Equal(2, post.Version, "the new version should be set to the post")
+
+	err := s.storage.UpdatePost(s.ctx, &domain.Post{Title: "Stale", Version: 1}, id)
+	s.Equal(http.StatusPreconditionFailed, httperr.HTTPStatusCode(err, -1))
+
+	stored, err := s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal("Updated", stored.Title, "the stale update should not be applied")
+	s.Equal(2, stored.Version)
+}
+
+func (s *Suite) TestUpdatePost_VersionNotFound() {
+	err := s.storage.UpdatePost(s.ctx, &domain.Post{Title: "Updated", Version: 1}, 1)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestUpdatePost_NotFound() {
+	err := s.storage.UpdatePost(s.ctx, &domain.Post{Title: "Updated"}, 1)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestDeletePost() {
+	id := s.createPost("title")
+
+	s.Require().NoError(s.storage.DeletePost(s.ctx, id, domain.AnyVersion))
+
+	_, err := s.storage.Post(s.ctx, id)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestDeletePost_NotExisting() {
+	s.NoError(s.storage.DeletePost(s.ctx, 1, domain.AnyVersion))
+}
+
+func (s *Suite) TestDeletePost_Version() {
+	id := s.createPost("title")
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "Updated"}, id))
+
+	err := s.storage.DeletePost(s.ctx, id, 1)
+	s.Equal(http.StatusPreconditionFailed, httperr.HTTPStatusCode(err, -1))
+	_, err = s.storage.Post(s.ctx, id)
+	s.Require().NoError(err, "the post should not be deleted on version mismatch")
+
+	s.Require().NoError(s.storage.DeletePost(s.ctx, id, 2))
+	_, err = s.storage.Post(s.ctx, id)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+
+	err = s.storage.DeletePost(s.ctx, id, 2)
+	s.Equal(http.StatusPreconditionFailed, httperr.HTTPStatusCode(err, -1), "a deleted post has no version")
+}
+
+func (s *Suite) TestPosts() {
+	posts, err := s.storage.Posts(s.ctx)
+	s.Require().NoError(err)
+	s.Empty(posts)
+
+	s.createPost("first")
+	s.createPost("second")
+
+	posts, err = s.storage.Posts(s.ctx)
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{"first", "second"}, titles(posts))
+}
+
+func (s *Suite) TestPostsPage() {
+	for _, title := range []string{"1", "2", "3", "4", "5"} {
+		s.createPost(title)
+	}
+	s.Require().NoError(s.storage.DeletePost(s.ctx, 3, domain.AnyVersion))
+
+	page, err := s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 2})
+	s.Require().NoError(err)
+	s.Equal([]string{"1", "2"}, titles(page.Posts))
+	s.True(page.HasMore)
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{After: 2, Limit: 2})
+	s.Require().NoError(err)
+	s.Equal([]string{"4", "5"}, titles(page.Posts))
+	s.False(page.HasMore)
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{After: 5, Limit: 2})
+	s.Require().NoError(err)
+	s.Empty(page.Posts)
+	s.False(page.HasMore)
+}
+
+func (s *Suite) TestPostsPage_Sort() {
+	// The posts are created an hour apart, so the creation order differs from the order of titles
+	for _, title := range []string{"b", "d", "a", "c", "a"} {
+		s.createPost(title)
+		s.now = s.now.Add(time.Hour)
+	}
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "d"}, 2))
+	s.now = s.now.Add(time.Hour)
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "b"}, 1))
+
+	tests := []struct {
+		name string
+		sort domain.PostSort
+		desc bool
+		ids  []domain.PostId
+	}{
+		{name: "Created at", sort: domain.SortByCreatedAt, ids: []domain.PostId{1, 2, 3, 4, 5}},
+		{name: "Created at desc", sort: domain.SortByCreatedAt, desc: true, ids: []domain.PostId{5, 4, 3, 2, 1}},
+		{name: "Updated at", sort: domain.SortByUpdatedAt, ids: []domain.PostId{3, 4, 5, 2, 1}},
+		{name: "Updated at desc", sort: domain.SortByUpdatedAt, desc: true, ids: []domain.PostId{1, 2, 5, 4, 3}},
+		{name: "Title, equal titles by id", sort: domain.SortByTitle, ids: []domain.PostId{3, 5, 1, 4, 2}},
+		{name: "Title desc", sort: domain.SortByTitle, desc: true, ids: []domain.PostId{2, 4, 1, 5, 3}},
+		{name: "ID desc", sort: domain.SortByID, desc: true, ids: []domain.PostId{5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			// Pages of two posts are walked to check the pages start right after the previous ones
+			var ids []domain.PostId
+			q := domain.PostsQuery{Sort: tt.sort, Desc: tt.desc, Limit: 2}
+			for {
+				page, err := s.storage.PostsPage(s.ctx, q)
+				s.Require().NoError(err)
+				for _, p := range page.Posts {
+					ids = append(ids, p.ID)
+				}
+
+				if !page.HasMore {
+					break
+				}
+				last := page.Posts[len(page.Posts)-1]
+				q.After, q.AfterKey = last.ID, tt.sort.Key(last)
+			}
+
+			s.Equal(tt.ids, ids)
+		})
+	}
+}
+
+func (s *Suite) TestPostsPage_SortTags() {
+	s.createTaggedPost("c", "go")
+	s.createTaggedPost("b", "go", "k8s")
+	s.createTaggedPost("a", "k8s")
+
+	page, err := s.storage.PostsPage(s.ctx, domain.PostsQuery{Sort: domain.SortByTitle, Limit: 10, Tags: []string{"go"}})
+	s.Require().NoError(err)
+	s.Equal([]string{"b", "c"}, titles(page.Posts))
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{
+		Sort: domain.SortByTitle, Limit: 10, Tags: []string{"go", "k8s"}, TagMatch: domain.MatchAnyTag,
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{"a", "b", "c"}, titles(page.Posts))
+
+	// The pages of the tagged posts go on after the cursor
+	q := domain.PostsQuery{Sort: domain.SortByTitle, Desc: true, Limit: 1, Tags: []string{"go"}}
+	page, err = s.storage.PostsPage(s.ctx, q)
+	s.Require().NoError(err)
+	s.Equal([]string{"c"}, titles(page.Posts))
+	s.True(page.HasMore)
+
+	q.After, q.AfterKey = page.Posts[0].ID, q.Sort.Key(page.Posts[0])
+	page, err = s.storage.PostsPage(s.ctx, q)
+	s.Require().NoError(err)
+	s.Equal([]string{"b"}, titles(page.Posts))
+	s.False(page.HasMore)
+}
+
+func (s *Suite) TestPostsPage_InvalidSortKey() {
+	s.createPost("title")
+
+	_, err := s.storage.PostsPage(s.ctx, domain.PostsQuery{After: 1, AfterKey: "yesterday", Sort: domain.SortByCreatedAt, Limit: 10})
+	s.Equal(http.StatusBadRequest, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) createTaggedPost(title string, tags ...string) domain.PostId {
+	post := &domain.Post{Title: title, Content: "content", Author: "author", Tags: tags, Status: domain.StatusPublished}
+	id, err := s.storage.CreatePost(s.ctx, post)
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *Suite) TestPostTags() {
+	id := s.createTaggedPost("title", "go", "k8s")
+
+	post, err := s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal([]string{"go", "k8s"}, post.Tags)
+
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "title", Tags: []string{"rust"}}, id))
+
+	posts, err := s.storage.Posts(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Len(posts, 1)
+	s.Equal([]string{"rust"}, posts[0].Tags)
+}
+
+func (s *Suite) TestPostsPage_Tags() {
+	s.createTaggedPost("1", "go")
+	s.createTaggedPost("2", "go", "k8s")
+	s.createTaggedPost("3", "k8s")
+	s.createTaggedPost("4")
+	s.createTaggedPost("5", "go", "k8s")
+	s.createTaggedPost("6", "go", "k8s", "rust")
+
+	page, err := s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 2, Tags: []string{"go", "k8s"}})
+	s.Require().NoError(err)
+	s.Equal([]string{"2", "5"}, titles(page.Posts))
+	s.True(page.HasMore)
+	s.Equal([]string{"go", "k8s"}, page.Posts[0].Tags)
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{After: 5, Limit: 2, Tags: []string{"go", "k8s"}})
+	s.Require().NoError(err)
+	s.Equal([]string{"6"}, titles(page.Posts))
+	s.False(page.HasMore)
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 10, Tags: []string{"rust", "k8s"}, TagMatch: domain.MatchAnyTag})
+	s.Require().NoError(err)
+	s.Equal([]string{"2", "3", "5", "6"}, titles(page.Posts))
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 10, Tags: []string{"python"}})
+	s.Require().NoError(err)
+	s.Empty(page.Posts)
+}
+
+func (s *Suite) TestTags() {
+	tags, err := s.storage.Tags(s.ctx)
+	s.Require().NoError(err)
+	s.NotNil(tags)
+	s.Empty(tags)
+
+	s.createTaggedPost("1", "go", "k8s")
+	s.createTaggedPost("2", "go", "rust")
+	s.createTaggedPost("3", "go")
+	deleted := s.createTaggedPost("4", "k8s", "python")
+	updated := s.createTaggedPost("5", "k8s")
+	drafted := s.createTaggedPost("6", "go", "python")
+	s.Require().NoError(s.storage.DeletePost(s.ctx, deleted, domain.AnyVersion))
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "5", Tags: []string{"rust"}, Status: domain.StatusPublished}, updated))
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "6", Tags: []string{"go", "python"}, Status: domain.StatusDraft}, drafted))
+
+	tags, err = s.storage.Tags(s.ctx)
+	s.Require().NoError(err)
+	s.Equal([]*domain.TagCount{
+		{Tag: "go", Posts: 3},
+		{Tag: "rust", Posts: 2},
+		{Tag: "k8s", Posts: 1},
+	}, tags)
+}
+
+func (s *Suite) TestStatusCounts() {
+	counts, err := s.storage.StatusCounts(s.ctx)
+	s.Require().NoError(err)
+	s.Empty(counts)
+
+	s.createPostWithStatus("1", 0, domain.StatusPublished, time.Time{})
+	s.createPostWithStatus("2", 0, domain.StatusPublished, time.Time{})
+	s.createPostWithStatus("3", 0, domain.StatusDraft, time.Time{})
+	deleted := s.createPostWithStatus("4", 0, domain.StatusDraft, time.Time{})
+	s.createPostWithStatus("5", 0, domain.StatusScheduled, s.now.Add(time.Hour))
+	s.Require().NoError(s.storage.DeletePost(s.ctx, deleted, domain.AnyVersion))
+
+	counts, err = s.storage.StatusCounts(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(map[domain.PostStatus]int{domain.StatusPublished: 2, domain.StatusDraft: 1, domain.StatusScheduled: 1}, counts)
+}
+
+func (s *Suite) createPostWithStatus(title string, authorId domain.UserId, status domain.PostStatus, publishAt time.Time) domain.PostId {
+	post := &domain.Post{Title: title, AuthorID: authorId, Status: status, PublishAt: publishAt}
+	id, err := s.storage.CreatePost(s.ctx, post)
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *Suite) TestPostStatus() {
+	publishAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	id := s.createPostWithStatus("title", 1, domain.StatusScheduled, publishAt)
+
+	post, err := s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal(domain.StatusScheduled, post.Status)
+	s.Equal(publishAt, post.PublishAt)
+
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "title", AuthorID: 1, Status: domain.StatusDraft}, id))
+	post, err = s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal(domain.StatusDraft, post.Status)
+	s.True(post.PublishAt.IsZero())
+}
+
+func (s *Suite) TestPostsPage_PublishedOnly() {
+	publishAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s.createPostWithStatus("published", 1, domain.StatusPublished, publishAt)
+	s.createPostWithStatus("own draft", 1, domain.StatusDraft, time.Time{})
+	s.createPostWithStatus("draft", 2, domain.StatusDraft, time.Time{})
+	s.createPostWithStatus("scheduled", 2, domain.StatusScheduled, publishAt)
+	s.createPostWithStatus("archived", 0, domain.StatusArchived, publishAt)
+	s.createPostWithStatus("last", 2, domain.StatusPublished, publishAt)
+
+	page, err := s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 10, PublishedOnly: true})
+	s.Require().NoError(err)
+	s.Equal([]string{"published", "last"}, titles(page.Posts))
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 2, PublishedOnly: true, Viewer: 1})
+	s.Require().NoError(err)
+	s.Equal([]string{"published", "own draft"}, titles(page.Posts))
+	s.True(page.HasMore)
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{After: 2, Limit: 2, PublishedOnly: true, Viewer: 1})
+	s.Require().NoError(err)
+	s.Equal([]string{"last"}, titles(page.Posts))
+	s.False(page.HasMore)
+
+	page, err = s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 10})
+	s.Require().NoError(err)
+	s.Len(page.Posts, 6)
+}
+
+func (s *Suite) TestPostsPage_Author() {
+	s.createPostWithStatus("first", 1, domain.StatusPublished, time.Time{})
+	s.createPostWithStatus("other", 2, domain.StatusPublished, time.Time{})
+	s.createPostWithStatus("second", 1, domain.StatusPublished, time.Time{})
+	s.createPostWithStatus("draft", 1, domain.StatusDraft, time.Time{})
+
+	page, err := s.storage.PostsPage(s.ctx, domain.PostsQuery{Limit: 10, Author: 1, PublishedOnly: true})
+	s.Require().NoError(err)
+	s.Equal([]string{"first", "second"}, titles(page.Posts))
+}
+
+func (s *Suite) TestScheduledPosts() {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s.createPostWithStatus("later", 1, domain.StatusScheduled, now.Add(time.Minute))
+	s.createPostWithStatus("now", 1, domain.StatusScheduled, now)
+	s.createPostWithStatus("earlier", 1, domain.StatusScheduled, now.Add(-time.Hour))
+	s.createPostWithStatus("published", 1, domain.StatusPublished, now.Add(-time.Hour))
+
+	posts, err := s.storage.ScheduledPosts(s.ctx, now)
+	s.Require().NoError(err)
+	s.Equal([]string{"earlier", "now"}, titles(posts))
+}
+
+func (s *Suite) revision(id domain.PostId, number int) *domain.Revision {
+	return &domain.Revision{
+		PostID:    id,
+		Number:    number,
+		Title:     "title",
+		Content:   "content",
+		Author:    "author",
+		CreatedAt: time.Date(2024, 5, 1, 10, 0, number, 0, time.UTC),
+		Diff:      "+content\n",
+	}
+}
+
+func (s *Suite) TestRevisions() {
+	id := s.createPost("title")
+
+	revisions, err := s.storage.Revisions(s.ctx, id)
+	s.Require().NoError(err)
+	s.NotNil(revisions)
+	s.Empty(revisions)
+
+	first, second := s.revision(id, 1), s.revision(id, 3)
+	s.Require().NoError(s.storage.AddRevision(s.ctx, first))
+	s.Require().NoError(s.storage.AddRevision(s.ctx, second))
+
+	revisions, err = s.storage.Revisions(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal([]*domain.Revision{first, second}, revisions)
+
+	rev, err := s.storage.Revision(s.ctx, id, 3)
+	s.Require().NoError(err)
+	s.Equal(second, rev)
+}
+
+func (s *Suite) TestRevision_NotFound() {
+	id := s.createPost("title")
+	s.Require().NoError(s.storage.AddRevision(s.ctx, s.revision(id, 1)))
+
+	_, err := s.storage.Revision(s.ctx, id, 2)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestAddRevision_PostNotFound() {
+	err := s.storage.AddRevision(s.ctx, s.revision(1, 1))
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestAddRevision_NumberShouldIncrease() {
+	id := s.createPost("title")
+	s.Require().NoError(s.storage.AddRevision(s.ctx, s.revision(id, 2)))
+
+	err := s.storage.AddRevision(s.ctx, s.revision(id, 2))
+	s.Equal(http.StatusConflict, httperr.HTTPStatusCode(err, -1))
+
+	err = s.storage.AddRevision(s.ctx, s.revision(id, 1))
+	s.Equal(http.StatusConflict, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestDeletePost_DeletesRevisions() {
+	id := s.createPost("title")
+	s.Require().NoError(s.storage.AddRevision(s.ctx, s.revision(id, 1)))
+
+	s.Require().NoError(s.storage.DeletePost(s.ctx, id, domain.AnyVersion))
+
+	revisions, err := s.storage.Revisions(s.ctx, id)
+	s.Require().NoError(err)
+	s.Empty(revisions)
+}
+
+func (s *Suite) createComment(postId domain.PostId, parentId domain.CommentId, content string) domain.CommentId {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	id, err := s.storage.CreateComment(s.ctx, &domain.Comment{
+		PostID:    postId,
+		ParentID:  parentId,
+		Author:    "author",
+		Content:   content,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	})
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *Suite) TestCreateComment() {
+	postId := s.createPost("title")
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	comment := &domain.Comment{PostID: postId, Author: "author", Content: "content", CreatedAt: createdAt, UpdatedAt: createdAt}
+	id, err := s.storage.CreateComment(s.ctx, comment)
+	s.Require().NoError(err)
+	s.Equal(domain.CommentId(1), id)
+	s.Equal(id, comment.ID)
+
+	stored, err := s.storage.Comment(s.ctx, postId, id)
+	s.Require().NoError(err)
+	s.Equal(comment, stored)
+
+	s.Equal(domain.CommentId(2), s.createComment(postId, id, "reply"))
+}
+
+func (s *Suite) TestCreateComment_PostNotFound() {
+	_, err := s.storage.CreateComment(s.ctx, &domain.Comment{PostID: 1, Content: "content"})
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestCreateComment_ParentNotFound() {
+	postId := s.createPost("first")
+	otherPostId := s.createPost("second")
+	otherId := s.createComment(otherPostId, 0, "content")
+
+	_, err := s.storage.CreateComment(s.ctx, &domain.Comment{PostID: postId, ParentID: 10, Content: "reply"})
+	s.Equal(http.StatusUnprocessableEntity, httperr.HTTPStatusCode(err, -1))
+
+	_, err = s.storage.CreateComment(s.ctx, &domain.Comment{PostID: postId, ParentID: otherId, Content: "reply"})
+	s.Equal(http.StatusUnprocessableEntity, httperr.HTTPStatusCode(err, -1), "the parent should belong to the same post")
+}
+
+func (s *Suite) TestComment_NotFound() {
+	postId := s.createPost("first")
+	otherPostId := s.createPost("second")
+	id := s.createComment(otherPostId, 0, "content")
+
+	_, err := s.storage.Comment(s.ctx, postId, id)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestComments() {
+	postId := s.createPost("first")
+	otherPostId := s.createPost("second")
+
+	comments, err := s.storage.Comments(s.ctx, postId)
+	s.Require().NoError(err)
+	s.NotNil(comments)
+	s.Empty(comments)
+
+	first := s.createComment(postId, 0, "first")
+	s.createComment(otherPostId, 0, "other")
+	s.createComment(postId, first, "reply")
+	s.createComment(postId, 0, "second")
+
+	comments, err = s.storage.Comments(s.ctx, postId)
+	s.Require().NoError(err)
+	s.Equal([]string{"first", "reply", "second"}, contents(comments))
+}
+
+func (s *Suite) TestUpdateComment() {
+	postId := s.createPost("title")
+	id := s.createComment(postId, 0, "content")
+	updatedAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+
+	err := s.storage.UpdateComment(s.ctx, &domain.Comment{ID: id, PostID: postId, Content: "updated", Author: "ignored", UpdatedAt: updatedAt})
+	s.Require().NoError(err)
+
+	stored, err := s.storage.Comment(s.ctx, postId, id)
+	s.Require().NoError(err)
+	s.Equal("updated", stored.Content)
+	s.Equal(updatedAt, stored.UpdatedAt)
+	s.Equal("author", stored.Author, "only the content should be changed")
+	s.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), stored.CreatedAt)
+}
+
+func (s *Suite) TestUpdateComment_NotFound() {
+	postId := s.createPost("title")
+
+	err := s.storage.UpdateComment(s.ctx, &domain.Comment{ID: 1, PostID: postId, Content: "updated"})
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestDeleteComment_DeletesReplies() {
+	postId := s.createPost("title")
+	first := s.createComment(postId, 0, "first")
+	reply := s.createComment(postId, first, "reply")
+	s.createComment(postId, 0, "second")
+	s.createComment(postId, reply, "reply to reply")
+
+	s.Require().NoError(s.storage.DeleteComment(s.ctx, postId, first))
+
+	comments, err := s.storage.Comments(s.ctx, postId)
+	s.Require().NoError(err)
+	s.Equal([]string{"second"}, contents(comments))
+}
+
+func (s *Suite) TestDeleteComment_NotExisting() {
+	postId := s.createPost("title")
+	s.NoError(s.storage.DeleteComment(s.ctx, postId, 1))
+}
+
+func (s *Suite) TestDeletePost_DeletesComments() {
+	postId := s.createPost("title")
+	s.createComment(postId, 0, "content")
+
+	s.Require().NoError(s.storage.DeletePost(s.ctx, postId, domain.AnyVersion))
+
+	comments, err := s.storage.Comments(s.ctx, postId)
+	s.Require().NoError(err)
+	s.Empty(comments)
+}
+
+func (s *Suite) createUser(username string) domain.UserId {
+	id, err := s.storage.CreateUser(s.ctx, &domain.User{
+		Username:     username,
+		DisplayName:  username + " name",
+		PasswordHash: "hash",
+		CreatedAt:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	})
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *Suite) TestCreateUser() {
+	user := &domain.User{
+		Username:     "alice",
+		DisplayName:  "Alice",
+		Bio:          "bio",
+		PasswordHash: "hash",
+		CreatedAt:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+	id, err := s.storage.CreateUser(s.ctx, user)
+	s.Require().NoError(err)
+	s.Equal(domain.UserId(1), id)
+	s.Equal(id, user.ID)
+
+	stored, err := s.storage.User(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal(user, stored)
+
+	stored, err = s.storage.UserByUsername(s.ctx, "alice")
+	s.Require().NoError(err)
+	s.Equal(user, stored)
+
+	s.Equal(domain.UserId(2), s.createUser("bob"))
+}
+
+func (s *Suite) TestCreateUser_UsernameTaken() {
+	s.createUser("alice")
+
+	_, err := s.storage.CreateUser(s.ctx, &domain.User{Username: "alice"})
+	s.Equal(http.StatusConflict, httperr.HTTPStatusCode(err, -1))
+	s.Equal(domain.UserId(2), s.createUser("bob"), "a failed signup should not break the id sequence")
+}
+
+func (s *Suite) TestUser_NotFound() {
+	_, err := s.storage.User(s.ctx, 1)
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+
+	_, err = s.storage.UserByUsername(s.ctx, "alice")
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestUpdateUser() {
+	id := s.createUser("alice")
+
+	err := s.storage.UpdateUser(s.ctx, &domain.User{ID: id, Username: "ignored", DisplayName: "Alice", Bio: "bio", PasswordHash: "new hash"})
+	s.Require().NoError(err)
+
+	stored, err := s.storage.User(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal(&domain.User{
+		ID:           id,
+		Username:     "alice",
+		DisplayName:  "Alice",
+		Bio:          "bio",
+		PasswordHash: "new hash",
+		CreatedAt:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}, stored)
+}
+
+func (s *Suite) TestUpdateUser_NotFound() {
+	err := s.storage.UpdateUser(s.ctx, &domain.User{ID: 1, DisplayName: "Alice"})
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+}
+
+func (s *Suite) TestMigrationLedger() {
+	_, err := s.storage.MigrationRecord(s.ctx, "posts-2024")
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+
+	rec := &domain.MigrationRecord{
+		ID:         "posts-2024",
+		Checksum:   "checksum",
+		AppliedAt:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Operations: 3,
+	}
+	s.Require().NoError(s.storage.AddMigrationRecord(s.ctx, rec))
+
+	stored, err := s.storage.MigrationRecord(s.ctx, "posts-2024")
+	s.Require().NoError(err)
+	s.Equal(rec, stored)
+
+	err = s.storage.AddMigrationRecord(s.ctx, &domain.MigrationRecord{ID: "posts-2024", Checksum: "other"})
+	s.Equal(http.StatusConflict, httperr.HTTPStatusCode(err, -1))
+
+	stored, err = s.storage.MigrationRecord(s.ctx, "posts-2024")
+	s.Require().NoError(err)
+	s.Equal("checksum", stored.Checksum, "a rejected record should not replace the applied one")
+
+	s.Require().NoError(s.storage.DeleteMigrationRecord(s.ctx, "posts-2024"))
+	_, err = s.storage.MigrationRecord(s.ctx, "posts-2024")
+	s.Equal(http.StatusNotFound, httperr.HTTPStatusCode(err, -1))
+	s.NoError(s.storage.DeleteMigrationRecord(s.ctx, "posts-2024"), "deletion of a missing record should not fail")
+	s.NoError(s.storage.AddMigrationRecord(s.ctx, rec), "deleted record should be added again")
+}
+
+func (s *Suite) TestPostAuthorId() {
+	authorId := s.createUser("alice")
+	id, err := s.storage.CreatePost(s.ctx, &domain.Post{Title: "Title", AuthorID: authorId, Author: "Alice"})
+	s.Require().NoError(err)
+	s.Require().NoError(s.storage.UpdatePost(s.ctx, &domain.Post{Title: "Updated", AuthorID: authorId, Author: "Alice"}, id))
+
+	post, err := s.storage.Post(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal(authorId, post.AuthorID)
+
+	commentId, err := s.storage.CreateComment(s.ctx, &domain.Comment{PostID: id, AuthorID: authorId, Content: "content"})
+	s.Require().NoError(err)
+	comment, err := s.storage.Comment(s.ctx, id, commentId)
+	s.Require().NoError(err)
+	s.Equal(authorId, comment.AuthorID)
+}
+
+func contents(comments []*domain.Comment) []string {
+	contents := make([]string, 0, len(comments))
+	for _, c := range comments {
+		contents = append(contents, c.Content)
+	}
+	return contents
+}
+
+func titles(posts []*domain.Post) []string {
+	titles := make([]string, 0, len(posts))
+	for _, p := range posts {
+		titles = append(titles, p.Title)
+	}
+	return titles
+}
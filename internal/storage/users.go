@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+)
+
+// CreateUser keeps the user assigning the next user id to it. Usernames are unique.
+func (s *Storage) CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error) {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	if _, taken := s.usernames[user.Username]; taken {
+		return 0, UsernameTakenError(user.Username)
+	}
+
+	user.ID = domain.UserId(s.userSeqId)
+	s.userSeqId++
+	s.users[user.ID] = user
+	s.usernames[user.Username] = user.ID
+	return user.ID, nil
+}
+
+func (s *Storage) User(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	if u, ok := s.users[id]; ok {
+		return u, nil
+	}
+
+	return nil, UserNotFoundError(id)
+}
+
+func (s *Storage) UserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	s.postsMtx.RLock()
+	defer s.postsMtx.RUnlock()
+
+	if id, ok := s.usernames[username]; ok {
+		return s.users[id], nil
+	}
+
+	err := fmt.Errorf("user not found. username: %v", username)
+	return nil, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+}
+
+// UpdateUser replaces the profile and the password hash of the user, the username and the creation time are kept
+func (s *Storage) UpdateUser(ctx context.Context, user *domain.User) error {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	stored, ok := s.users[user.ID]
+	if !ok {
+		return UserNotFoundError(user.ID)
+	}
+
+	updated := *stored
+	updated.DisplayName = user.DisplayName
+	updated.Bio = user.Bio
+	updated.PasswordHash = user.PasswordHash
+	s.users[user.ID] = &updated
+	return nil
+}
+
+// DeleteUser deletes the user. Users are not deleted through the API, it's used to roll back a failed creation.
+func (s *Storage) DeleteUser(ctx context.Context, id domain.UserId) {
+	s.postsMtx.Lock()
+	defer s.postsMtx.Unlock()
+
+	if u, ok := s.users[id]; ok {
+		delete(s.usernames, u.Username)
+		delete(s.users, id)
+	}
+}
+
+func UserNotFoundError(id domain.UserId) error {
+	err := fmt.Errorf("user not found. id: %v", id)
+	return httperr.WrapWithHttpCode(err, http.StatusNotFound)
+}
+
+func UsernameTakenError(username string) error {
+	err := fmt.Errorf("username is taken. username: %v", username)
+	return httperr.WrapWithCode(err, http.StatusConflict, httperr.CodeUsernameTaken)
+}
@@ -0,0 +1,214 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scope is the instrumentation scope of the storage spans
+const scope = "github.com/voltento/go-blog-project/internal/tracing"
+
+// InstrumentStorage returns the storage which makes a span for every operation made with s
+func InstrumentStorage(s blog.Storage) blog.Storage {
+	return &tracedStorage{next: s}
+}
+
+// tracedStorage makes the client spans of the storage operations
+type tracedStorage struct {
+	next blog.Storage
+}
+
+// start starts the span with the global provider, so the provider set after the storage is made is used
+func start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// end records the error of the operation and ends the span
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedStorage) Post(ctx context.Context, id domain.PostId) (*domain.Post, error) {
+	ctx, span := start(ctx, "Storage.Post")
+	post, err := s.next.Post(ctx, id)
+	end(span, err)
+	return post, err
+}
+
+func (s *tracedStorage) CreatePost(ctx context.Context, post *domain.Post) (domain.PostId, error) {
+	ctx, span := start(ctx, "Storage.CreatePost")
+	id, err := s.next.CreatePost(ctx, post)
+	end(span, err)
+	return id, err
+}
+
+func (s *tracedStorage) CreatePostWithID(ctx context.Context, post *domain.Post) error {
+	ctx, span := start(ctx, "Storage.CreatePostWithID")
+	err := s.next.CreatePostWithID(ctx, post)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) DeletePost(ctx context.Context, id domain.PostId, version int) error {
+	ctx, span := start(ctx, "Storage.DeletePost")
+	err := s.next.DeletePost(ctx, id, version)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) UpdatePost(ctx context.Context, post *domain.Post, id domain.PostId) error {
+	ctx, span := start(ctx, "Storage.UpdatePost")
+	err := s.next.UpdatePost(ctx, post, id)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) Posts(ctx context.Context) ([]*domain.Post, error) {
+	ctx, span := start(ctx, "Storage.Posts")
+	posts, err := s.next.Posts(ctx)
+	end(span, err)
+	return posts, err
+}
+
+func (s *tracedStorage) PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error) {
+	ctx, span := start(ctx, "Storage.PostsPage")
+	page, err := s.next.PostsPage(ctx, q)
+	end(span, err)
+	return page, err
+}
+
+func (s *tracedStorage) ScheduledPosts(ctx context.Context, until time.Time) ([]*domain.Post, error) {
+	ctx, span := start(ctx, "Storage.ScheduledPosts")
+	posts, err := s.next.ScheduledPosts(ctx, until)
+	end(span, err)
+	return posts, err
+}
+
+func (s *tracedStorage) Tags(ctx context.Context) ([]*domain.TagCount, error) {
+	ctx, span := start(ctx, "Storage.Tags")
+	tags, err := s.next.Tags(ctx)
+	end(span, err)
+	return tags, err
+}
+
+func (s *tracedStorage) StatusCounts(ctx context.Context) (map[domain.PostStatus]int, error) {
+	ctx, span := start(ctx, "Storage.StatusCounts")
+	counts, err := s.next.StatusCounts(ctx)
+	end(span, err)
+	return counts, err
+}
+
+func (s *tracedStorage) AddRevision(ctx context.Context, rev *domain.Revision) error {
+	ctx, span := start(ctx, "Storage.AddRevision")
+	err := s.next.AddRevision(ctx, rev)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error) {
+	ctx, span := start(ctx, "Storage.Revisions")
+	revs, err := s.next.Revisions(ctx, id)
+	end(span, err)
+	return revs, err
+}
+
+func (s *tracedStorage) Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error) {
+	ctx, span := start(ctx, "Storage.Revision")
+	rev, err := s.next.Revision(ctx, id, number)
+	end(span, err)
+	return rev, err
+}
+
+func (s *tracedStorage) CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error) {
+	ctx, span := start(ctx, "Storage.CreateComment")
+	id, err := s.next.CreateComment(ctx, comment)
+	end(span, err)
+	return id, err
+}
+
+func (s *tracedStorage) Comment(ctx context.Context, postId domain.PostId, id domain.CommentId) (*domain.Comment, error) {
+	ctx, span := start(ctx, "Storage.Comment")
+	comment, err := s.next.Comment(ctx, postId, id)
+	end(span, err)
+	return comment, err
+}
+
+func (s *tracedStorage) Comments(ctx context.Context, postId domain.PostId) ([]*domain.Comment, error) {
+	ctx, span := start(ctx, "Storage.Comments")
+	comments, err := s.next.Comments(ctx, postId)
+	end(span, err)
+	return comments, err
+}
+
+func (s *tracedStorage) UpdateComment(ctx context.Context, comment *domain.Comment) error {
+	ctx, span := start(ctx, "Storage.UpdateComment")
+	err := s.next.UpdateComment(ctx, comment)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	ctx, span := start(ctx, "Storage.DeleteComment")
+	err := s.next.DeleteComment(ctx, postId, id)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error) {
+	ctx, span := start(ctx, "Storage.CreateUser")
+	id, err := s.next.CreateUser(ctx, user)
+	end(span, err)
+	return id, err
+}
+
+func (s *tracedStorage) User(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	ctx, span := start(ctx, "Storage.User")
+	user, err := s.next.User(ctx, id)
+	end(span, err)
+	return user, err
+}
+
+func (s *tracedStorage) UserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	ctx, span := start(ctx, "Storage.UserByUsername")
+	user, err := s.next.UserByUsername(ctx, username)
+	end(span, err)
+	return user, err
+}
+
+func (s *tracedStorage) UpdateUser(ctx context.Context, user *domain.User) error {
+	ctx, span := start(ctx, "Storage.UpdateUser")
+	err := s.next.UpdateUser(ctx, user)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) MigrationRecord(ctx context.Context, id string) (*domain.MigrationRecord, error) {
+	ctx, span := start(ctx, "Storage.MigrationRecord")
+	rec, err := s.next.MigrationRecord(ctx, id)
+	end(span, err)
+	return rec, err
+}
+
+func (s *tracedStorage) AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error {
+	ctx, span := start(ctx, "Storage.AddMigrationRecord")
+	err := s.next.AddMigrationRecord(ctx, rec)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) DeleteMigrationRecord(ctx context.Context, id string) error {
+	ctx, span := start(ctx, "Storage.DeleteMigrationRecord")
+	err := s.next.DeleteMigrationRecord(ctx, id)
+	end(span, err)
+	return err
+}
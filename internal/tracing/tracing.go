@@ -0,0 +1,55 @@
+// Package tracing exports the traces of the service to an OpenTelemetry collector
+package tracing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const serviceName = "blog"
+
+// Config keeps the settings of the trace export
+type Config struct {
+	// Endpoint is the URL of the OTLP/HTTP collector, e.g. http://localhost:4318. The traces are not exported if it's empty.
+	Endpoint string
+	// SampleRatio is the share of the traces started by the service which are sampled
+	SampleRatio float64
+}
+
+// BindFlags binds the config to the -otlp-endpoint and -trace-sample-ratio flags
+func (c *Config) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Endpoint, "otlp-endpoint", "", "URL of the OTLP/HTTP collector the traces are exported to, tracing is off if it's empty")
+	fs.Float64Var(&c.SampleRatio, "trace-sample-ratio", 1, "Share of the traces started by the service which are sampled")
+}
+
+// Setup makes the provider exporting the spans to the collector the global one.
+// The returned function flushes the spans left and stops the export.
+func Setup(ctx context.Context, cfg Config) (func(ctx context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("can not create OTLP exporter. error: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("can not create trace resource. error: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
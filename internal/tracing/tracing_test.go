@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/storage"
+	"github.com/voltento/go-blog-project/internal/tracing/tracingtest"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestInstrumentStorage(t *testing.T) {
+	exporter := tracingtest.Record(t)
+	s := InstrumentStorage(storage.NewStorage())
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	id, err := s.CreatePost(ctx, &domain.Post{Title: "Title"})
+	require.NoError(t, err)
+	_, err = s.Post(ctx, id+1)
+	require.Error(t, err)
+	parent.End()
+
+	created := tracingtest.Span(t, exporter, "Storage.CreatePost")
+	assert.Equal(t, parent.SpanContext().SpanID(), created.Parent.SpanID())
+	assert.Equal(t, trace.SpanKindClient, created.SpanKind)
+	assert.Equal(t, codes.Unset, created.Status.Code)
+
+	notFound := tracingtest.Span(t, exporter, "Storage.Post")
+	assert.Equal(t, parent.SpanContext().SpanID(), notFound.Parent.SpanID())
+	assert.Equal(t, codes.Error, notFound.Status.Code)
+	assert.Equal(t, err.Error(), notFound.Status.Description)
+}
+
+func TestSetup(t *testing.T) {
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	shutdown, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+	_, exported := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	assert.False(t, exported, "no provider should be set without the endpoint")
+
+	shutdown, err = Setup(context.Background(), Config{Endpoint: "http://localhost:4318", SampleRatio: 1})
+	require.NoError(t, err)
+	_, exported = otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	assert.True(t, exported)
+	require.NoError(t, shutdown(context.Background()))
+}
@@ -0,0 +1,285 @@
+package web
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/feed"
+	"golang.org/x/exp/slog"
+)
+
+// Export writes the static site of the published posts to dir, so the site is served by a file server or a CDN
+// without the API. The pages and feeds have the paths they are served at by RegisterPages, a page is written to
+// the index.html of the directory named after its path, e.g. posts/1/index.html. The listings are split
+// into pages at /page/2, /tags/go/page/2 and so on instead of the after query parameter.
+// The site also has sitemap.xml and 404.html. The files written before are overwritten, the others are kept.
+func Export(ctx context.Context, blog Blog, templates *Templates, baseURL, dir string) error {
+	all, err := blog.Posts(ctx)
+	if err != nil {
+		return err
+	}
+
+	e := &exporter{
+		ctx:  ctx,
+		site: &site{blog: blog, templates: templates, baseURL: trimBaseURL(baseURL)},
+		dir:  dir,
+		all:  all,
+	}
+	return e.export()
+}
+
+type exporter struct {
+	ctx  context.Context
+	site *site
+	dir  string
+	all  []*domain.Post
+	// sitemap keeps the URLs of the written pages
+	sitemap []sitemapURL
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemap struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+func (e *exporter) export() error {
+	// The listings show the newest posts first as the pages served by RegisterPages do
+	var posts []*domain.Post
+	for _, post := range e.all {
+		if post.Status == domain.StatusPublished {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID > posts[j].ID })
+
+	for _, post := range posts {
+		if err := e.writePost(post); err != nil {
+			return err
+		}
+	}
+
+	err := e.writeListing("/", posts, func(l *listing) (string, any) { return "index.html", l })
+	if err != nil {
+		return err
+	}
+	if err := e.writeFeeds("/", e.site.indexFeed(), posts); err != nil {
+		return err
+	}
+
+	if err := e.writeAuthors(posts); err != nil {
+		return err
+	}
+	if err := e.writeTags(posts); err != nil {
+		return err
+	}
+
+	if err := e.writeSitemap(); err != nil {
+		return err
+	}
+
+	html, err := e.site.templates.render("error.html", &errorPage{Status: http.StatusNotFound, Text: http.StatusText(http.StatusNotFound)})
+	if err != nil {
+		return err
+	}
+	return e.writeFile("404.html", html)
+}
+
+func (e *exporter) writePost(post *domain.Post) error {
+	doc, err := e.site.blog.RenderPost(post)
+	if err != nil {
+		return err
+	}
+
+	// The rendered content is sanitized, so it's not escaped again
+	data := &postPage{Post: post, HTML: template.HTML(doc.HTML), TOC: doc.TOC}
+	return e.writePage(postPath(post.ID), "post.html", data, post.UpdatedAt)
+}
+
+func (e *exporter) writeAuthors(posts []*domain.Post) error {
+	byAuthor := map[domain.UserId][]*domain.Post{}
+	var authors []domain.UserId
+	for _, post := range posts {
+		// The posts migrated without an author have no author page
+		if post.AuthorID == 0 {
+			continue
+		}
+		if _, ok := byAuthor[post.AuthorID]; !ok {
+			authors = append(authors, post.AuthorID)
+		}
+		byAuthor[post.AuthorID] = append(byAuthor[post.AuthorID], post)
+	}
+
+	for _, id := range authors {
+		user, err := e.site.blog.User(e.ctx, id)
+		if err != nil {
+			return err
+		}
+
+		p := authorPath(id)
+		err = e.writeListing(p, byAuthor[id], func(l *listing) (string, any) {
+			return "author.html", &authorPage{User: user, listing: *l}
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := e.writeFeeds(p, e.site.authorFeed(user), byAuthor[id]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *exporter) writeTags(posts []*domain.Post) error {
+	byTag := map[string][]*domain.Post{}
+	var tags []string
+	for _, post := range posts {
+		for _, tag := range post.Tags {
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], post)
+		}
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		p := tagPath(tag)
+		// The escaped tag is a directory name, the dot names would write the pages out of the tag directory
+		if name := path.Base(p); name == "." || name == ".." {
+			slog.Warn("tag can not be exported", "tag", tag)
+			continue
+		}
+
+		err := e.writeListing(p, byTag[tag], func(l *listing) (string, any) {
+			return "tag.html", &tagPage{Tag: tag, listing: *l}
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := e.writeFeeds(p, e.site.tagFeed(tag), byTag[tag]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeListing writes the posts split into pages, the first page is at p and the next ones at p/page/<n>.
+// page returns the template and the data of a listing page.
+func (e *exporter) writeListing(p string, posts []*domain.Post, page func(l *listing) (string, any)) error {
+	pages := (len(posts) + pageSize - 1) / pageSize
+	for n := 1; n == 1 || n <= pages; n++ {
+		chunk := posts[min((n-1)*pageSize, len(posts)):min(n*pageSize, len(posts))]
+
+		l := &listing{Posts: make([]*listedPost, 0, len(chunk))}
+		for _, post := range chunk {
+			doc, err := e.site.blog.RenderPost(post)
+			if err != nil {
+				return err
+			}
+			l.Posts = append(l.Posts, &listedPost{Post: post, Excerpt: doc.Excerpt})
+		}
+		if n < pages {
+			l.Next = listingPage(p, n+1)
+		}
+
+		tmpl, data := page(l)
+		if err := e.writePage(listingPage(p, n), tmpl, data, time.Time{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listingPage returns the path of the n-th page of the listing at p
+func listingPage(p string, n int) string {
+	if n == 1 {
+		return p
+	}
+	return path.Join(p, "page", strconv.Itoa(n))
+}
+
+// writeFeeds writes the feed of the latest of the listed posts in both formats next to the page at p
+func (e *exporter) writeFeeds(p string, f *feed.Feed, posts []*domain.Post) error {
+	if err := e.site.addEntries(f, posts[:min(len(posts), feedSize)]); err != nil {
+		return err
+	}
+
+	for _, format := range []feedFormat{rssFormat, atomFormat} {
+		name := path.Join(p, "feed."+format.ext)
+		f.Self = e.site.url(name)
+		data, err := format.encode(f)
+		if err != nil {
+			return err
+		}
+		if err := e.writeFile(name, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePage renders the page served at p and adds it to the sitemap
+func (e *exporter) writePage(p, tmpl string, data any, updated time.Time) error {
+	html, err := e.site.templates.render(tmpl, data)
+	if err != nil {
+		return err
+	}
+
+	if err := e.writeFile(path.Join(p, "index.html"), html); err != nil {
+		return err
+	}
+
+	u := sitemapURL{Loc: e.site.url(p)}
+	if !updated.IsZero() {
+		u.LastMod = updated.UTC().Format(time.RFC3339)
+	}
+	e.sitemap = append(e.sitemap, u)
+	return nil
+}
+
+func (e *exporter) writeSitemap() error {
+	data, err := xml.MarshalIndent(sitemap{URLs: e.sitemap}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can not encode sitemap. error: %w", err)
+	}
+
+	return e.writeFile("sitemap.xml", append([]byte(xml.Header), data...))
+}
+
+// writeFile writes the file at the slash separated path relative to the export directory
+func (e *exporter) writeFile(name string, data []byte) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+
+	file := filepath.Join(e.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("can not create directory of '%s'. error: %w", name, err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return fmt.Errorf("can not write '%s'. error: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/voltento/go-blog-project/internal/domain"
+)
+
+func (s *WebTestSuite) export() string {
+	templates, err := LoadTemplates("")
+	s.Require().NoError(err)
+
+	dir := s.T().TempDir()
+	s.Require().NoError(Export(context.Background(), s.blog, templates, "https://blog.example.com/", dir))
+	return dir
+}
+
+func (s *WebTestSuite) readFile(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	s.Require().NoError(err, name)
+	return string(data)
+}
+
+func (s *WebTestSuite) TestExport() {
+	first := s.createPost(&domain.Post{Title: "First post", Content: "# Intro\n\nThe first paragraph.", Tags: []string{"go"}})
+	draft := s.createPost(&domain.Post{Title: "Draft post", Content: "draft", Status: domain.StatusDraft})
+
+	dir := s.export()
+
+	post := s.readFile(dir, "posts/"+strconv.Itoa(int(first))+"/index.html")
+	s.Contains(post, "<title>First post</title>")
+	s.Contains(post, `<h1 id="intro">Intro`)
+	s.NoFileExists(filepath.Join(dir, "posts", strconv.Itoa(int(draft)), "index.html"))
+
+	index := s.readFile(dir, "index.html")
+	s.Contains(index, `<a href="/posts/1">First post</a>`)
+	s.NotContains(index, "Draft post")
+
+	s.Contains(s.readFile(dir, "authors/"+strconv.Itoa(int(s.authorId))+"/index.html"), "<h1>Alice</h1>")
+	s.Contains(s.readFile(dir, "tags/go/index.html"), "First post")
+	s.Contains(s.readFile(dir, "404.html"), "Not Found")
+
+	s.Contains(s.readFile(dir, "feed.rss"), `<guid isPermaLink="true">https://blog.example.com/posts/1</guid>`)
+	s.Contains(s.readFile(dir, "feed.atom"), `<link href="https://blog.example.com/feed.atom" rel="self"`)
+	s.Contains(s.readFile(dir, "authors/"+strconv.Itoa(int(s.authorId))+"/feed.atom"), "First post")
+	s.Contains(s.readFile(dir, "tags/go/feed.rss"), "First post")
+
+	sitemap := s.readFile(dir, "sitemap.xml")
+	s.Contains(sitemap, "<loc>https://blog.example.com/</loc>")
+	s.Contains(sitemap, "<loc>https://blog.example.com/posts/1</loc>")
+	s.Contains(sitemap, "<loc>https://blog.example.com/tags/go</loc>")
+	s.NotContains(sitemap, "/posts/"+strconv.Itoa(int(draft))+"<")
+}
+
+func (s *WebTestSuite) TestExport_Pagination() {
+	for i := 1; i <= pageSize+1; i++ {
+		s.createPost(&domain.Post{Title: "Post " + strconv.Itoa(i), Content: "content", Tags: []string{"go"}})
+	}
+
+	dir := s.export()
+
+	index := s.readFile(dir, "index.html")
+	s.Contains(index, `<a href="/page/2">Older posts</a>`)
+	s.NotContains(index, `<a href="/posts/1">`)
+
+	page := s.readFile(dir, "page/2/index.html")
+	s.Contains(page, `<a href="/posts/1">`)
+	s.NotContains(page, "Older posts")
+
+	s.Contains(s.readFile(dir, "tags/go/index.html"), `<a href="/tags/go/page/2">Older posts</a>`)
+	s.FileExists(filepath.Join(dir, "tags", "go", "page", "2", "index.html"))
+}
+
+func (s *WebTestSuite) TestExport_Empty() {
+	dir := s.export()
+
+	s.Contains(s.readFile(dir, "index.html"), "There are no posts yet.")
+	s.Contains(s.readFile(dir, "feed.rss"), "<channel>")
+}
+
+func (s *WebTestSuite) TestExport_DotTag() {
+	s.createPost(&domain.Post{Title: "Dot post", Content: "content", Tags: []string{".."}})
+
+	dir := s.export()
+
+	s.Contains(s.readFile(dir, "index.html"), "<title>Blog</title>", "the tag page should not overwrite the index")
+	s.NotContains(s.readFile(dir, "feed.rss"), "<title>#..</title>")
+}
@@ -0,0 +1,234 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/feed"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"golang.org/x/exp/slog"
+)
+
+// feedSize is the number of the latest posts in a feed
+const feedSize = 20
+
+// feedFormat is an encoding of the feeds
+type feedFormat struct {
+	ext         string
+	contentType string
+	encode      func(f *feed.Feed) ([]byte, error)
+}
+
+var (
+	rssFormat  = feedFormat{ext: "rss", contentType: "application/rss+xml; charset=utf-8", encode: (*feed.Feed).RSS}
+	atomFormat = feedFormat{ext: "atom", contentType: "application/atom+xml; charset=utf-8", encode: (*feed.Feed).Atom}
+)
+
+// registerFeeds binds the feeds of all the posts, the posts of an author and the posts with a tag in both formats
+func (s *site) registerFeeds(r gin.IRoutes) {
+	for _, format := range []feedFormat{rssFormat, atomFormat} {
+		r.GET("/feed."+format.ext, s.feed(format, s.indexFeedSource))
+		r.GET("/authors/:id/feed."+format.ext, s.feed(format, s.authorFeedSource))
+		r.GET("/tags/:tag/feed."+format.ext, s.feed(format, s.tagFeedSource))
+	}
+}
+
+// feedSource returns the feed without entries and the query of the posts going to the feed
+type feedSource func(c *gin.Context) (*feed.Feed, domain.PostsQuery, error)
+
+func (s *site) indexFeedSource(*gin.Context) (*feed.Feed, domain.PostsQuery, error) {
+	return s.indexFeed(), domain.PostsQuery{}, nil
+}
+
+func (s *site) authorFeedSource(c *gin.Context) (*feed.Feed, domain.PostsQuery, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, domain.PostsQuery{}, httperr.WrapWithHttpCode(err, http.StatusNotFound)
+	}
+
+	user, err := s.blog.User(c.Request.Context(), domain.UserId(id))
+	if err != nil {
+		return nil, domain.PostsQuery{}, err
+	}
+
+	return s.authorFeed(user), domain.PostsQuery{Author: user.ID}, nil
+}
+
+func (s *site) tagFeedSource(c *gin.Context) (*feed.Feed, domain.PostsQuery, error) {
+	tag := normalizeTag(c.Param("tag"))
+	return s.tagFeed(tag), domain.PostsQuery{Tags: []string{tag}}, nil
+}
+
+func (s *site) indexFeed() *feed.Feed {
+	return &feed.Feed{Title: "Blog", Description: "The latest posts", Link: s.url("/")}
+}
+
+func (s *site) authorFeed(user *domain.User) *feed.Feed {
+	return &feed.Feed{
+		Title:       user.DisplayName,
+		Description: "The latest posts by " + user.DisplayName,
+		Link:        s.url(authorPath(user.ID)),
+	}
+}
+
+func (s *site) tagFeed(tag string) *feed.Feed {
+	tag = normalizeTag(tag)
+	return &feed.Feed{
+		Title:       "#" + tag,
+		Description: "The latest posts tagged " + tag,
+		Link:        s.url(tagPath(tag)),
+	}
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// feed returns the handler serving the feed of the source in the format.
+// The feed is validated by its ETag and Last-Modified headers, so the readers polling an unchanged feed get 304
+// without the posts being rendered.
+func (s *site) feed(format feedFormat, source feedSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, q, err := source(c)
+		if err != nil {
+			s.feedError(c, err)
+			return
+		}
+
+		posts, err := s.feedPosts(c, q)
+		if err != nil {
+			s.feedError(c, err)
+			return
+		}
+
+		etag, updated := feedETag(format, posts), lastUpdated(posts)
+		c.Header("ETag", etag)
+		if !updated.IsZero() {
+			c.Header("Last-Modified", updated.UTC().Format(http.TimeFormat))
+		}
+		if notModified(c.Request, etag, updated) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		f.Self = s.url(c.Request.URL.Path)
+		if err := s.addEntries(f, posts); err != nil {
+			s.feedError(c, err)
+			return
+		}
+
+		data, err := format.encode(f)
+		if err != nil {
+			s.feedError(c, err)
+			return
+		}
+
+		c.Data(http.StatusOK, format.contentType, data)
+	}
+}
+
+// feedPosts returns the latest published posts matching the query, the newest go first.
+// The latest posts are the last created ones as on the pages of the site.
+func (s *site) feedPosts(c *gin.Context, q domain.PostsQuery) ([]*domain.Post, error) {
+	q.Desc, q.Limit = true, feedSize
+	page, err := s.blog.PostsPage(c.Request.Context(), q)
+	if err != nil {
+		return nil, err
+	}
+
+	// The page has the own posts of the authenticated readers in any status, the feeds have the published posts only
+	return slices.DeleteFunc(page.Posts, func(post *domain.Post) bool { return post.Status != domain.StatusPublished }), nil
+}
+
+// lastUpdated returns the time the latest of the posts was updated at, it's the update time of the feed
+func lastUpdated(posts []*domain.Post) time.Time {
+	var updated time.Time
+	for _, post := range posts {
+		t := post.UpdatedAt
+		if t.IsZero() {
+			t = post.PublishAt
+		}
+		if t.After(updated) {
+			updated = t
+		}
+	}
+	return updated
+}
+
+func (s *site) addEntries(f *feed.Feed, posts []*domain.Post) error {
+	f.Entries = make([]*feed.Entry, 0, len(posts))
+	for _, post := range posts {
+		doc, err := s.blog.RenderPost(post)
+		if err != nil {
+			return err
+		}
+
+		link := s.url(postPath(post.ID))
+		f.Entries = append(f.Entries, &feed.Entry{
+			ID:        link,
+			Title:     post.Title,
+			Link:      link,
+			Author:    post.Author,
+			Tags:      post.Tags,
+			Published: post.PublishAt,
+			Updated:   post.UpdatedAt,
+			Summary:   doc.Excerpt,
+		})
+	}
+
+	return nil
+}
+
+// url returns the absolute URL of the path, the feeds are read out of the site so the links can not be relative
+func (s *site) url(path string) string {
+	return s.baseURL + path
+}
+
+// feedError responds with the status of the error only, the feed readers do not show pages
+func (s *site) feedError(c *gin.Context, err error) {
+	status := httperr.HTTPStatusCode(err, http.StatusInternalServerError)
+	if status >= http.StatusInternalServerError {
+		slog.Error("can not build feed", "path", c.Request.URL.Path, "error", err)
+	}
+
+	c.String(status, http.StatusText(status))
+}
+
+// feedETag identifies the posts of the feed by their ids and versions, so a change of any of them changes the tag
+func feedETag(format feedFormat, posts []*domain.Post) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s;", format.ext)
+	for _, post := range posts {
+		_, _ = fmt.Fprintf(h, "%d:%d;", post.ID, post.Version)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// notModified reports whether the client already has the feed. If-None-Match takes precedence over
+// If-Modified-Since as RFC 9110 requires.
+func notModified(r *http.Request, etag string, updated time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !updated.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !updated.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
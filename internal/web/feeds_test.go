@@ -0,0 +1,158 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/markdown"
+)
+
+func (s *WebTestSuite) getWithHeader(path, header, value string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.Header.Set(header, value)
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+func (s *WebTestSuite) TestFeed_RSS() {
+	s.createPost(&domain.Post{Title: "First post", Content: "The *first* paragraph.\n\nThe second one.", Tags: []string{"go"}})
+	s.createPost(&domain.Post{Title: "Draft post", Content: "draft", Status: domain.StatusDraft})
+
+	w := s.get("/feed.rss")
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("application/rss+xml; charset=utf-8", w.Header().Get("Content-Type"))
+	s.NotEmpty(w.Header().Get("ETag"))
+	s.NotEmpty(w.Header().Get("Last-Modified"))
+
+	body := w.Body.String()
+	s.Contains(body, `<guid isPermaLink="true">https://blog.example.com/posts/1</guid>`)
+	s.Contains(body, "<description>The first paragraph.</description>", "the excerpt should be plain text")
+	s.Contains(body, "<dc:creator>Alice</dc:creator>")
+	s.Contains(body, "<pubDate>")
+	s.Contains(body, `<atom:link href="https://blog.example.com/feed.rss" rel="self"`)
+	s.NotContains(body, "Draft post")
+}
+
+func (s *WebTestSuite) TestFeed_Atom() {
+	s.createPost(&domain.Post{Title: "First post", Content: "content"})
+	s.createPost(&domain.Post{Title: "Second post", Content: "content"})
+
+	w := s.get("/feed.atom")
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("application/atom+xml; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	s.Contains(body, "<id>https://blog.example.com/posts/2</id>")
+	s.Contains(body, "<published>")
+	s.Less(strings.Index(body, "Second post"), strings.Index(body, "First post"), "the newest post should go first")
+}
+
+func (s *WebTestSuite) TestFeed_Size() {
+	for i := 1; i <= feedSize+1; i++ {
+		s.createPost(&domain.Post{Title: "Post " + strconv.Itoa(i), Content: "content"})
+	}
+
+	body := s.get("/feed.atom").Body.String()
+	s.Equal(feedSize, strings.Count(body, "<entry>"))
+	s.NotContains(body, "<id>https://blog.example.com/posts/1</id>", "the oldest post should not fit the feed")
+}
+
+func (s *WebTestSuite) TestFeed_AuthorAndTag() {
+	s.createPost(&domain.Post{Title: "Go post", Content: "content", Tags: []string{"go"}})
+	s.createPost(&domain.Post{Title: "Rust post", Content: "content", Tags: []string{"rust"}})
+
+	body := s.get("/authors/" + strconv.Itoa(int(s.authorId)) + "/feed.rss").Body.String()
+	s.Contains(body, "<title>Alice</title>")
+	s.Contains(body, "Go post")
+	s.Contains(body, "Rust post")
+
+	body = s.get("/tags/Go/feed.atom").Body.String()
+	s.Contains(body, "<title>#go</title>")
+	s.Contains(body, "Go post")
+	s.NotContains(body, "Rust post")
+
+	s.Equal(http.StatusNotFound, s.get("/authors/100/feed.rss").Code)
+	s.Equal(http.StatusNotFound, s.get("/authors/abc/feed.atom").Code)
+}
+
+func (s *WebTestSuite) TestFeed_ConditionalGet() {
+	id := s.createPost(&domain.Post{Title: "First post", Content: "content"})
+
+	w := s.get("/feed.rss")
+	s.Require().Equal(http.StatusOK, w.Code)
+	etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+
+	w = s.getWithHeader("/feed.rss", "If-None-Match", etag)
+	s.Equal(http.StatusNotModified, w.Code)
+	s.Empty(w.Body.String())
+	s.Equal(etag, w.Header().Get("ETag"))
+
+	s.Equal(http.StatusNotModified, s.getWithHeader("/feed.rss", "If-Modified-Since", lastModified).Code)
+	s.Equal(http.StatusOK, s.getWithHeader("/feed.atom", "If-None-Match", etag).Code, "the formats should not share tags")
+
+	post, err := s.blog.Post(s.authorCtx, id)
+	s.Require().NoError(err)
+	post.Title = "Updated post"
+	s.Require().NoError(s.blog.UpdatePost(s.authorCtx, post, id))
+
+	w = s.getWithHeader("/feed.rss", "If-None-Match", etag)
+	s.Equal(http.StatusOK, w.Code, "an updated post should change the tag")
+	s.Contains(w.Body.String(), "Updated post")
+}
+
+// countingBlog counts the calls the feeds make
+type countingBlog struct {
+	Blog
+	queries  []domain.PostsQuery
+	all      int
+	rendered int
+}
+
+func (b *countingBlog) PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error) {
+	b.queries = append(b.queries, q)
+	return b.Blog.PostsPage(ctx, q)
+}
+
+func (b *countingBlog) Posts(ctx context.Context) ([]*domain.Post, error) {
+	b.all++
+	return b.Blog.Posts(ctx)
+}
+
+func (b *countingBlog) RenderPost(post *domain.Post) (*markdown.Document, error) {
+	b.rendered++
+	return b.Blog.RenderPost(post)
+}
+
+func (s *WebTestSuite) TestFeed_NotModifiedIsNotRendered() {
+	s.createPost(&domain.Post{Title: "Go post", Content: "content", Tags: []string{"go"}})
+	b := &countingBlog{Blog: s.blog}
+	templates, err := LoadTemplates("")
+	s.Require().NoError(err)
+	router := gin.New()
+	RegisterPages(router, b, templates, "https://blog.example.com/")
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/tags/go/feed.rss", nil)
+		r.Header.Set("If-None-Match", etag)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("")
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(1, b.rendered)
+
+	b.rendered = 0
+	s.Equal(http.StatusNotModified, get(w.Header().Get("ETag")).Code)
+	s.Zero(b.rendered, "unchanged feed should not be rendered")
+
+	s.Zero(b.all, "feed should not load all the posts")
+	s.Equal(domain.PostsQuery{Tags: []string{"go"}, Desc: true, Limit: feedSize}, b.queries[0])
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// layoutTemplate keeps the page frame and the partials shared by the pages
+const layoutTemplate = "layout.html"
+
+// pages are the templates of the pages, every page defines "title" and "content" rendered into the layout
+var pages = []string{"index.html", "post.html", "author.html", "tag.html", "error.html"}
+
+// Templates keeps the parsed pages. It's safe for concurrent use.
+type Templates struct {
+	pages map[string]*template.Template
+}
+
+// LoadTemplates parses the embedded templates. A template found in overrideDir is used instead of the embedded one
+// with the same name, so a theme overrides only the templates it changes. Empty overrideDir keeps the embedded templates.
+func LoadTemplates(overrideDir string) (*Templates, error) {
+	base, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := base
+	if overrideDir != "" {
+		if _, err := os.Stat(overrideDir); err != nil {
+			return nil, fmt.Errorf("can not open templates directory. error: %w", err)
+		}
+		fsys = overlayFS{top: os.DirFS(overrideDir), base: base}
+	}
+
+	t := &Templates{pages: make(map[string]*template.Template, len(pages))}
+	for _, page := range pages {
+		parsed, err := template.ParseFS(fsys, layoutTemplate, page)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse template '%s'. error: %w", page, err)
+		}
+		t.pages[page] = parsed
+	}
+
+	return t, nil
+}
+
+// render executes the page, the output is buffered so a failed page is not sent partially
+func (t *Templates) render(page string, data any) ([]byte, error) {
+	tmpl, ok := t.pages[page]
+	if !ok {
+		return nil, fmt.Errorf("unknown page '%s'", page)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return nil, fmt.Errorf("can not render page '%s'. error: %w", page, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// overlayFS opens the files of top falling back to base for the files top does not have
+type overlayFS struct {
+	top  fs.FS
+	base fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.top.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.base.Open(name)
+	}
+	return f, err
+}
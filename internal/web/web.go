@@ -0,0 +1,226 @@
+// Package web serves the reader-facing HTML pages of the blog.
+// The pages are rendered on the server with html/template, so they are readable without JavaScript.
+package web
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/httperr"
+	"github.com/voltento/go-blog-project/internal/markdown"
+	"golang.org/x/exp/slog"
+)
+
+// pageSize is the number of posts on a listing page
+const pageSize = 10
+
+// Blog is the part of blog.Blog the pages are built from
+type Blog interface {
+	Post(ctx context.Context, id domain.PostId) (*domain.Post, error)
+	PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error)
+	Posts(ctx context.Context) ([]*domain.Post, error)
+	RenderPost(post *domain.Post) (*markdown.Document, error)
+	User(ctx context.Context, id domain.UserId) (*domain.User, error)
+}
+
+// RegisterPages binds the page and feed handlers to the router.
+// baseURL is the public URL of the site the links in the feeds are built with, e.g. https://blog.example.com
+func RegisterPages(r gin.IRoutes, blog Blog, templates *Templates, baseURL string) {
+	s := site{blog: blog, templates: templates, baseURL: trimBaseURL(baseURL)}
+	r.GET("/", s.Index)
+	r.GET("/posts/:id", s.Post)
+	r.GET("/authors/:id", s.Author)
+	r.GET("/tags/:tag", s.Tag)
+	s.registerFeeds(r)
+}
+
+// trimBaseURL drops the trailing slash, so the URLs are built by appending the absolute paths
+func trimBaseURL(baseURL string) string {
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+type site struct {
+	blog      Blog
+	templates *Templates
+	baseURL   string
+}
+
+// listing is a page of posts, the newest go first
+type listing struct {
+	Posts []*listedPost
+	// Next is the URL of the next page, it's empty on the last page
+	Next string
+}
+
+type listedPost struct {
+	*domain.Post
+	Excerpt string
+}
+
+type postPage struct {
+	Post *domain.Post
+	// HTML is the sanitized content of the post
+	HTML template.HTML
+	TOC  []*markdown.Heading
+}
+
+type authorPage struct {
+	User *domain.User
+	listing
+}
+
+type tagPage struct {
+	Tag string
+	listing
+}
+
+type errorPage struct {
+	Status int
+	Text   string
+}
+
+func (s *site) Index(c *gin.Context) {
+	l, err := s.listing(c, domain.PostsQuery{})
+	if err != nil {
+		s.renderError(c, err)
+		return
+	}
+
+	s.render(c, "index.html", l)
+}
+
+func (s *site) Post(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		s.renderError(c, httperr.WrapWithHttpCode(err, http.StatusNotFound))
+		return
+	}
+
+	post, err := s.blog.Post(c.Request.Context(), domain.PostId(id))
+	if err != nil {
+		s.renderError(c, err)
+		return
+	}
+
+	doc, err := s.blog.RenderPost(post)
+	if err != nil {
+		s.renderError(c, err)
+		return
+	}
+
+	// The rendered content is sanitized, so it's not escaped again
+	s.render(c, "post.html", &postPage{Post: post, HTML: template.HTML(doc.HTML), TOC: doc.TOC})
+}
+
+// Author renders the profile of the user with the posts written by them
+func (s *site) Author(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		s.renderError(c, httperr.WrapWithHttpCode(err, http.StatusNotFound))
+		return
+	}
+
+	user, err := s.blog.User(c.Request.Context(), domain.UserId(id))
+	if err != nil {
+		s.renderError(c, err)
+		return
+	}
+
+	l, err := s.listing(c, domain.PostsQuery{Author: user.ID})
+	if err != nil {
+		s.renderError(c, err)
+		return
+	}
+
+	s.render(c, "author.html", &authorPage{User: user, listing: *l})
+}
+
+func (s *site) Tag(c *gin.Context) {
+	tag := c.Param("tag")
+	l, err := s.listing(c, domain.PostsQuery{Tags: []string{tag}})
+	if err != nil {
+		s.renderError(c, err)
+		return
+	}
+
+	s.render(c, "tag.html", &tagPage{Tag: tag, listing: *l})
+}
+
+// listing returns the page of the posts matching q which starts after the post passed in the after query parameter
+func (s *site) listing(c *gin.Context, q domain.PostsQuery) (*listing, error) {
+	q.Desc, q.Limit = true, pageSize
+	if after := c.Query("after"); after != "" {
+		id, err := strconv.Atoi(after)
+		if err != nil {
+			err = fmt.Errorf("can not parse post id '%s'. error: %w", after, err)
+			return nil, httperr.WrapWithHttpCode(err, http.StatusBadRequest)
+		}
+		q.After = domain.PostId(id)
+	}
+
+	page, err := s.blog.PostsPage(c.Request.Context(), q)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listing{Posts: make([]*listedPost, 0, len(page.Posts))}
+	for _, post := range page.Posts {
+		doc, err := s.blog.RenderPost(post)
+		if err != nil {
+			return nil, err
+		}
+		l.Posts = append(l.Posts, &listedPost{Post: post, Excerpt: doc.Excerpt})
+	}
+
+	if page.HasMore && len(page.Posts) > 0 {
+		next := url.URL{Path: c.Request.URL.Path}
+		next.RawQuery = url.Values{"after": {strconv.Itoa(int(page.Posts[len(page.Posts)-1].ID))}}.Encode()
+		l.Next = next.String()
+	}
+
+	return l, nil
+}
+
+func postPath(id domain.PostId) string {
+	return "/posts/" + strconv.Itoa(int(id))
+}
+
+func authorPath(id domain.UserId) string {
+	return "/authors/" + strconv.Itoa(int(id))
+}
+
+func tagPath(tag string) string {
+	return "/tags/" + url.PathEscape(tag)
+}
+
+func (s *site) render(c *gin.Context, page string, data any) {
+	s.renderStatus(c, http.StatusOK, page, data)
+}
+
+func (s *site) renderStatus(c *gin.Context, status int, page string, data any) {
+	html, err := s.templates.render(page, data)
+	if err != nil {
+		slog.Error("can not render page", "page", page, "error", err)
+		c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	c.Data(status, "text/html; charset=utf-8", html)
+}
+
+// renderError renders the page of the error status. The error text is not shown as it may reveal the internals.
+func (s *site) renderError(c *gin.Context, err error) {
+	status := httperr.HTTPStatusCode(err, http.StatusInternalServerError)
+	if status >= http.StatusInternalServerError {
+		slog.Error("can not build page", "path", c.Request.URL.Path, "error", err)
+	}
+
+	s.renderStatus(c, status, "error.html", &errorPage{Status: status, Text: http.StatusText(status)})
+}
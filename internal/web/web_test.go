@@ -0,0 +1,175 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/auth"
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/domain"
+	"github.com/voltento/go-blog-project/internal/storage"
+)
+
+type WebTestSuite struct {
+	suite.Suite
+	blog   *blog.Blog
+	router *gin.Engine
+	// authorCtx is the context of the requests made by the author
+	authorCtx context.Context
+	authorId  domain.UserId
+}
+
+func TestWebTestSuite(t *testing.T) {
+	suite.Run(t, new(WebTestSuite))
+}
+
+func (s *WebTestSuite) SetupTest() {
+	st := storage.NewStorage()
+	id, err := st.CreateUser(context.Background(), &domain.User{Username: "alice", DisplayName: "Alice", Bio: "Writes about Go"})
+	s.Require().NoError(err)
+
+	s.authorId = id
+	s.authorCtx = auth.WithPrincipal(context.Background(), &auth.Principal{Subject: strconv.Itoa(int(id))})
+	s.blog = blog.NewBlog(st)
+
+	templates, err := LoadTemplates("")
+	s.Require().NoError(err)
+
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	RegisterPages(s.router, s.blog, templates, "https://blog.example.com/")
+}
+
+func (s *WebTestSuite) createPost(post *domain.Post) domain.PostId {
+	id, err := s.blog.CreatePost(s.authorCtx, post)
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *WebTestSuite) get(path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func (s *WebTestSuite) TestIndex() {
+	s.createPost(&domain.Post{Title: "First post", Content: "# Intro\n\nThe *first* paragraph.\n\nThe second one."})
+	s.createPost(&domain.Post{Title: "Draft post", Content: "draft", Status: domain.StatusDraft})
+	s.createPost(&domain.Post{Title: "Second <post>", Content: "content", Tags: []string{"go"}})
+
+	w := s.get("/")
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	s.Contains(body, `<a href="/posts/1">First post</a>`)
+	s.Contains(body, "The first paragraph.", "the excerpt should be plain text")
+	s.Contains(body, "Second &lt;post&gt;", "the title should be escaped")
+	s.Contains(body, `<a href="/tags/go">#go</a>`)
+	s.Contains(body, `<a href="/authors/1">Alice</a>`)
+	s.NotContains(body, "Draft post")
+	s.Less(strings.Index(body, "Second"), strings.Index(body, "First post"), "the newest post should go first")
+	s.NotContains(body, "Older posts")
+}
+
+func (s *WebTestSuite) TestIndex_Pagination() {
+	for i := 1; i <= pageSize+1; i++ {
+		s.createPost(&domain.Post{Title: "Post " + strconv.Itoa(i), Content: "content"})
+	}
+
+	body := s.get("/").Body.String()
+	s.Contains(body, `<a href="/?after=2">Older posts</a>`)
+	s.NotContains(body, `<a href="/posts/1">`)
+
+	body = s.get("/?after=2").Body.String()
+	s.Contains(body, `<a href="/posts/1">`)
+	s.NotContains(body, "Older posts")
+
+	s.Equal(http.StatusBadRequest, s.get("/?after=abc").Code)
+}
+
+func (s *WebTestSuite) TestPost() {
+	id := s.createPost(&domain.Post{
+		Title:   "Title",
+		Content: "# Intro\n\ntext<script>alert(1)</script>\n\n## Details\n\nmore",
+	})
+
+	w := s.get("/posts/" + strconv.Itoa(int(id)))
+	s.Equal(http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	s.Contains(body, "<title>Title</title>")
+	s.Contains(body, `<h1 id="intro">Intro`)
+	s.Contains(body, `<a href="#details">Details</a>`, "the table of contents should link the headings")
+	s.NotContains(body, "<script>")
+}
+
+func (s *WebTestSuite) TestPost_NotFound() {
+	draft := s.createPost(&domain.Post{Title: "Draft", Content: "content", Status: domain.StatusDraft})
+
+	for _, path := range []string{"/posts/" + strconv.Itoa(int(draft)), "/posts/100", "/posts/abc"} {
+		w := s.get(path)
+		s.Equal(http.StatusNotFound, w.Code, path)
+		s.Contains(w.Body.String(), "Not Found", path)
+	}
+}
+
+func (s *WebTestSuite) TestAuthor() {
+	s.createPost(&domain.Post{Title: "Alice post", Content: "content"})
+
+	w := s.get("/authors/" + strconv.Itoa(int(s.authorId)))
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "<h1>Alice</h1>")
+	s.Contains(w.Body.String(), "Writes about Go")
+	s.Contains(w.Body.String(), "Alice post")
+
+	s.Equal(http.StatusNotFound, s.get("/authors/100").Code)
+}
+
+func (s *WebTestSuite) TestTag() {
+	s.createPost(&domain.Post{Title: "Go post", Content: "content", Tags: []string{"go"}})
+	s.createPost(&domain.Post{Title: "Rust post", Content: "content", Tags: []string{"rust"}})
+
+	w := s.get("/tags/Go")
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "Go post")
+	s.NotContains(w.Body.String(), "Rust post")
+}
+
+func TestLoadTemplates_Override(t *testing.T) {
+	dir := t.TempDir()
+	index := `{{define "title"}}Themed{{end}}{{define "content"}}<p class="themed">{{len .Posts}} posts</p>{{end}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(index), 0o644))
+
+	templates, err := LoadTemplates(dir)
+	require.NoError(t, err)
+
+	html, err := templates.render("index.html", &listing{})
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "<title>Themed</title>", "the layout should be the embedded one")
+	assert.Contains(t, string(html), `<p class="themed">0 posts</p>`)
+
+	html, err = templates.render("error.html", &errorPage{Status: http.StatusNotFound, Text: "Not Found"})
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "<h1>Not Found</h1>", "the templates which are not overridden should be embedded")
+}
+
+func TestLoadTemplates_Errors(t *testing.T) {
+	_, err := LoadTemplates(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "post.html"), []byte(`{{define "content"}}{{.Missing`), 0o644))
+	_, err = LoadTemplates(dir)
+	assert.Error(t, err)
+}
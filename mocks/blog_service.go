@@ -5,8 +5,12 @@ package mocks
 import (
 	context "context"
 
+	bulk "github.com/voltento/go-blog-project/internal/bulk"
+
 	domain "github.com/voltento/go-blog-project/internal/domain"
 
+	markdown "github.com/voltento/go-blog-project/internal/markdown"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -36,13 +40,13 @@ func (_m *BlogService) CreatePost(ctx context.Context, p *domain.Post) (domain.P
 	return r0, r1
 }
 
-// DeletePost provides a mock function with given fields: ctx, id
-func (_m *BlogService) DeletePost(ctx context.Context, id domain.PostId) error {
-	ret := _m.Called(ctx, id)
+// DeletePost provides a mock function with given fields: ctx, id, version
+func (_m *BlogService) DeletePost(ctx context.Context, id domain.PostId, version int) error {
+	ret := _m.Called(ctx, id, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, int) error); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -88,7 +92,7 @@ func (_m *BlogService) UpdatePost(ctx context.Context, post *domain.Post, id dom
 }
 
 // Posts provides a mock function with given fields: ctx
-func (_m *BlogService) Posts(ctx context.Context) []*domain.Post {
+func (_m *BlogService) Posts(ctx context.Context) ([]*domain.Post, error) {
 	ret := _m.Called(ctx)
 
 	var r0 []*domain.Post
@@ -100,6 +104,394 @@ func (_m *BlogService) Posts(ctx context.Context) []*domain.Post {
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostsPage provides a mock function with given fields: ctx, q
+func (_m *BlogService) PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error) {
+	ret := _m.Called(ctx, q)
+
+	var r0 *domain.Page
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostsQuery) *domain.Page); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Page)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostsQuery) error); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: ctx, query, limit
+func (_m *BlogService) Search(ctx context.Context, query string, limit int) ([]*domain.SearchResult, error) {
+	ret := _m.Called(ctx, query, limit)
+
+	var r0 []*domain.SearchResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*domain.SearchResult); ok {
+		r0 = rf(ctx, query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.SearchResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, query, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Revisions provides a mock function with given fields: ctx, id
+func (_m *BlogService) Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 []*domain.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId) []*domain.Revision); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Revision provides a mock function with given fields: ctx, id, number
+func (_m *BlogService) Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error) {
+	ret := _m.Called(ctx, id, number)
+
+	var r0 *domain.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, int) *domain.Revision); ok {
+		r0 = rf(ctx, id, number)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId, int) error); ok {
+		r1 = rf(ctx, id, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreRevision provides a mock function with given fields: ctx, id, number
+func (_m *BlogService) RestoreRevision(ctx context.Context, id domain.PostId, number int) (*domain.Post, error) {
+	ret := _m.Called(ctx, id, number)
+
+	var r0 *domain.Post
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, int) *domain.Post); ok {
+		r0 = rf(ctx, id, number)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Post)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId, int) error); ok {
+		r1 = rf(ctx, id, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateComment provides a mock function with given fields: ctx, comment
+func (_m *BlogService) CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error) {
+	ret := _m.Called(ctx, comment)
+
+	var r0 domain.CommentId
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Comment) domain.CommentId); ok {
+		r0 = rf(ctx, comment)
+	} else {
+		r0 = ret.Get(0).(domain.CommentId)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *domain.Comment) error); ok {
+		r1 = rf(ctx, comment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CommentThreads provides a mock function with given fields: ctx, postId
+func (_m *BlogService) CommentThreads(ctx context.Context, postId domain.PostId) ([]*domain.CommentThread, error) {
+	ret := _m.Called(ctx, postId)
+
+	var r0 []*domain.CommentThread
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId) []*domain.CommentThread); ok {
+		r0 = rf(ctx, postId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.CommentThread)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId) error); ok {
+		r1 = rf(ctx, postId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateComment provides a mock function with given fields: ctx, comment
+func (_m *BlogService) UpdateComment(ctx context.Context, comment *domain.Comment) error {
+	ret := _m.Called(ctx, comment)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Comment) error); ok {
+		r0 = rf(ctx, comment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteComment provides a mock function with given fields: ctx, postId, id
+func (_m *BlogService) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	ret := _m.Called(ctx, postId, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, domain.CommentId) error); ok {
+		r0 = rf(ctx, postId, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Tags provides a mock function with given fields: ctx
+func (_m *BlogService) Tags(ctx context.Context) ([]*domain.TagCount, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*domain.TagCount
+	if rf, ok := ret.Get(0).(func(context.Context) []*domain.TagCount); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.TagCount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Signup provides a mock function with given fields: ctx, user, password
+func (_m *BlogService) Signup(ctx context.Context, user *domain.User, password string) (domain.UserId, error) {
+	ret := _m.Called(ctx, user, password)
+
+	var r0 domain.UserId
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.User, string) domain.UserId); ok {
+		r0 = rf(ctx, user, password)
+	} else {
+		r0 = ret.Get(0).(domain.UserId)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *domain.User, string) error); ok {
+		r1 = rf(ctx, user, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Login provides a mock function with given fields: ctx, username, password
+func (_m *BlogService) Login(ctx context.Context, username string, password string) (*domain.User, error) {
+	ret := _m.Called(ctx, username, password)
+
+	var r0 *domain.User
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *domain.User); ok {
+		r0 = rf(ctx, username, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, username, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// User provides a mock function with given fields: ctx, id
+func (_m *BlogService) User(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *domain.User
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserId) *domain.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.UserId) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CurrentUser provides a mock function with given fields: ctx
+func (_m *BlogService) CurrentUser(ctx context.Context) (*domain.User, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *domain.User
+	if rf, ok := ret.Get(0).(func(context.Context) *domain.User); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, profile
+func (_m *BlogService) UpdateProfile(ctx context.Context, profile *domain.User) (*domain.User, error) {
+	ret := _m.Called(ctx, profile)
+
+	var r0 *domain.User
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.User) *domain.User); ok {
+		r0 = rf(ctx, profile)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *domain.User) error); ok {
+		r1 = rf(ctx, profile)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RenderPost provides a mock function with given fields: post
+func (_m *BlogService) RenderPost(post *domain.Post) (*markdown.Document, error) {
+	ret := _m.Called(post)
+
+	var r0 *markdown.Document
+	if rf, ok := ret.Get(0).(func(*domain.Post) *markdown.Document); ok {
+		r0 = rf(post)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*markdown.Document)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*domain.Post) error); ok {
+		r1 = rf(post)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportPosts provides a mock function with given fields: ctx, open
+func (_m *BlogService) ImportPosts(ctx context.Context, open func() (bulk.Decoder, error)) (int, error) {
+	ret := _m.Called(ctx, open)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, func() (bulk.Decoder, error)) int); ok {
+		r0 = rf(ctx, open)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, func() (bulk.Decoder, error)) error); ok {
+		r1 = rf(ctx, open)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExportPosts provides a mock function with given fields: ctx, fn
+func (_m *BlogService) ExportPosts(ctx context.Context, fn func(*domain.Post) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(*domain.Post) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
 	return r0
 }
 
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/voltento/go-blog-project/internal/domain"
@@ -58,13 +59,13 @@ func (_m *Storage) Post(ctx context.Context, id domain.PostId) (*domain.Post, er
 	return r0, r1
 }
 
-// DeletePost provides a mock function with given fields: ctx, id
-func (_m *Storage) DeletePost(ctx context.Context, id domain.PostId) error {
-	ret := _m.Called(ctx, id)
+// DeletePost provides a mock function with given fields: ctx, id, version
+func (_m *Storage) DeletePost(ctx context.Context, id domain.PostId, version int) error {
+	ret := _m.Called(ctx, id, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, int) error); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -110,7 +111,7 @@ func (_m *Storage) ListPosts(ctx context.Context, page int, pageSize int) ([]*do
 }
 
 // Posts provides a mock function with given fields: ctx
-func (_m *Storage) Posts(ctx context.Context) []*domain.Post {
+func (_m *Storage) Posts(ctx context.Context) ([]*domain.Post, error) {
 	ret := _m.Called(ctx)
 
 	var r0 []*domain.Post
@@ -122,6 +123,406 @@ func (_m *Storage) Posts(ctx context.Context) []*domain.Post {
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostsPage provides a mock function with given fields: ctx, q
+func (_m *Storage) PostsPage(ctx context.Context, q domain.PostsQuery) (*domain.Page, error) {
+	ret := _m.Called(ctx, q)
+
+	var r0 *domain.Page
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostsQuery) *domain.Page); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Page)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostsQuery) error); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddRevision provides a mock function with given fields: ctx, rev
+func (_m *Storage) AddRevision(ctx context.Context, rev *domain.Revision) error {
+	ret := _m.Called(ctx, rev)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Revision) error); ok {
+		r0 = rf(ctx, rev)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Revisions provides a mock function with given fields: ctx, id
+func (_m *Storage) Revisions(ctx context.Context, id domain.PostId) ([]*domain.Revision, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 []*domain.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId) []*domain.Revision); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Revision provides a mock function with given fields: ctx, id, number
+func (_m *Storage) Revision(ctx context.Context, id domain.PostId, number int) (*domain.Revision, error) {
+	ret := _m.Called(ctx, id, number)
+
+	var r0 *domain.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, int) *domain.Revision); ok {
+		r0 = rf(ctx, id, number)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId, int) error); ok {
+		r1 = rf(ctx, id, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateComment provides a mock function with given fields: ctx, comment
+func (_m *Storage) CreateComment(ctx context.Context, comment *domain.Comment) (domain.CommentId, error) {
+	ret := _m.Called(ctx, comment)
+
+	var r0 domain.CommentId
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Comment) domain.CommentId); ok {
+		r0 = rf(ctx, comment)
+	} else {
+		r0 = ret.Get(0).(domain.CommentId)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *domain.Comment) error); ok {
+		r1 = rf(ctx, comment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Comment provides a mock function with given fields: ctx, postId, id
+func (_m *Storage) Comment(ctx context.Context, postId domain.PostId, id domain.CommentId) (*domain.Comment, error) {
+	ret := _m.Called(ctx, postId, id)
+
+	var r0 *domain.Comment
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, domain.CommentId) *domain.Comment); ok {
+		r0 = rf(ctx, postId, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId, domain.CommentId) error); ok {
+		r1 = rf(ctx, postId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Comments provides a mock function with given fields: ctx, postId
+func (_m *Storage) Comments(ctx context.Context, postId domain.PostId) ([]*domain.Comment, error) {
+	ret := _m.Called(ctx, postId)
+
+	var r0 []*domain.Comment
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId) []*domain.Comment); ok {
+		r0 = rf(ctx, postId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Comment)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostId) error); ok {
+		r1 = rf(ctx, postId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateComment provides a mock function with given fields: ctx, comment
+func (_m *Storage) UpdateComment(ctx context.Context, comment *domain.Comment) error {
+	ret := _m.Called(ctx, comment)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Comment) error); ok {
+		r0 = rf(ctx, comment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteComment provides a mock function with given fields: ctx, postId, id
+func (_m *Storage) DeleteComment(ctx context.Context, postId domain.PostId, id domain.CommentId) error {
+	ret := _m.Called(ctx, postId, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostId, domain.CommentId) error); ok {
+		r0 = rf(ctx, postId, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StatusCounts provides a mock function with given fields: ctx
+func (_m *Storage) StatusCounts(ctx context.Context) (map[domain.PostStatus]int, error) {
+	ret := _m.Called(ctx)
+
+	var r0 map[domain.PostStatus]int
+	if rf, ok := ret.Get(0).(func(context.Context) map[domain.PostStatus]int); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[domain.PostStatus]int)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Tags provides a mock function with given fields: ctx
+func (_m *Storage) Tags(ctx context.Context) ([]*domain.TagCount, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*domain.TagCount
+	if rf, ok := ret.Get(0).(func(context.Context) []*domain.TagCount); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.TagCount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: ctx, user
+func (_m *Storage) CreateUser(ctx context.Context, user *domain.User) (domain.UserId, error) {
+	ret := _m.Called(ctx, user)
+
+	var r0 domain.UserId
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.User) domain.UserId); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(domain.UserId)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *domain.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// User provides a mock function with given fields: ctx, id
+func (_m *Storage) User(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *domain.User
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserId) *domain.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.UserId) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserByUsername provides a mock function with given fields: ctx, username
+func (_m *Storage) UserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	ret := _m.Called(ctx, username)
+
+	var r0 *domain.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.User); ok {
+		r0 = rf(ctx, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateUser provides a mock function with given fields: ctx, user
+func (_m *Storage) UpdateUser(ctx context.Context, user *domain.User) error {
+	ret := _m.Called(ctx, user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ScheduledPosts provides a mock function with given fields: ctx, until
+func (_m *Storage) ScheduledPosts(ctx context.Context, until time.Time) ([]*domain.Post, error) {
+	ret := _m.Called(ctx, until)
+
+	var r0 []*domain.Post
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []*domain.Post); ok {
+		r0 = rf(ctx, until)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Post)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, until)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MigrationRecord provides a mock function with given fields: ctx, id
+func (_m *Storage) MigrationRecord(ctx context.Context, id string) (*domain.MigrationRecord, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *domain.MigrationRecord
+	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.MigrationRecord); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.MigrationRecord)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddMigrationRecord provides a mock function with given fields: ctx, rec
+func (_m *Storage) AddMigrationRecord(ctx context.Context, rec *domain.MigrationRecord) error {
+	ret := _m.Called(ctx, rec)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.MigrationRecord) error); ok {
+		r0 = rf(ctx, rec)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteMigrationRecord provides a mock function with given fields: ctx, id
+func (_m *Storage) DeleteMigrationRecord(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePostWithID provides a mock function with given fields: ctx, post
+func (_m *Storage) CreatePostWithID(ctx context.Context, post *domain.Post) error {
+	ret := _m.Called(ctx, post)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Post) error); ok {
+		r0 = rf(ctx, post)
+	} else {
+		r0 = ret.Error(0)
+	}
+
 	return r0
 }
 
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Format is the format of the posts of an import or an export
+type Format string
+
+const (
+	// JSONLines is a post per line as a JSON object
+	JSONLines Format = "jsonl"
+	// CSV has a header row naming the columns
+	CSV Format = "csv"
+)
+
+var formatContentTypes = map[Format]string{
+	JSONLines: "application/x-ndjson",
+	CSV:       "text/csv",
+}
+
+// ImportPosts creates the posts read in the format, all of them or none, and returns their number.
+// The invalid rows are listed in Rows of the *Error of ErrUnprocessable. It's allowed to the admins only.
+// The import is retried only if the reader is *bytes.Buffer, *bytes.Reader or *strings.Reader,
+// as the other readers can not be read again.
+func (c *Client) ImportPosts(ctx context.Context, format Format, posts io.Reader) (int, error) {
+	r := request{
+		method:      http.MethodPost,
+		path:        "v1/admin/import",
+		query:       url.Values{"format": {string(format)}},
+		rawBody:     posts,
+		contentType: formatContentTypes[format],
+	}
+
+	var resp struct {
+		Imported int `json:"imported"`
+	}
+	if _, err := c.call(ctx, r, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Imported, nil
+}
+
+// ExportPosts streams all the posts in the format ordered by id, the caller closes the stream.
+// It's allowed to the admins only.
+func (c *Client) ExportPosts(ctx context.Context, format Format) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "v1/admin/export", query: url.Values{"format": {string(format)}}})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
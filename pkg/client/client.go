@@ -0,0 +1,219 @@
+// Package client is a typed client of the blog HTTP API.
+//
+// The methods send the requests with the context they are given and return *Error for the problems the API
+// responds with, so the errors are matched with errors.Is against ErrNotFound and the other errors of the statuses.
+// The requests failed with 429 or 5xx are retried with backoff, see Retry.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const jsonContentType = "application/json"
+
+// DefaultRetry is the retry policy of the new clients
+var DefaultRetry = Retry{Attempts: 3, MinBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// Retry is the policy of retrying the requests failed with 429 Too Many Requests or a 5xx status.
+// A POST request is retried on 429 and 503 only, as the other statuses do not tell whether it was handled,
+// e.g. whether the post was created. A request with a body which can not be sent again is not retried.
+type Retry struct {
+	// Attempts is the maximum number of the attempts of a request, the request is not retried if it's 1 or less
+	Attempts int
+	// MinBackoff is the delay before the first retry, it's doubled on every next retry up to MaxBackoff.
+	// The delays are randomized, so the clients retrying together do not hit the server at once.
+	// The delay of Retry-After is used if the response has it.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Client calls the blog API. Its fields should not be changed while it's used.
+type Client struct {
+	baseURL *url.URL
+
+	// HTTPClient sends the requests, it's http.DefaultClient by default
+	HTTPClient *http.Client
+	// Token is the bearer token the requests are authorized with, they are anonymous without it. See Login.
+	Token string
+	// Retry is the retry policy, it's DefaultRetry by default
+	Retry Retry
+}
+
+// New returns the client of the API served at the base URL, e.g. http://localhost:8080
+func New(baseURL string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url. error: %w", err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("base url should be absolute, got '%s'", baseURL)
+	}
+
+	return &Client{baseURL: u, HTTPClient: http.DefaultClient, Retry: DefaultRetry}, nil
+}
+
+// request is a call of the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	// body is sent as JSON if it's not nil
+	body any
+	// rawBody is sent as is with the content type, it's used instead of body
+	rawBody     io.Reader
+	contentType string
+}
+
+// call sends the request and decodes the JSON response to out if it's not nil. It returns the headers of the response.
+func (c *Client) call(ctx context.Context, r request, out any) (http.Header, error) {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("can not decode response of %s %s. error: %w", r.method, r.path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// send sends the request retrying it according to the retry policy.
+// The response is returned if its status is not an error, the caller closes its body.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	req, err := c.newRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		if attempt >= c.Retry.Attempts || !retryable(req, resp.StatusCode) {
+			defer resp.Body.Close()
+			return nil, decodeError(resp)
+		}
+
+		delay := c.Retry.backoff(attempt, resp.Header.Get("Retry-After"))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, r request) (*http.Request, error) {
+	u := c.baseURL.JoinPath(r.path)
+	u.RawQuery = r.query.Encode()
+
+	body, contentType := r.rawBody, r.contentType
+	if r.body != nil {
+		data, err := json.Marshal(r.body)
+		if err != nil {
+			return nil, fmt.Errorf("can not encode request of %s %s. error: %w", r.method, r.path, err)
+		}
+		body, contentType = bytes.NewReader(data), jsonContentType
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", jsonContentType+", "+problemContentType)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+// retryable reports whether the request failed with the status may be sent again
+func retryable(req *http.Request, status int) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		return true
+	case status >= http.StatusInternalServerError:
+		return req.Method != http.MethodPost
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before the retry following the attempt
+func (r Retry) backoff(attempt int, retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	delay := r.MinBackoff
+	for i := 1; i < attempt && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if r.MaxBackoff > 0 {
+		delay = min(delay, r.MaxBackoff)
+	}
+	if delay < 2 {
+		return delay
+	}
+	// The delay is randomized between its half and itself
+	return delay/2 + rand.N(delay/2)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// version returns the version of the post from its ETag
+func version(header http.Header) (int, error) {
+	etag := header.Get("ETag")
+	v, err := strconv.Unquote(etag)
+	if err == nil {
+		var n int
+		if n, err = strconv.Atoi(v); err == nil {
+			return n, nil
+		}
+	}
+	return 0, errors.New("response has no version of the post, ETag is " + strconv.Quote(etag))
+}
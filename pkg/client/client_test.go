@@ -0,0 +1,478 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/voltento/go-blog-project/internal/blog"
+	"github.com/voltento/go-blog-project/internal/handlers"
+	"github.com/voltento/go-blog-project/internal/ids"
+	"github.com/voltento/go-blog-project/internal/middlewares"
+	"github.com/voltento/go-blog-project/internal/storage"
+)
+
+var testSecret = []byte("test secret")
+
+type ClientTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	// requests counts the requests the server got
+	requests atomic.Int32
+	// failures are the statuses the next requests fail with before they are handled
+	failures chan int
+
+	storage *storage.Storage
+	ctx     context.Context
+	client  *Client
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
+
+// SetupTest serves the API the way the service does, over the memory storage
+func (s *ClientTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.storage = storage.NewStorage()
+	b := blog.NewBlog(s.storage)
+	s.Require().NoError(b.IndexPosts(context.Background()))
+
+	r := gin.New()
+	middlewares.Setup(r)
+	r.Use(middlewares.AuthMiddleware(middlewares.JWTConfig{HMACSecret: testSecret}))
+	r.Use(middlewares.RequestValidationMiddleware(handlers.OpenAPI()))
+	handlers.RegisterHandlers(r, b, &middlewares.TokenIssuer{Secret: testSecret, TTL: time.Hour})
+
+	s.requests.Store(0)
+	s.failures = make(chan int, 10)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.requests.Add(1)
+		select {
+		case status := <-s.failures:
+			http.Error(w, http.StatusText(status), status)
+		default:
+			r.ServeHTTP(w, req)
+		}
+	}))
+
+	var err error
+	s.client, err = New(s.server.URL)
+	s.Require().NoError(err)
+	s.client.Retry = Retry{Attempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	s.ctx = context.Background()
+}
+
+func (s *ClientTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// login signs the user up and sets the token of the user to the client
+func (s *ClientTestSuite) login(username string) int64 {
+	id, err := s.client.Signup(s.ctx, &Signup{Username: username, Password: "password1"})
+	s.Require().NoError(err)
+
+	token, err := s.client.Login(s.ctx, username, "password1")
+	s.Require().NoError(err)
+	s.client.Token = token.Token
+	return id
+}
+
+// loginAdmin sets the token of an admin to the client
+func (s *ClientTestSuite) loginAdmin() {
+	id := s.login("admin")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   strconv.FormatInt(id, 10),
+		"roles": []string{"admin"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString(testSecret)
+	s.Require().NoError(err)
+	s.client.Token = token
+}
+
+func (s *ClientTestSuite) createPost(title string, tags ...string) *PostVersion {
+	v, err := s.client.CreatePost(s.ctx, &PostInput{Title: title, Content: "# " + title, Tags: tags})
+	s.Require().NoError(err)
+	return v
+}
+
+func (s *ClientTestSuite) TestPostLifecycle() {
+	s.login("ann")
+
+	created := s.createPost("First", "go")
+	s.Equal(1, created.Version)
+
+	post, err := s.client.Post(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal("First", post.Title)
+	s.Equal([]string{"go"}, post.Tags)
+	s.Equal(StatusPublished, post.Status)
+	s.Empty(post.HTML)
+
+	rendered, err := s.client.RenderedPost(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Contains(rendered.HTML, "<h1")
+	s.Require().Len(rendered.TOC, 1)
+	s.Equal("First", rendered.TOC[0].Title)
+
+	updated, err := s.client.UpdatePost(s.ctx, created.ID, &PostInput{Title: "Second", Content: "content"}, post.Version)
+	s.Require().NoError(err)
+	s.Equal(&PostVersion{ID: created.ID, Version: 2}, updated)
+
+	_, err = s.client.UpdatePost(s.ctx, created.ID, &PostInput{Title: "Third", Content: "content"}, post.Version)
+	s.ErrorIs(err, ErrPreconditionFailed, "a change of a stale version should fail")
+	s.ErrorIs(s.client.DeletePost(s.ctx, created.ID, post.Version), ErrPreconditionFailed)
+
+	s.Require().NoError(s.client.DeletePost(s.ctx, created.ID, updated.Version))
+	_, err = s.client.Post(s.ctx, created.ID)
+	s.ErrorIs(err, ErrNotFound)
+
+	var apiErr *Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(http.StatusNotFound, apiErr.Status)
+	s.Equal(CodeNotFound, apiErr.Code)
+	s.Equal("/v1/posts/1", apiErr.Instance)
+}
+
+func (s *ClientTestSuite) TestLargePostId() {
+	s.login("ann")
+	// The time-ordered ids are greater than 2^53, they are sent as strings
+	s.storage.SetIDAllocator(ids.NewTimeOrdered())
+
+	created := s.createPost("First")
+	s.Greater(created.ID, int64(1<<53))
+
+	post, err := s.client.Post(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal(created.ID, post.ID)
+
+	page, err := s.client.PostsPage(s.ctx, PostsQuery{})
+	s.Require().NoError(err)
+	s.Require().Len(page.Posts, 1)
+	s.Equal(created.ID, page.Posts[0].ID)
+}
+
+func (s *ClientTestSuite) TestScheduledPost() {
+	s.login("ann")
+	at := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	created, err := s.client.CreatePost(s.ctx, &PostInput{Title: "Later", Content: "content", Status: StatusScheduled, PublishAt: &at})
+	s.Require().NoError(err)
+
+	post, err := s.client.Post(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal(StatusScheduled, post.Status)
+	s.True(at.Equal(post.PublishAt))
+}
+
+func (s *ClientTestSuite) TestInvalidPost() {
+	s.login("ann")
+
+	_, err := s.client.CreatePost(s.ctx, &PostInput{Title: strings.Repeat("t", 201), Content: "content"})
+	s.ErrorIs(err, ErrBadRequest)
+
+	var apiErr *Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(CodeValidationFailed, apiErr.Code)
+	s.Equal([]FieldError{{Field: "title", Message: "should be at most 200 characters long"}}, apiErr.Fields)
+	s.Contains(err.Error(), "400 Bad Request (validation_failed)")
+}
+
+func (s *ClientTestSuite) TestAnonymous() {
+	_, err := s.client.CreatePost(s.ctx, &PostInput{Title: "First", Content: "content"})
+	s.ErrorIs(err, ErrUnauthorized)
+
+	s.client.Token = "invalid"
+	_, err = s.client.Tags(s.ctx)
+	s.ErrorIs(err, ErrUnauthorized)
+
+	var apiErr *Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(CodeInvalidToken, apiErr.Code)
+}
+
+func (s *ClientTestSuite) TestPostsPages() {
+	s.login("ann")
+	for _, title := range []string{"a", "b", "c", "d", "e"} {
+		s.createPost(title, "go")
+	}
+	s.createPost("f", "web")
+
+	page, err := s.client.PostsPage(s.ctx, PostsQuery{Limit: 2, Tags: []string{"go"}, Sort: SortByTitle, Desc: true})
+	s.Require().NoError(err)
+	s.Equal([]string{"e", "d"}, titles(page.Posts))
+	s.NotEmpty(page.NextCursor)
+
+	requests := s.requests.Load()
+	it := s.client.Posts(PostsQuery{Limit: 2, Tags: []string{"go"}, Sort: SortByTitle, Desc: true, Cursor: page.NextCursor})
+	var posts []*Post
+	for it.Next(s.ctx) {
+		posts = append(posts, it.Post())
+	}
+	s.Require().NoError(it.Err())
+	s.Equal([]string{"c", "b", "a"}, titles(posts))
+	s.Equal(int32(2), s.requests.Load()-requests, "the pages should be requested as they are iterated")
+	s.Nil(it.Post())
+	s.False(it.Next(s.ctx), "the iterator should stay done")
+
+	it = s.client.Posts(PostsQuery{Tags: []string{"go", "web"}, AnyTag: true, Render: true})
+	s.Require().True(it.Next(s.ctx))
+	s.Equal("a", it.Post().Title)
+	s.NotEmpty(it.Post().HTML)
+}
+
+func (s *ClientTestSuite) TestPostsIteratorError() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	it := s.client.Posts(PostsQuery{})
+	s.False(it.Next(ctx))
+	s.ErrorIs(it.Err(), context.Canceled)
+	s.False(it.Next(s.ctx), "the iterator should stop on the error")
+}
+
+func titles(posts []*Post) []string {
+	var t []string
+	for _, p := range posts {
+		t = append(t, p.Title)
+	}
+	return t
+}
+
+func (s *ClientTestSuite) TestSearchAndTags() {
+	s.login("ann")
+	s.createPost("Gophers", "go")
+	s.createPost("Rust", "rust")
+
+	results, err := s.client.Search(s.ctx, "gophers", 10)
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal("Gophers", results[0].Post.Title)
+	s.Contains(results[0].Snippet, "<mark>")
+
+	tags, err := s.client.Tags(s.ctx)
+	s.Require().NoError(err)
+	s.Equal([]*TagCount{{Tag: "go", Posts: 1}, {Tag: "rust", Posts: 1}}, tags)
+
+	_, err = s.client.Search(s.ctx, "", 0)
+	s.ErrorIs(err, ErrBadRequest)
+}
+
+func (s *ClientTestSuite) TestRevisions() {
+	s.login("ann")
+	created := s.createPost("First")
+	_, err := s.client.UpdatePost(s.ctx, created.ID, &PostInput{Title: "Second", Content: "content"}, 0)
+	s.Require().NoError(err)
+
+	revisions, err := s.client.Revisions(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Len(revisions, 2)
+
+	rev, err := s.client.Revision(s.ctx, created.ID, 1)
+	s.Require().NoError(err)
+	s.Equal("First", rev.Title)
+	s.Equal("ann", rev.Author)
+
+	restored, err := s.client.RestoreRevision(s.ctx, created.ID, 1)
+	s.Require().NoError(err)
+	s.Equal(3, restored.Version)
+
+	post, err := s.client.Post(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal("First", post.Title)
+
+	_, err = s.client.Revision(s.ctx, created.ID, 9)
+	s.ErrorIs(err, ErrNotFound)
+}
+
+func (s *ClientTestSuite) TestComments() {
+	s.login("ann")
+	post := s.createPost("First")
+
+	id, err := s.client.CreateComment(s.ctx, post.ID, &CommentInput{Content: "Nice"})
+	s.Require().NoError(err)
+	replyID, err := s.client.CreateComment(s.ctx, post.ID, &CommentInput{ParentID: id, Content: "Thanks"})
+	s.Require().NoError(err)
+	s.Require().NoError(s.client.UpdateComment(s.ctx, post.ID, replyID, "Thank you"))
+
+	comments, err := s.client.Comments(s.ctx, post.ID)
+	s.Require().NoError(err)
+	s.Require().Len(comments, 1)
+	s.Equal("Nice", comments[0].Content)
+	s.Require().Len(comments[0].Replies, 1)
+	s.Equal("Thank you", comments[0].Replies[0].Content)
+	s.Equal(id, comments[0].Replies[0].ParentID)
+
+	s.Require().NoError(s.client.DeleteComment(s.ctx, post.ID, id))
+	comments, err = s.client.Comments(s.ctx, post.ID)
+	s.Require().NoError(err)
+	s.Empty(comments)
+}
+
+func (s *ClientTestSuite) TestUsers() {
+	id := s.login("ann")
+
+	me, err := s.client.CurrentUser(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(id, me.ID)
+	s.Equal("ann", me.DisplayName)
+
+	updated, err := s.client.UpdateProfile(s.ctx, &Profile{DisplayName: "Ann", Bio: "Writes"})
+	s.Require().NoError(err)
+	s.Equal("Ann", updated.DisplayName)
+
+	user, err := s.client.User(s.ctx, id)
+	s.Require().NoError(err)
+	s.Equal("Writes", user.Bio)
+
+	_, err = s.client.Signup(s.ctx, &Signup{Username: "ann", Password: "password2"})
+	s.ErrorIs(err, ErrConflict)
+	var apiErr *Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(CodeUsernameTaken, apiErr.Code)
+
+	_, err = s.client.Login(s.ctx, "ann", "wrong password")
+	s.ErrorIs(err, ErrUnauthorized)
+}
+
+func (s *ClientTestSuite) TestImportExport() {
+	s.loginAdmin()
+
+	n, err := s.client.ImportPosts(s.ctx, CSV, strings.NewReader("title,content,tags\nFirst,content,go\nSecond,content,\n"))
+	s.Require().NoError(err)
+	s.Equal(2, n)
+
+	_, err = s.client.ImportPosts(s.ctx, JSONLines, strings.NewReader(`{"Title": "Third", "Content": "content"}`+"\n"+`{"Content": "content"}`+"\n"))
+	s.ErrorIs(err, ErrUnprocessable)
+	var apiErr *Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(CodeImportFailed, apiErr.Code)
+	s.Require().Len(apiErr.Rows, 1)
+	s.Equal(2, apiErr.Rows[0].Row)
+
+	export, err := s.client.ExportPosts(s.ctx, JSONLines)
+	s.Require().NoError(err)
+	defer export.Close()
+	data, err := io.ReadAll(export)
+	s.Require().NoError(err)
+	s.Equal(2, strings.Count(string(data), "\n"))
+	s.Contains(string(data), `"First"`)
+
+	s.client.Token = ""
+	_, err = s.client.ExportPosts(s.ctx, CSV)
+	s.ErrorIs(err, ErrUnauthorized)
+}
+
+func (s *ClientTestSuite) TestRetry() {
+	s.failures <- http.StatusServiceUnavailable
+	s.failures <- http.StatusBadGateway
+
+	_, err := s.client.Tags(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(int32(3), s.requests.Load(), "a GET request should be retried on 5xx")
+}
+
+func (s *ClientTestSuite) TestRetry_Exhausted() {
+	for range 3 {
+		s.failures <- http.StatusTooManyRequests
+	}
+
+	_, err := s.client.Tags(s.ctx)
+	s.ErrorIs(err, ErrTooManyRequests)
+	s.Equal(int32(3), s.requests.Load())
+
+	var apiErr *Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal("Too Many Requests", apiErr.Detail, "the text body should be the detail")
+}
+
+func (s *ClientTestSuite) TestRetry_Post() {
+	s.login("ann")
+	requests := s.requests.Load()
+
+	s.failures <- http.StatusServiceUnavailable
+	created, err := s.client.CreatePost(s.ctx, &PostInput{Title: "First", Content: "content"})
+	s.Require().NoError(err, "a POST request should be retried on 503 with its body")
+	s.Equal(int32(2), s.requests.Load()-requests)
+
+	post, err := s.client.Post(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal("First", post.Title)
+
+	requests = s.requests.Load()
+	s.failures <- http.StatusInternalServerError
+	_, err = s.client.CreatePost(s.ctx, &PostInput{Title: "Second", Content: "content"})
+	s.ErrorIs(err, ErrInternal)
+	s.Equal(int32(1), s.requests.Load()-requests, "a POST request should not be retried on 500")
+}
+
+func (s *ClientTestSuite) TestRetry_UnreadableBody() {
+	s.loginAdmin()
+	requests := s.requests.Load()
+
+	s.failures <- http.StatusServiceUnavailable
+	_, err := s.client.ImportPosts(s.ctx, CSV, io.MultiReader(strings.NewReader("title,content\nFirst,content\n")))
+	s.ErrorIs(err, ErrUnavailable)
+	s.Equal(int32(1), s.requests.Load()-requests, "a body which can not be read again should not be sent again")
+}
+
+func (s *ClientTestSuite) TestRetry_Canceled() {
+	s.client.Retry = Retry{Attempts: 3, MinBackoff: time.Hour, MaxBackoff: time.Hour}
+	s.failures <- http.StatusServiceUnavailable
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err := s.client.Tags(ctx)
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+// TestCodes fails when a problem code is added to the API without adding it to the client
+func (s *ClientTestSuite) TestCodes() {
+	codes := []string{
+		CodeBadRequest, CodeValidationFailed, CodeMalformedBody, CodeUnauthorized, CodeInvalidToken, CodeForbidden,
+		CodeNotFound, CodeNotAcceptable, CodeConflict, CodePostExists, CodeUsernameTaken, CodePreconditionFailed,
+		CodeVersionMismatch, CodeUnprocessable, CodeImportFailed, CodeTooManyRequests, CodeInternal, CodeUnavailable,
+	}
+
+	var documented []string
+	for _, code := range handlers.OpenAPI().Components.Schemas["Problem"].Properties["code"].Enum {
+		documented = append(documented, code.(string))
+	}
+	s.ElementsMatch(documented, codes)
+}
+
+func TestRetry_Backoff(t *testing.T) {
+	r := Retry{Attempts: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for attempt, max := range []time.Duration{100, 200, 300, 300} {
+		max *= time.Millisecond
+		delay := r.backoff(attempt+1, "")
+		assert.GreaterOrEqual(t, delay, max/2, "attempt %d", attempt+1)
+		assert.LessOrEqual(t, delay, max, "attempt %d", attempt+1)
+	}
+
+	assert.Equal(t, 2*time.Second, r.backoff(1, "2"), "the delay of Retry-After should be used")
+}
+
+func TestNew(t *testing.T) {
+	for _, url := range []string{"", "localhost:8080", "/v1", "http://%zz"} {
+		_, err := New(url)
+		assert.Error(t, err, url)
+	}
+
+	c, err := New("http://localhost:8080/blog/")
+	require.NoError(t, err)
+	req, err := c.newRequest(context.Background(), request{method: http.MethodGet, path: "v1/posts/1"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/blog/v1/posts/1", req.URL.String(), "the path should be joined to the base url")
+}
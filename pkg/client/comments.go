@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Comment is a comment with the replies to it
+type Comment struct {
+	ID int64 `json:"id"`
+	// ParentID is the comment this one replies to, it's zero for a top-level comment
+	ParentID  int64      `json:"parent_id"`
+	Author    string     `json:"author"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Replies   []*Comment `json:"replies"`
+}
+
+// CommentInput is a comment written by the client, its author is the user of the token
+type CommentInput struct {
+	// ParentID is the comment to reply to, it's zero for a top-level comment
+	ParentID int64  `json:"parent_id,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Comments returns the top-level comments of the post, the replies are nested into the comments they answer
+func (c *Client) Comments(ctx context.Context, postID int64) ([]*Comment, error) {
+	var resp struct {
+		Comments []*Comment `json:"comments"`
+	}
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: commentsPath(postID)}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Comments, nil
+}
+
+// CreateComment comments the post and returns the id of the comment
+func (c *Client) CreateComment(ctx context.Context, postID int64, comment *CommentInput) (int64, error) {
+	var resp struct {
+		CommentID int64 `json:"commentId"`
+	}
+	if _, err := c.call(ctx, request{method: http.MethodPost, path: commentsPath(postID), body: comment}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.CommentID, nil
+}
+
+// UpdateComment changes the content of the comment, it's allowed to its author and the admins
+func (c *Client) UpdateComment(ctx context.Context, postID, id int64, content string) error {
+	body := struct {
+		Content string `json:"content"`
+	}{content}
+	_, err := c.call(ctx, request{method: http.MethodPut, path: commentPath(postID, id), body: body}, nil)
+	return err
+}
+
+// DeleteComment deletes the comment with all the replies to it
+func (c *Client) DeleteComment(ctx context.Context, postID, id int64) error {
+	_, err := c.call(ctx, request{method: http.MethodDelete, path: commentPath(postID, id)}, nil)
+	return err
+}
+
+func commentsPath(postID int64) string {
+	return postPath(postID) + "/comments"
+}
+
+func commentPath(postID, id int64) string {
+	return commentsPath(postID) + "/" + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// The errors of the statuses the API responds with. An *Error matches the error of its status with errors.Is.
+var (
+	ErrBadRequest         error = statusError(http.StatusBadRequest)
+	ErrUnauthorized       error = statusError(http.StatusUnauthorized)
+	ErrForbidden          error = statusError(http.StatusForbidden)
+	ErrNotFound           error = statusError(http.StatusNotFound)
+	ErrNotAcceptable      error = statusError(http.StatusNotAcceptable)
+	ErrConflict           error = statusError(http.StatusConflict)
+	ErrPreconditionFailed error = statusError(http.StatusPreconditionFailed)
+	ErrUnprocessable      error = statusError(http.StatusUnprocessableEntity)
+	ErrTooManyRequests    error = statusError(http.StatusTooManyRequests)
+	ErrInternal           error = statusError(http.StatusInternalServerError)
+	ErrUnavailable        error = statusError(http.StatusServiceUnavailable)
+)
+
+// The codes of the problems, they tell the errors of the same status apart
+const (
+	CodeBadRequest         = "bad_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeMalformedBody      = "malformed_body"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidToken       = "invalid_token"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeNotAcceptable      = "not_acceptable"
+	CodeConflict           = "conflict"
+	CodePostExists         = "post_exists"
+	CodeUsernameTaken      = "username_taken"
+	CodePreconditionFailed = "precondition_failed"
+	CodeVersionMismatch    = "version_mismatch"
+	CodeUnprocessable      = "unprocessable"
+	CodeImportFailed       = "import_failed"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal"
+	CodeUnavailable        = "unavailable"
+)
+
+type statusError int
+
+func (e statusError) Error() string {
+	return http.StatusText(int(e))
+}
+
+// Error is the RFC 7807 problem the API responded with
+type Error struct {
+	Status   int    `json:"status"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Code     string `json:"code"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	// Fields are the invalid fields of the request
+	Fields []FieldError `json:"errors"`
+	// Rows are the invalid rows of an import, Truncated tells whether there are more of them than listed
+	Rows      []ImportRow `json:"rows"`
+	Truncated bool        `json:"truncated"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportRow is an invalid row of an import, the rows are counted from 1
+type ImportRow struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("blog api: %d %s", e.Status, e.Title)
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// Is reports whether the target is the error of the status of the problem, e.g. ErrNotFound
+func (e *Error) Is(target error) bool {
+	status, ok := target.(statusError)
+	return ok && int(status) == e.Status
+}
+
+// decodeError returns the error of the failed response. The responses which are not problem details,
+// e.g. of a proxy, are described by their status and text body.
+func decodeError(resp *http.Response) error {
+	e := &Error{}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("can not read response of %s %s. error: %w", resp.Request.Method, resp.Request.URL.Path, err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == problemContentType || mediaType == jsonContentType:
+		_ = json.Unmarshal(data, e)
+	case mediaType == "text/plain":
+		e.Detail = strings.TrimSpace(string(data))
+	}
+
+	e.Status = resp.StatusCode
+	if e.Title == "" {
+		e.Title = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
@@ -0,0 +1,380 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type PostStatus string
+
+const (
+	StatusDraft     PostStatus = "draft"
+	StatusPublished PostStatus = "published"
+	StatusScheduled PostStatus = "scheduled"
+	StatusArchived  PostStatus = "archived"
+)
+
+type Post struct {
+	ID       int64  `json:"ID"`
+	Title    string `json:"Title"`
+	Content  string `json:"Content"`
+	AuthorID int64  `json:"AuthorID"`
+	// Author is the display name of the author
+	Author string     `json:"Author"`
+	Tags   []string   `json:"Tags"`
+	Status PostStatus `json:"Status"`
+	// PublishAt is the time a scheduled post is published at or a published post was published at, zero for drafts
+	PublishAt time.Time `json:"PublishAt"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+	// Version is the version of the post the changes are based on, see UpdatePost
+	Version int `json:"Version"`
+
+	// HTML is the content rendered to sanitized HTML and TOC are its headings, they are set for the rendered posts only
+	HTML string     `json:"HTML,omitempty"`
+	TOC  []*Heading `json:"TOC,omitempty"`
+}
+
+// UnmarshalJSON decodes the post with the id sent as a number or a string
+func (p *Post) UnmarshalJSON(data []byte) error {
+	type plain Post
+	v := struct {
+		*plain
+		ID postID `json:"ID"`
+	}{plain: (*plain)(p)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	p.ID = int64(v.ID)
+	return nil
+}
+
+// postID is a post id in a response. The ids greater than 2^53 are sent as strings,
+// so the clients parsing numbers as float64 keep them.
+type postID int64
+
+func (id *postID) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseInt(string(bytes.Trim(data, `"`)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("post id should be an integer, got %s", data)
+	}
+
+	*id = postID(n)
+	return nil
+}
+
+type Heading struct {
+	Level int `json:"Level"`
+	// ID is the anchor of the heading, the heading is linked with "#" + ID
+	ID    string `json:"ID"`
+	Title string `json:"Title"`
+}
+
+// PostInput is a post written by the client, its author is the user of the token
+type PostInput struct {
+	// Title is at most 200 characters long and Content is at most 100000 characters long
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`
+	// Status of a new post is published and a changed post keeps its status if it's empty
+	Status PostStatus `json:"status,omitempty"`
+	// PublishAt is the time a scheduled post is published at
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+// PostVersion is a written post, the next change of the post is based on its version
+type PostVersion struct {
+	ID      int64
+	Version int
+}
+
+type PostSort string
+
+// The orders of the posts, they are ordered by id by default
+const (
+	SortByCreatedAt PostSort = "created_at"
+	SortByUpdatedAt PostSort = "updated_at"
+	SortByTitle     PostSort = "title"
+)
+
+// PostsQuery selects a page of the posts
+type PostsQuery struct {
+	// Limit is the number of the posts of a page from 1 to 100, the server picks it if it's zero
+	Limit int
+	// Tags are the tags the posts should have, all of them or any of them if AnyTag is set
+	Tags   []string
+	AnyTag bool
+	Sort   PostSort
+	Desc   bool
+	// Render requests the posts with the content rendered to HTML
+	Render bool
+	// Cursor is the position the page starts at, it's NextCursor of the previous page
+	Cursor string
+}
+
+func (q PostsQuery) values() url.Values {
+	v := url.Values{}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	for _, tag := range q.Tags {
+		v.Add("tag", tag)
+	}
+	if q.AnyTag {
+		v.Set("match", "any")
+	}
+	if q.Sort != "" {
+		v.Set("sort", string(q.Sort))
+	}
+	if q.Desc {
+		v.Set("order", "desc")
+	}
+	if q.Render {
+		v.Set("render", "html")
+	}
+	if q.Cursor != "" {
+		v.Set("cursor", q.Cursor)
+	}
+	return v
+}
+
+type PostsPage struct {
+	Posts []*Post `json:"posts"`
+	// NextCursor is the cursor of the next page, it's empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+type SearchResult struct {
+	Post  *Post   `json:"post"`
+	Score float64 `json:"score"`
+	// Snippet is an HTML fragment of the content with the matching words highlighted
+	Snippet string `json:"snippet"`
+}
+
+type TagCount struct {
+	Tag string `json:"tag"`
+	// Posts is the number of the published posts with the tag
+	Posts int `json:"posts"`
+}
+
+// Revision is a change of a post, the listed revisions have no title, content and diff
+type Revision struct {
+	// Number is the version of the post the revision made
+	Number    int       `json:"number"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	// Diff is the line diff of the content against the previous revision
+	Diff string `json:"diff"`
+}
+
+// PostsPage returns a page of the posts. The anonymous clients get the published posts,
+// the authors get their own posts in any status as well and the admins get all the posts.
+func (c *Client) PostsPage(ctx context.Context, q PostsQuery) (*PostsPage, error) {
+	var page PostsPage
+	_, err := c.call(ctx, request{method: http.MethodGet, path: "v1/posts", query: q.values()}, &page)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Posts returns the iterator of the posts of all the pages of the query starting at its cursor
+func (c *Client) Posts(q PostsQuery) *PostIterator {
+	return &PostIterator{client: c, query: q}
+}
+
+// PostIterator requests the pages of the posts as they are iterated
+//
+//	it := c.Posts(client.PostsQuery{Tags: []string{"go"}})
+//	for it.Next(ctx) {
+//		post := it.Post()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type PostIterator struct {
+	client *Client
+	query  PostsQuery
+	page   []*Post
+	post   *Post
+	done   bool
+	err    error
+}
+
+// Next advances the iterator to the next post requesting the next page if it's needed.
+// It returns false when the posts are over or the request failed, see Err.
+func (it *PostIterator) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.post = nil
+			return false
+		}
+
+		page, err := it.client.PostsPage(ctx, it.query)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		it.page, it.query.Cursor, it.done = page.Posts, page.NextCursor, page.NextCursor == ""
+	}
+
+	it.post, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Post returns the current post
+func (it *PostIterator) Post() *Post {
+	return it.post
+}
+
+// Err returns the error the iteration stopped with
+func (it *PostIterator) Err() error {
+	return it.err
+}
+
+// Cursor returns the cursor of the page after the current one, the iteration is continued from it with a new iterator
+func (it *PostIterator) Cursor() string {
+	return it.query.Cursor
+}
+
+// Post returns the post. The drafts are returned to their authors and the admins only.
+func (c *Client) Post(ctx context.Context, id int64) (*Post, error) {
+	return c.post(ctx, id, nil)
+}
+
+// RenderedPost returns the post with the content rendered to HTML
+func (c *Client) RenderedPost(ctx context.Context, id int64) (*Post, error) {
+	return c.post(ctx, id, url.Values{"render": {"html"}})
+}
+
+func (c *Client) post(ctx context.Context, id int64, query url.Values) (*Post, error) {
+	var post Post
+	_, err := c.call(ctx, request{method: http.MethodGet, path: postPath(id), query: query}, &post)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// CreatePost creates the post of the user of the token
+func (c *Client) CreatePost(ctx context.Context, post *PostInput) (*PostVersion, error) {
+	var resp struct {
+		PostID postID `json:"postId"`
+	}
+	header, err := c.call(ctx, request{method: http.MethodPost, path: "v1/posts", body: post}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return postVersion(int64(resp.PostID), header)
+}
+
+// UpdatePost changes the post if it has the version, the change fails with ErrPreconditionFailed otherwise.
+// The post is changed whatever version it has if the version is zero.
+func (c *Client) UpdatePost(ctx context.Context, id int64, post *PostInput, version int) (*PostVersion, error) {
+	var resp struct {
+		PostID postID `json:"postId"`
+	}
+	r := request{method: http.MethodPut, path: postPath(id), header: ifMatch(version), body: post}
+	header, err := c.call(ctx, r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return postVersion(int64(resp.PostID), header)
+}
+
+// DeletePost deletes the post if it has the version, see UpdatePost
+func (c *Client) DeletePost(ctx context.Context, id int64, version int) error {
+	_, err := c.call(ctx, request{method: http.MethodDelete, path: postPath(id), header: ifMatch(version)}, nil)
+	return err
+}
+
+// Search returns the published posts matching the query ordered by relevance.
+// The server picks the number of the results if the limit is zero.
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]*SearchResult, error) {
+	q := url.Values{"q": {query}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp struct {
+		Results []*SearchResult `json:"results"`
+	}
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: "v1/posts/search", query: q}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Tags returns all the tags with the number of the published posts having them
+func (c *Client) Tags(ctx context.Context) ([]*TagCount, error) {
+	var resp struct {
+		Tags []*TagCount `json:"tags"`
+	}
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: "v1/tags"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// Revisions returns the revisions of the post without their title, content and diff
+func (c *Client) Revisions(ctx context.Context, id int64) ([]*Revision, error) {
+	var resp struct {
+		Revisions []*Revision `json:"revisions"`
+	}
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: postPath(id) + "/revisions"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Revisions, nil
+}
+
+func (c *Client) Revision(ctx context.Context, id int64, number int) (*Revision, error) {
+	var rev Revision
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: revisionPath(id, number)}, &rev); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// RestoreRevision makes the content of the revision the current content of the post
+func (c *Client) RestoreRevision(ctx context.Context, id int64, number int) (*PostVersion, error) {
+	var resp struct {
+		PostID postID `json:"postId"`
+	}
+	header, err := c.call(ctx, request{method: http.MethodPost, path: revisionPath(id, number) + "/restore"}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return postVersion(int64(resp.PostID), header)
+}
+
+func postPath(id int64) string {
+	return "v1/posts/" + strconv.FormatInt(id, 10)
+}
+
+func revisionPath(id int64, number int) string {
+	return postPath(id) + "/revisions/" + strconv.Itoa(number)
+}
+
+// ifMatch returns the header of the change based on the version, a zero version is not checked
+func ifMatch(version int) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {strconv.Quote(strconv.Itoa(version))}}
+}
+
+func postVersion(id int64, header http.Header) (*PostVersion, error) {
+	v, err := version(header)
+	if err != nil {
+		return nil, err
+	}
+	return &PostVersion{ID: id, Version: v}, nil
+}
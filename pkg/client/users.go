@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// User is the public profile of a user
+type User struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Signup is a new account, the display name defaults to the username
+type Signup struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+}
+
+// Profile is the editable part of a user
+type Profile struct {
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+}
+
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Signup registers the user and returns its id, the user logs in to get a token
+func (c *Client) Signup(ctx context.Context, signup *Signup) (int64, error) {
+	var resp struct {
+		UserID int64 `json:"userId"`
+	}
+	if _, err := c.call(ctx, request{method: http.MethodPost, path: "v1/users", body: signup}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.UserID, nil
+}
+
+// Login returns the token of the user, the client makes the changes as the user with it set as Token
+func (c *Client) Login(ctx context.Context, username, password string) (*Token, error) {
+	body := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{username, password}
+
+	var token Token
+	if _, err := c.call(ctx, request{method: http.MethodPost, path: "v1/users/login", body: body}, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CurrentUser returns the user of the token
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: "v1/users/me"}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateProfile changes the profile of the user of the token
+func (c *Client) UpdateProfile(ctx context.Context, profile *Profile) (*User, error) {
+	var user User
+	if _, err := c.call(ctx, request{method: http.MethodPut, path: "v1/users/me", body: profile}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) User(ctx context.Context, id int64) (*User, error) {
+	var user User
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: "v1/users/" + strconv.FormatInt(id, 10)}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}